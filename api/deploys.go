@@ -0,0 +1,25 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/megamsys/libgo/errors"
+	"github.com/megamsys/vertice/carton"
+)
+
+// deploys lists the deploys of a box, the latest first. An operations/rollback
+// request takes the id of one of them.
+func deploys(w http.ResponseWriter, r *http.Request) error {
+	id := r.URL.Query().Get(":id")
+	h := carton.Deploys()
+	if h == nil {
+		return &errors.HTTP{Code: http.StatusServiceUnavailable, Message: carton.ErrNoDeployHistory.Error()}
+	}
+	list, err := h.List(id)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(list)
+}
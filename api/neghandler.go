@@ -50,13 +50,17 @@ func NewNegHandler() *negroni.Negroni {
 	m.Add("Post", "/logs/", socketServer)
 	m.Add("Get", "/logs/", socketServer)
 	m.Add("Get", "/ping", Handler(ping))
-	m.Add("Get", "/vnc/", Handler(vnc))
+	m.Add("Get", "/operations", Handler(operations))
+	m.Add("Get", "/operations/{id}", Handler(operation))
+	m.Add("Get", "/deploys/{id}", Handler(deploys))
+	m.Add("Get", "/vnc/{id}/screenshot", Handler(vncScreenshot))
 
 	socketHandler(socketServer)
 
-	// Shell also doesn't use {app} on purpose. Middlewares don't play well
-	// with websocket.
+	// Shell and vnc also don't use {app} on purpose. Middlewares don't play
+	// well with websocket.
 	m.Add("Get", "/shell/{email}/{asmsid}/{id}", websocket.Handler(remoteShellHandler))
+	m.Add("Get", "/vnc/{email}/{asmsid}/{id}", vncServer)
 
 	n := negroni.New()
 	n.Use(negroni.NewRecovery())
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/megamsys/libgo/errors"
+	"github.com/megamsys/vertice/provision"
+)
+
+// operations lists the operations run by vertice, the latest first.
+// They can be filtered by ?assembly_id= and ?account_id=
+func operations(w http.ResponseWriter, r *http.Request) error {
+	asm := r.URL.Query().Get("assembly_id")
+	email := r.URL.Query().Get("account_id")
+	ops := []*provision.Operation{}
+	for _, op := range provision.ListOperations() {
+		if (asm == "" || op.AssemblyId == asm) && (email == "" || op.AccountId == email) {
+			ops = append(ops, op)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(ops)
+}
+
+// operation shows an operation with its pipeline actions.
+func operation(w http.ResponseWriter, r *http.Request) error {
+	id := r.URL.Query().Get(":id")
+	op, ok := provision.GetOperation(id)
+	if !ok {
+		return &errors.HTTP{Code: http.StatusNotFound, Message: "operation " + id + " not found"}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(op)
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/megamsys/libgo/action"
+	liberr "github.com/megamsys/libgo/errors"
+	"github.com/megamsys/vertice/provision"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestOperationRecordsPipelineActions(c *check.C) {
+	op := provision.NewOperation("RER001", "AMS001", "info@megam.io", "state", "create")
+	box := &provision.Box{Name: "tiny", DomainName: "megambox.com", OperationId: op.Id}
+	actions := provision.TrackActions(box, []*action.Action{
+		{Name: "create-machine", Forward: func(ctx action.FWContext) (action.Result, error) { return nil, nil }},
+		{Name: "get-vm-host-ip-port", Forward: func(ctx action.FWContext) (action.Result, error) { return nil, errors.New("timed out") }},
+	})
+	err := action.NewPipeline(actions...).Execute(box)
+	c.Assert(err, check.NotNil)
+	op.Done(err)
+
+	request, err := http.NewRequest("GET", "/operations/"+op.Id+"?:id="+op.Id, nil)
+	c.Assert(err, check.IsNil)
+	recorder := httptest.NewRecorder()
+	err = operation(recorder, request)
+	c.Assert(err, check.IsNil)
+	var got provision.Operation
+	c.Assert(json.Unmarshal(recorder.Body.Bytes(), &got), check.IsNil)
+	c.Assert(got.Status, check.Equals, provision.OP_FAILED)
+	c.Assert(got.Steps, check.HasLen, 2)
+	c.Assert(got.Steps[0].Name, check.Equals, "create-machine")
+	c.Assert(got.Steps[1].Error, check.Equals, "timed out")
+}
+
+func (s *S) TestOperationsFilterByAssembly(c *check.C) {
+	op := provision.NewOperation("RER002", "AMS002", "info@megam.io", "control", "stop")
+	op.Done(nil)
+	request, err := http.NewRequest("GET", "/operations?assembly_id=AMS002", nil)
+	c.Assert(err, check.IsNil)
+	recorder := httptest.NewRecorder()
+	err = operations(recorder, request)
+	c.Assert(err, check.IsNil)
+	var got []provision.Operation
+	c.Assert(json.Unmarshal(recorder.Body.Bytes(), &got), check.IsNil)
+	c.Assert(got, check.HasLen, 1)
+	c.Assert(got[0].Id, check.Equals, op.Id)
+}
+
+func (s *S) TestOperationNotFound(c *check.C) {
+	request, err := http.NewRequest("GET", "/operations/OPS000?:id=OPS000", nil)
+	c.Assert(err, check.IsNil)
+	recorder := httptest.NewRecorder()
+	err = operation(recorder, request)
+	e, ok := err.(*liberr.HTTP)
+	c.Assert(ok, check.Equals, true)
+	c.Assert(e.Code, check.Equals, http.StatusNotFound)
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	stdcontext "context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/megamsys/libgo/errors"
+	"github.com/megamsys/vertice/api/context"
+	"github.com/megamsys/vertice/govnc"
+)
+
+const (
+	// screenshotTTL is how long a screenshot of a box is served from cache.
+	screenshotTTL = 30 * time.Second
+	// screenshotEvery is the least time between two captures of a box, even
+	// when the last one failed.
+	screenshotEvery = 10 * time.Second
+	// screenshotTimeout is how long a capture may take.
+	screenshotTimeout = 20 * time.Second
+)
+
+var errScreenshotLimit = fmt.Errorf("screenshots are limited to one every %s a box", screenshotEvery)
+
+// vncScreenshot is a png of the vnc console of a box, for a thumbnail without
+// opening a session.
+func vncScreenshot(w http.ResponseWriter, r *http.Request) error {
+	t := context.GetAuthToken(r)
+	if t == nil {
+		return &errors.HTTP{Code: http.StatusUnauthorized, Message: "no token provided"}
+	}
+	img, taken, err := screenshots.get(r.URL.Query().Get(":id"), t.GetUserName())
+	if err == errScreenshotLimit {
+		w.Header().Set("Retry-After", strconv.Itoa(int(screenshotEvery/time.Second)))
+		return &errors.HTTP{Code: http.StatusTooManyRequests, Message: err.Error()}
+	}
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "private, max-age="+strconv.Itoa(int(screenshotTTL/time.Second)))
+	w.Header().Set("Last-Modified", taken.UTC().Format(http.TimeFormat))
+	_, err = w.Write(img)
+	return err
+}
+
+// screenshotOf captures the vnc console of the box id of email.
+func screenshotOf(id, email string) ([]byte, error) {
+	vh, err := vncHostOf(id, email)
+	if err != nil {
+		return nil, &errors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+	}
+	if vh.IpAddress == "" {
+		return nil, &errors.HTTP{Code: http.StatusNotFound, Message: "box " + id + " has no vnc console"}
+	}
+	ctx, cancel := stdcontext.WithTimeout(stdcontext.Background(), screenshotTimeout)
+	defer cancel()
+	img, err := govnc.ScreenshotPNG(ctx, vh)
+	if err != nil {
+		return nil, &errors.HTTP{Code: http.StatusBadGateway, Message: err.Error()}
+	}
+	return img, nil
+}
+
+type screenshot struct {
+	sync.Mutex
+	email string
+	png   []byte
+	taken time.Time
+	tried time.Time
+}
+
+// screenshotCache keeps the last screenshot of the boxes, and captures a box
+// again when it is older than screenshotTTL, at most once every
+// screenshotEvery whoever asks. Requests for the same box wait for the same
+// capture.
+type screenshotCache struct {
+	sync.Mutex
+	shots   map[string]*screenshot
+	capture func(id, email string) ([]byte, error)
+	now     func() time.Time
+}
+
+func (c *screenshotCache) shot(key string, now time.Time) *screenshot {
+	c.Lock()
+	defer c.Unlock()
+	if c.shots == nil {
+		c.shots = make(map[string]*screenshot)
+	}
+	for k, s := range c.shots {
+		if k != key && now.Sub(s.tried) > screenshotTTL {
+			delete(c.shots, k)
+		}
+	}
+	s, ok := c.shots[key]
+	if !ok {
+		s = &screenshot{}
+		c.shots[key] = s
+	}
+	return s
+}
+
+func (c *screenshotCache) get(id, email string) ([]byte, time.Time, error) {
+	now := c.now()
+	s := c.shot(id, now)
+	s.Lock()
+	defer s.Unlock()
+	now = c.now()
+	// only the account that captured it is served the cached one.
+	mine := s.png != nil && s.email == email
+	if mine && now.Sub(s.taken) < screenshotTTL {
+		return s.png, s.taken, nil
+	}
+	if now.Sub(s.tried) < screenshotEvery {
+		if mine {
+			return s.png, s.taken, nil
+		}
+		return nil, time.Time{}, errScreenshotLimit
+	}
+	s.tried = now
+	img, err := c.capture(id, email)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	s.email, s.png, s.taken = email, img, now
+	return s.png, s.taken, nil
+}
+
+var screenshots = screenshotCache{capture: screenshotOf, now: time.Now}
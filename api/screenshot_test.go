@@ -0,0 +1,52 @@
+package api
+
+import (
+	"errors"
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestScreenshotCache(c *check.C) {
+	now := time.Date(2017, 3, 1, 10, 0, 0, 0, time.UTC)
+	captured := 0
+	fail := false
+	cache := screenshotCache{
+		now: func() time.Time { return now },
+		capture: func(id, email string) ([]byte, error) {
+			captured++
+			if fail {
+				return nil, errors.New("vnc server gone")
+			}
+			return []byte(id), nil
+		},
+	}
+	img, taken, err := cache.get("ASM01", "info@megam.io")
+	c.Assert(err, check.IsNil)
+	c.Assert(string(img), check.Equals, "ASM01")
+	c.Assert(taken, check.Equals, now)
+	now = now.Add(screenshotTTL - time.Second)
+	_, _, err = cache.get("ASM01", "info@megam.io")
+	c.Assert(err, check.IsNil)
+	c.Assert(captured, check.Equals, 1)
+	now = now.Add(time.Second)
+	fail = true
+	_, _, err = cache.get("ASM01", "info@megam.io")
+	c.Assert(err, check.ErrorMatches, "vnc server gone")
+	c.Assert(captured, check.Equals, 2)
+	// the last capture failed, the stale one is served until it may try again.
+	img, _, err = cache.get("ASM01", "info@megam.io")
+	c.Assert(err, check.IsNil)
+	c.Assert(string(img), check.Equals, "ASM01")
+	c.Assert(captured, check.Equals, 2)
+	_, _, err = cache.get("ASM02", "info@megam.io")
+	c.Assert(err, check.ErrorMatches, "vnc server gone")
+	_, _, err = cache.get("ASM02", "info@megam.io")
+	c.Assert(err, check.Equals, errScreenshotLimit)
+	// the limit is of the box, not of who asks.
+	_, _, err = cache.get("ASM02", "admin@megam.io")
+	c.Assert(err, check.Equals, errScreenshotLimit)
+	c.Assert(captured, check.Equals, 3)
+	_, _, err = cache.get("ASM01", "admin@megam.io")
+	c.Assert(err, check.Equals, errScreenshotLimit)
+}
@@ -1,8 +1,11 @@
 package api
 
 import (
+	"io"
 	"net/http"
-	//"strconv"
+	"regexp"
+	"strconv"
+	"sync"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/megamsys/libgo/errors"
@@ -12,6 +15,13 @@ import (
 	"golang.org/x/net/websocket"
 )
 
+// the terminal of a shell when the client doesn't say.
+const (
+	defaultWidth  = 140
+	defaultHeight = 38
+	defaultTerm   = "xterm"
+)
+
 func remoteShellHandler(ws *websocket.Conn) {
 	var httpErr *errors.HTTP
 	defer func() {
@@ -60,24 +70,30 @@ func remoteShellHandler(ws *websocket.Conn) {
 		return
 	}
 	boxId := r.URL.Query().Get(":id")
-	//width, _ := strconv.Atoi(r.URL.Query().Get("width"))
-	//height, _ := strconv.Atoi(r.URL.Query().Get("height"))
-	//term := r.URL.Query().Get("term")
-	width := 140
-	height := 38
-	term := "xterm"
-	log.Debugf("%s %s %s %s", boxId, width, height, term)
+	width, height, term := termOf(r)
+	log.Debugf("%s %d %d %s", boxId, width, height, term)
 
 	for _, box := range *car.Boxes {
+		conn := newResizeConn(ws)
 		opts := provision.ShellOptions{
 			Box:    &box,
-			Conn:   ws,
+			Conn:   conn,
 			Width:  width,
 			Height: height,
 			Unit:   boxId,
 			Term:   term,
+			Resize: conn.resize,
+		}
+		p := carton.ProvisionerMap[box.Provider]
+		if err = provision.Need(box.Provider, p, provision.CAP_SHELL); err != nil {
+			httpErr = &errors.HTTP{
+				Code:    http.StatusNotImplemented,
+				Message: err.Error(),
+			}
+			return
 		}
-		err = carton.ProvisionerMap[box.Provider].Shell(opts) //BUG: we need get the provisioner of the correct provider
+		err = p.Shell(opts) //BUG: we need get the provisioner of the correct provider
+		conn.done()
 		if err != nil {
 			httpErr = &errors.HTTP{
 				Code:    http.StatusInternalServerError,
@@ -87,6 +103,84 @@ func remoteShellHandler(ws *websocket.Conn) {
 	}
 }
 
+// termOf is the size and type of the terminal of the user, from the width,
+// height and term of the query.
+func termOf(r *http.Request) (int, int, string) {
+	width, height, term := defaultWidth, defaultHeight, defaultTerm
+	if w, err := strconv.Atoi(r.URL.Query().Get("width")); err == nil && w > 0 {
+		width = w
+	}
+	if h, err := strconv.Atoi(r.URL.Query().Get("height")); err == nil && h > 0 {
+		height = h
+	}
+	if t := r.URL.Query().Get("term"); t != "" {
+		term = t
+	}
+	return width, height, term
+}
+
+// resizeSeq is the xterm sequence to resize the window to rows and columns,
+// ESC [ 8 ; rows ; cols t. A client sends it when the terminal of the user is
+// resized.
+var resizeSeq = regexp.MustCompile(`\x1b\[8;(\d+);(\d+)t`)
+
+// resizeConn is the shell websocket. It takes the resize sequences out of the
+// input of the user and sends them on resize instead.
+type resizeConn struct {
+	io.ReadWriteCloser
+	resize chan provision.TermSize
+	mu     sync.Mutex
+	closed bool
+}
+
+func newResizeConn(conn io.ReadWriteCloser) *resizeConn {
+	return &resizeConn{ReadWriteCloser: conn, resize: make(chan provision.TermSize, 1)}
+}
+
+func (c *resizeConn) Read(p []byte) (int, error) {
+	for {
+		n, err := c.ReadWriteCloser.Read(p)
+		if n > 0 && resizeSeq.Match(p[:n]) {
+			rest := resizeSeq.ReplaceAllFunc(p[:n], func(seq []byte) []byte {
+				m := resizeSeq.FindSubmatch(seq)
+				height, _ := strconv.Atoi(string(m[1]))
+				width, _ := strconv.Atoi(string(m[2]))
+				c.send(provision.TermSize{Width: width, Height: height})
+				return nil
+			})
+			n = copy(p, rest)
+			if n == 0 && err == nil {
+				continue
+			}
+		}
+		return n, err
+	}
+}
+
+// send keeps the latest size when the shell hasn't taken the one before.
+func (c *resizeConn) send(size provision.TermSize) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	select {
+	case <-c.resize:
+	default:
+	}
+	c.resize <- size
+}
+
+// done closes resize once the shell is over.
+func (c *resizeConn) done() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.closed {
+		c.closed = true
+		close(c.resize)
+	}
+}
+
 //Return the Box object ?  Get the carton, and make a Box
 func getBox(asmsid string, id string, account_id string) (*carton.Carton, error) {
 	c, err := carton.NewCarton(asmsid, id, account_id)
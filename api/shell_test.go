@@ -1,5 +1,41 @@
 package api
 
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/megamsys/vertice/provision"
+	"gopkg.in/check.v1"
+)
+
+type bufConn struct {
+	*bytes.Buffer
+}
+
+func (bufConn) Close() error { return nil }
+
+func (s *S) TestShellTermOf(c *check.C) {
+	r, _ := http.NewRequest("GET", "/shell/a@b.com/AMS01/ASM01?width=200&height=50&term=xterm-256color", nil)
+	width, height, term := termOf(r)
+	c.Assert([]interface{}{width, height, term}, check.DeepEquals, []interface{}{200, 50, "xterm-256color"})
+	r, _ = http.NewRequest("GET", "/shell/a@b.com/AMS01/ASM01?width=x", nil)
+	width, height, term = termOf(r)
+	c.Assert([]interface{}{width, height, term}, check.DeepEquals, []interface{}{defaultWidth, defaultHeight, defaultTerm})
+}
+
+func (s *S) TestShellResizeConn(c *check.C) {
+	conn := newResizeConn(bufConn{bytes.NewBufferString("ls\x1b[8;40;120t -l\n")})
+	in, err := ioutil.ReadAll(conn)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(in), check.Equals, "ls -l\n")
+	c.Assert(<-conn.resize, check.Equals, provision.TermSize{Width: 120, Height: 40})
+	conn.done()
+	conn.send(provision.TermSize{Width: 1, Height: 1})
+	_, open := <-conn.resize
+	c.Assert(open, check.Equals, false)
+}
+
 /*
 func (s *S) TestAppShellSpecifyUnit(c *check.C) {
 	a := app.App{
@@ -1,6 +1,11 @@
 package api
 
-import "github.com/megamsys/vertice/auth"
+import (
+	"crypto/subtle"
+
+	"github.com/megamsys/vertice/auth"
+	"github.com/megamsys/vertice/carton"
+)
 
 type Token struct {
 	Token     string
@@ -26,3 +31,28 @@ func Auth(t string) (auth.Token, error) {
 	}
 	return &tt, nil
 }
+
+// apiKeyOf is the api key of the account of email.
+var apiKeyOf = func(email string) (string, error) {
+	act, err := carton.NewAccounts(email)
+	if err != nil {
+		return "", err
+	}
+	return act.ApiKey, nil
+}
+
+// AuthAccount validates the token as the api key of the account of email.
+func AuthAccount(token, email string) (auth.Token, error) {
+	key, err := auth.ParseToken(token)
+	if err != nil {
+		return nil, err
+	}
+	want, err := apiKeyOf(email)
+	if err != nil || want == "" {
+		return nil, auth.ErrInvalidToken
+	}
+	if subtle.ConstantTimeCompare([]byte(key), []byte(want)) != 1 {
+		return nil, auth.ErrInvalidToken
+	}
+	return &Token{Token: key, UserEmail: email}, nil
+}
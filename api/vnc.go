@@ -1,12 +1,127 @@
 package api
 
 import (
-	//"encoding/json"
 	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/megamsys/libgo/errors"
+	"github.com/megamsys/vertice/auth"
+	"github.com/megamsys/vertice/carton"
+	"github.com/megamsys/vertice/govnc"
+	"golang.org/x/net/websocket"
 )
 
-func vnc(w http.ResponseWriter, r *http.Request) error {
+// vncIdleTimeout ends a vnc session nothing went through for this long.
+const vncIdleTimeout = 15 * time.Minute
+
+// vncServer proxies the vnc server of a vm to noVNC in the browser, which
+// asks for the binary subprotocol.
+var vncServer = websocket.Server{Handler: remoteVncHandler, Handshake: vncHandshake}
+
+func vncHandshake(config *websocket.Config, r *http.Request) error {
+	for _, p := range config.Protocol {
+		if p == "binary" {
+			config.Protocol = []string{p}
+			return nil
+		}
+	}
+	config.Protocol = nil
+	return nil
+}
+
+func remoteVncHandler(ws *websocket.Conn) {
+	var httpErr *errors.HTTP
+	defer func() {
+		if httpErr != nil {
+			ws.Write([]byte("Error: " + httpErr.Message + "\n"))
+			ws.Close()
+		}
+	}()
+	r := ws.Request()
+	email := r.URL.Query().Get(":email")
+	if httpErr = wsAuth(r, email); httpErr != nil {
+		return
+	}
+	vh, err := vncHostOf(r.URL.Query().Get(":id"), email)
+	if err != nil {
+		httpErr = &errors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+		return
+	}
+	server, err := govnc.Connect(vh)
+	if err != nil {
+		httpErr = &errors.HTTP{Code: http.StatusBadGateway, Message: err.Error()}
+		return
+	}
+	ws.PayloadType = websocket.BinaryFrame
+	// the session outlives the read and write timeouts of httpd, it ends
+	// when idle instead.
+	ws.SetDeadline(time.Time{})
+	VncTracker.add(ws)
+	defer VncTracker.remove(ws)
+	if err = govnc.Proxy(ws, server, vncIdleTimeout); err != nil {
+		log.Debugf("vnc session of %s to %s ended : %s", email, vh.Addr(), err)
+	}
+}
 
+// wsAuth authenticates the caller of a websocket of email, whose token is the
+// api key of the account. A browser can't set the headers of a websocket, so
+// the token can be in the query too.
+func wsAuth(r *http.Request, email string) *errors.HTTP {
+	token := r.Header.Get("Authorization")
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+	if token == "" {
+		return &errors.HTTP{Code: http.StatusUnauthorized, Message: "no token provided"}
+	}
+	if _, err := AuthAccount(token, email); err != nil {
+		return &errors.HTTP{Code: http.StatusUnauthorized, Message: auth.ErrInvalidToken.Error()}
+	}
 	return nil
+}
+
+// vncHostOf is the vnc server of the vm of the assembly id.
+func vncHostOf(id, email string) (*govnc.VncHost, error) {
+	asm, err := carton.NewAssembly(id, email, "")
+	if err != nil {
+		return nil, err
+	}
+	host, port := asm.VNC()
+	return &govnc.VncHost{IpAddress: host, Port: port, Password: asm.VNCPassword()}, nil
+}
 
+type vncSessionTracker struct {
+	sync.Mutex
+	conn map[*websocket.Conn]struct{}
 }
+
+func (t *vncSessionTracker) add(ws *websocket.Conn) {
+	t.Lock()
+	defer t.Unlock()
+	if t.conn == nil {
+		t.conn = make(map[*websocket.Conn]struct{})
+	}
+	t.conn[ws] = struct{}{}
+}
+
+func (t *vncSessionTracker) remove(ws *websocket.Conn) {
+	t.Lock()
+	defer t.Unlock()
+	delete(t.conn, ws)
+}
+
+func (t *vncSessionTracker) String() string {
+	return "vnc sessions"
+}
+
+func (t *vncSessionTracker) Shutdown() {
+	t.Lock()
+	defer t.Unlock()
+	for ws := range t.conn {
+		ws.Close()
+	}
+}
+
+var VncTracker vncSessionTracker
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"golang.org/x/net/websocket"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestVncAuth(c *check.C) {
+	keys := map[string]string{"info@megam.io": "LMIKPTFZ", "other@megam.io": "QWERTYUI"}
+	old := apiKeyOf
+	apiKeyOf = func(email string) (string, error) {
+		if k, ok := keys[email]; ok {
+			return k, nil
+		}
+		return "", errors.New("account " + email + " not found")
+	}
+	defer func() { apiKeyOf = old }()
+	r, _ := http.NewRequest("GET", "/vnc/info@megam.io/AMS01/ASM01", nil)
+	c.Assert(wsAuth(r, "info@megam.io").Code, check.Equals, http.StatusUnauthorized)
+	r, _ = http.NewRequest("GET", "/vnc/info@megam.io/AMS01/ASM01?token=LMIKPTFZ", nil)
+	c.Assert(wsAuth(r, "info@megam.io"), check.IsNil)
+	c.Assert(wsAuth(r, "other@megam.io").Code, check.Equals, http.StatusUnauthorized)
+	c.Assert(wsAuth(r, "nobody@megam.io").Code, check.Equals, http.StatusUnauthorized)
+	r, _ = http.NewRequest("GET", "/vnc/info@megam.io/AMS01/ASM01?token=aaaa", nil)
+	c.Assert(wsAuth(r, "info@megam.io").Code, check.Equals, http.StatusUnauthorized)
+	r, _ = http.NewRequest("GET", "/vnc/info@megam.io/AMS01/ASM01", nil)
+	r.Header.Set("Authorization", "token LMIKPTFZ")
+	c.Assert(wsAuth(r, "info@megam.io"), check.IsNil)
+}
+
+func (s *S) TestVncHandshake(c *check.C) {
+	config := &websocket.Config{Protocol: []string{"base64", "binary"}}
+	c.Assert(vncHandshake(config, nil), check.IsNil)
+	c.Assert(config.Protocol, check.DeepEquals, []string{"binary"})
+	config = &websocket.Config{Protocol: []string{"base64"}}
+	c.Assert(vncHandshake(config, nil), check.IsNil)
+	c.Assert(config.Protocol, check.HasLen, 0)
+}
@@ -43,9 +43,12 @@ const (
 	SSHKEY                = "sshkey"
 	VNCPORT               = "vncport"
 	VNCHOST               = "vnchost"
+	VNCPASS               = "vncpass"
 	INSTANCE_ID           = "instance_id"
+	SSH_HOSTKEY           = "ssh_hostkey"
 	INSTANCE_PORTS        = "instance_ports"
 	BACKUP                = "backup"
+	TRANSACTIONAL         = "transactional"
 	YES                   = "yes"
 	REGION                = "region"
 	QUOTAID               = "quota_id"
@@ -194,30 +197,32 @@ func mkCarton(aies, ay, email string) (*Carton, error) {
 	}
 
 	c := &Carton{
-		Id:           ay,   //assembly id
-		CartonsId:    aies, //assemblies id
-		OrgId:        a.OrgId,
-		Name:         a.Name,
-		Tosca:        a.Tosca,
-		AccountId:    a.AccountId,
-		Authority:    act.States.Authority,
-		ApiArgs:      args,
-		ImageVersion: a.imageVersion(),
-		DomainName:   a.domain(),
-		SSH:          a.newSSH(),
-		Provider:     a.provider(),
-		PublicIp:     a.publicIp(),
-		Region:       a.region(),
-		Vnets:        a.vnets(),
-		InstanceId:   a.instanceId(),
-		PolicyOps:    a.policyOps(),
-		Backup:       a.isBackup(),
-		ImageName:    a.imageName(),
-		StorageType:  a.storageType(),
-		QuotaId:      a.quotaID(),
-		Boxes:        &b,
-		Status:       utils.Status(a.Status),
-		State:        utils.State(a.State),
+		Id:            ay,   //assembly id
+		CartonsId:     aies, //assemblies id
+		OrgId:         a.OrgId,
+		Name:          a.Name,
+		Tosca:         a.Tosca,
+		AccountId:     a.AccountId,
+		Authority:     act.States.Authority,
+		ApiArgs:       args,
+		ImageVersion:  a.imageVersion(),
+		DomainName:    a.domain(),
+		SSH:           a.newSSH(),
+		Provider:      a.provider(),
+		PublicIp:      a.publicIp(),
+		Region:        a.region(),
+		Vnets:         a.vnets(),
+		InstanceId:    a.instanceId(),
+		PolicyOps:     a.policyOps(),
+		Backup:        a.isBackup(),
+		ImageName:     a.imageName(),
+		StorageType:   a.storageType(),
+		QuotaId:       a.quotaID(),
+		Boxes:         &b,
+		Status:        utils.Status(a.Status),
+		State:         utils.State(a.State),
+		OnFailure:     a.onFailure(),
+		Transactional: a.isTransactional(),
 	}
 	if len(a.flavorId()) > 0 {
 		comp, err := a.newCompute()
@@ -359,6 +364,10 @@ func (a *Assembly) trigger_event(status utils.Status) error {
 }
 
 func DoneNotify(box *provision.Box, w io.Writer, evtAction alerts.EventAction, message string) error {
+	if box.Muted && (evtAction == alerts.FAILURE || evtAction == alerts.DESTROYED) {
+		// the carton notifies the failure once, for all its boxes.
+		return nil
+	}
 	fmt.Fprintf(w, lb.W(lb.DEPLOY, lb.INFO, fmt.Sprintf("--- done %s box ", box.GetFullName())))
 	mi := make(map[string]string)
 	mi[constants.VERTNAME] = box.GetFullName()
@@ -404,6 +413,16 @@ func (a *Assembly) NukeAndSetOutputs(m map[string][]string) error {
 	return nil
 }
 
+//update inputs in scylla, nuke the matching keys available
+func (a *Assembly) NukeAndSetInputs(m map[string][]string) error {
+	if len(m) > 0 {
+		log.Debugf("nuke and set inputs in scylla [%s]", m)
+		a.Inputs.NukeAndSet(m) //just nuke the matching input key:
+		return a.update()
+	}
+	return provision.ErrNoOutputsFound
+}
+
 func (a *Assembly) Delete(asmid string) error {
 	args := newArgs(a.AccountId, a.OrgId)
 	cl := api.NewClient(args, "/assembly/"+asmid)
@@ -495,6 +514,14 @@ func (a *Assembly) isBackup() bool {
 	return (strings.TrimSpace(a.Inputs.Match(BACKUP)) == YES)
 }
 
+func (a *Assembly) isTransactional() bool {
+	return (strings.TrimSpace(a.Inputs.Match(TRANSACTIONAL)) == YES)
+}
+
+func (a *Assembly) onFailure() string {
+	return strings.ToLower(strings.TrimSpace(a.Inputs.Match(ON_FAILURE)))
+}
+
 func (a *Assembly) flavorId() string {
 	return strings.TrimSpace(a.Inputs.Match(FLAVOR_ID))
 }
@@ -573,6 +600,38 @@ func (a *Assembly) GetContainerMemoryCost() string {
 	return a.Inputs.Match(CONTAINER_MEMORY_COST)
 }
 
+// VNC is the host and port of the vnc server of the vm.
+func (a *Assembly) VNC() (string, string) {
+	return a.vncHost(), a.vncPort()
+}
+
+// VNCPassword is the password of the vnc server of the vm, when it has one.
+func (a *Assembly) VNCPassword() string {
+	return a.Outputs.Match(VNCPASS)
+}
+
+// CloneImageId is the image made to boot a clone from a snapshot, or in
+// another region than its backup.
+func (a *Assembly) CloneImageId() string {
+	return a.Outputs.Match(CLONE_IMAGE_ID)
+}
+
+// InstanceId is the id of the vm of the assembly.
+func (a *Assembly) InstanceId() string {
+	return a.instanceId()
+}
+
+// SSHHostKey is the instance id of the vm and the ssh host key trusted for
+// it, in authorized_keys format.
+func (a *Assembly) SSHHostKey() (string, string) {
+	for _, o := range a.Outputs {
+		if o.K == SSH_HOSTKEY && len(o.V) == 2 {
+			return o.V[0], o.V[1]
+		}
+	}
+	return "", ""
+}
+
 func (a *Assembly) HostName() string {
 	return a.Outputs.Match(VNCHOST)
 }
@@ -675,8 +734,11 @@ func (a *Assembly) isStopped() bool {
 	return a.State == constants.STOPPED || a.State == constants.STOPPING
 }
 
+// isSuspended tells if the box is suspended. A suspended container keeps the
+// state stopped, only its status says it is paused.
 func (a *Assembly) isSuspended() bool {
-	return a.State == constants.SUSPENDED || a.State == constants.SUSPENDING
+	return a.State == constants.SUSPENDED || a.State == constants.SUSPENDING ||
+		a.Status == constants.StatusSuspended.String()
 }
 
 func (a *Assembly) IsContainer() bool {
@@ -2,6 +2,7 @@ package carton
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	log "github.com/Sirupsen/logrus"
 	"github.com/megamsys/libgo/api"
@@ -58,14 +59,20 @@ func (s *Backups) String() string {
 }
 
 // ChangeState runs a state increment of a machine or a container.
-func CreateImage(opts *DiskOpts) error {
+func CreateImage(ctx context.Context, opts *DiskOpts) error {
+	if err := opts.B.Can(provision.CYCLE_BACKUP_CREATE); err != nil {
+		return err
+	}
+	if err := capable(opts.B, provision.CYCLE_BACKUP_CREATE); err != nil {
+		return err
+	}
 	var outBuffer bytes.Buffer
 	start := time.Now()
 	logWriter := lw.LogWriter{Box: opts.B}
 	logWriter.Async()
 	defer logWriter.Close()
 	writer := io.MultiWriter(&outBuffer, &logWriter)
-	err := ProvisionerMap[opts.B.Provider].SaveImage(opts.B, writer)
+	err := ProvisionerMap[opts.B.Provider].SaveImage(ctx, opts.B, writer)
 	elapsed := time.Since(start)
 
 	if err != nil {
@@ -80,14 +87,20 @@ func CreateImage(opts *DiskOpts) error {
 }
 
 // ChangeState runs a state increment of a machine or a container.
-func DeleteImage(opts *DiskOpts) error {
+func DeleteImage(ctx context.Context, opts *DiskOpts) error {
+	if err := opts.B.Can(provision.CYCLE_BACKUP_DELETE); err != nil {
+		return err
+	}
+	if err := capable(opts.B, provision.CYCLE_BACKUP_DELETE); err != nil {
+		return err
+	}
 	var outBuffer bytes.Buffer
 	start := time.Now()
 	logWriter := lw.LogWriter{Box: opts.B}
 	logWriter.Async()
 	defer logWriter.Close()
 	writer := io.MultiWriter(&outBuffer, &logWriter)
-	err := ProvisionerMap[opts.B.Provider].DeleteImage(opts.B, writer)
+	err := ProvisionerMap[opts.B.Provider].DeleteImage(ctx, opts.B, writer)
 	elapsed := time.Since(start)
 
 	if err != nil {
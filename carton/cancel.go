@@ -0,0 +1,72 @@
+/*
+** Copyright [2013-2017] [Megam Systems]
+**
+** Licensed under the Apache License, Version 2.0 (the "License");
+** you may not use this file except in compliance with the License.
+** You may obtain a copy of the License at
+**
+** http://www.apache.org/licenses/LICENSE-2.0
+**
+** Unless required by applicable law or agreed to in writing, software
+** distributed under the License is distributed on an "AS IS" BASIS,
+** WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+** See the License for the specific language governing permissions and
+** limitations under the License.
+ */
+package carton
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrCancelled is the outcome of an operation cancelled by a control/cancel request.
+var ErrCancelled = errors.New("operation cancelled")
+
+type inflightOp struct {
+	cancel    context.CancelFunc
+	cancelled bool
+}
+
+// inflight are the cancellable operations, keyed on the assembly (Requests.CatId).
+// The dispatcher runs one operation per assembly at a time, so a key has
+// atmost one.
+var inflight = struct {
+	sync.Mutex
+	ops map[string]*inflightOp
+}{ops: make(map[string]*inflightOp)}
+
+// withCancel derives a context for the operation on key, which can be
+// cancelled by Cancel(key). The returned func must be called when the
+// operation is over, and tells if it was cancelled through Cancel.
+func withCancel(parent context.Context, key string) (context.Context, func() bool) {
+	ctx, cancel := context.WithCancel(parent)
+	op := &inflightOp{cancel: cancel}
+	inflight.Lock()
+	inflight.ops[key] = op
+	inflight.Unlock()
+	return ctx, func() bool {
+		inflight.Lock()
+		defer inflight.Unlock()
+		if inflight.ops[key] == op {
+			delete(inflight.ops, key)
+		}
+		cancel()
+		return op.cancelled
+	}
+}
+
+// Cancel cancels the operation running on the assembly key, and tells if
+// there was one.
+func Cancel(key string) bool {
+	inflight.Lock()
+	defer inflight.Unlock()
+	op, ok := inflight.ops[key]
+	if !ok {
+		return false
+	}
+	op.cancelled = true
+	op.cancel()
+	return true
+}
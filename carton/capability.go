@@ -0,0 +1,55 @@
+/*
+** Copyright [2013-2017] [Megam Systems]
+**
+** Licensed under the Apache License, Version 2.0 (the "License");
+** you may not use this file except in compliance with the License.
+** You may obtain a copy of the License at
+**
+** http://www.apache.org/licenses/LICENSE-2.0
+**
+** Unless required by applicable law or agreed to in writing, software
+** distributed under the License is distributed on an "AS IS" BASIS,
+** WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+** See the License for the specific language governing permissions and
+** limitations under the License.
+ */
+package carton
+
+import (
+	"fmt"
+	"io"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/megamsys/libgo/events/alerts"
+	lw "github.com/megamsys/libgo/writer"
+	lb "github.com/megamsys/vertice/logbox"
+	"github.com/megamsys/vertice/provision"
+)
+
+// capable returns a provision.CapabilityError when the provisioner of the box
+// can't do what op needs, and tells the owner of the box it failed.
+func capable(b *provision.Box, op provision.BoxOp) error {
+	err := provision.NeedFor(b.Provider, ProvisionerMap[b.Provider], op)
+	if err == nil {
+		return nil
+	}
+	log.Warnf("  %s on %s : %s", op, b.GetFullName(), err)
+	logWriter := lw.LogWriter{Box: b}
+	logWriter.Async()
+	defer logWriter.Close()
+	fmt.Fprintf(&logWriter, lb.W(lb.UPDATING, lb.ERROR, fmt.Sprintf("--- %s %s --> %s", op, b.GetFullName(), err)))
+	if nerr := DoneNotify(b, &logWriter, alerts.FAILURE, err.Error()); nerr != nil {
+		log.Errorf("  unable to notify the failure of %s : %s", b.GetFullName(), nerr)
+	}
+	return err
+}
+
+// ExecuteCommandOnce runs the command in the box, when its provisioner can
+// run commands in boxes.
+func ExecuteCommandOnce(stdout, stderr io.Writer, b *provision.Box, cmd string, args ...string) error {
+	p := ProvisionerMap[b.Provider]
+	if err := provision.Need(b.Provider, p, provision.CAP_EXEC); err != nil {
+		return err
+	}
+	return p.ExecuteCommandOnce(stdout, stderr, b, cmd, args...)
+}
@@ -0,0 +1,65 @@
+package carton
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/megamsys/libgo/utils"
+	"github.com/megamsys/vertice/provision"
+	"gopkg.in/check.v1"
+)
+
+type capableProvisioner struct {
+	planningProvisioner
+}
+
+func (p *capableProvisioner) Capabilities() []provision.Capability {
+	return []provision.Capability{provision.CAP_RESIZE}
+}
+
+type execProvisioner struct {
+	planningProvisioner
+	ran []string
+}
+
+func (p *execProvisioner) ExecuteCommandOnce(stdout, stderr io.Writer, box *provision.Box, cmd string, args ...string) error {
+	p.ran = append(p.ran, cmd)
+	return nil
+}
+
+func (s *S) TestNeedForCapabilities(c *check.C) {
+	fake := &capableProvisioner{}
+	err := provision.NeedFor("capable", fake, provision.CYCLE_SNAP_CREATE)
+	c.Assert(provision.IsCapabilityError(err), check.Equals, true)
+	c.Assert(err, check.ErrorMatches, "provisioner capable doesn't support snapshots: .*")
+	c.Assert(provision.NeedFor("capable", fake, provision.CYCLE_RESIZE), check.IsNil)
+	c.Assert(provision.NeedFor("capable", fake, provision.CYCLE_STOP), check.IsNil)
+	// a provisioner that declares nothing is trusted.
+	c.Assert(provision.NeedFor("planning", &planningProvisioner{}, provision.CYCLE_SNAP_CREATE), check.IsNil)
+}
+
+func (s *S) TestPlanUnsupportedOp(c *check.C) {
+	fake := &capableProvisioner{}
+	ProvisionerMap["capable"] = fake
+	defer delete(ProvisionerMap, "capable")
+	boxes := []provision.Box{
+		{Id: "COM01", CartonName: "bigbang", DomainName: "megambox.com", Provider: "capable", State: utils.StateRunning},
+	}
+	plans := planCartons(Cartons{&Carton{Boxes: &boxes}}, provision.CYCLE_DISK_ATTACH)
+	c.Assert(plans, check.HasLen, 1)
+	c.Assert(plans[0].Error, check.Matches, "provisioner capable doesn't support disks: .*")
+	c.Assert(fake.planned, check.HasLen, 0)
+}
+
+func (s *S) TestExecuteCommandOnceNeedsExec(c *check.C) {
+	ProvisionerMap["capable"] = &capableProvisioner{}
+	run := &execProvisioner{}
+	ProvisionerMap["exec"] = run
+	defer delete(ProvisionerMap, "capable")
+	defer delete(ProvisionerMap, "exec")
+	var buf bytes.Buffer
+	err := ExecuteCommandOnce(&buf, &buf, &provision.Box{Provider: "capable"}, "ls", "-l")
+	c.Assert(err, check.ErrorMatches, "provisioner capable doesn't support exec: .*")
+	c.Assert(ExecuteCommandOnce(&buf, &buf, &provision.Box{Provider: "exec"}, "ls", "-l"), check.IsNil)
+	c.Assert(run.ran, check.DeepEquals, []string{"ls"})
+}
@@ -1,6 +1,8 @@
 package carton
 
 import (
+	"context"
+
 	log "github.com/Sirupsen/logrus"
 	"github.com/megamsys/libgo/api"
 	"github.com/megamsys/libgo/utils"
@@ -43,6 +45,12 @@ type Carton struct {
 	PolicyOps    *provision.PolicyOps
 	Status       utils.Status
 	State        utils.State
+	OnFailure    string
+	// Transactional cartons destroy the boxes deployed, when any of them fails.
+	Transactional bool
+	// RequestId is the request working on the carton. The boxes it served are
+	// journalled, so a retry of the request skips them.
+	RequestId string
 }
 
 //Global provisioners set by the subd daemons.
@@ -104,48 +112,45 @@ func (c *Carton) toBox() error { //assemblies id.
 	return nil
 }
 
-// Deploy carton, which basically deploys the boxes.
-func (c *Carton) Deploy() error {
-	for _, box := range *c.Boxes {
-		err := Deploy(&DeployOpts{B: &box})
-		if err != nil {
-			return err
-		}
+//stamps the operation on the boxes, so the provisioners can record their progress in it.
+func (c *Carton) track(op *provision.Operation) {
+	if c.Boxes == nil {
+		return
+	}
+	for i := range *c.Boxes {
+		(*c.Boxes)[i].OperationId = op.Id
 	}
-	return nil
 }
 
-func (c *Carton) Running() error {
-	for _, box := range *c.Boxes {
-		err := Running(&DeployOpts{B: &box})
-		if err != nil {
-			return err
-		}
+// Deploy carton, which basically deploys the boxes.
+func (c *Carton) Deploy(ctx context.Context) error {
+	if c.Transactional {
+		return c.deployOrRollback(ctx)
 	}
-	return nil
+	return c.eachBox(ctx, func(ctx context.Context, box *provision.Box) error {
+		return Deploy(ctx, &DeployOpts{B: box})
+	})
+}
+
+func (c *Carton) Running(ctx context.Context) error {
+	return c.eachBox(ctx, func(ctx context.Context, box *provision.Box) error {
+		return Running(ctx, &DeployOpts{B: box})
+	})
 }
 
 // Destroys a carton, which deletes its boxes.
-func (c *Carton) Destroy() error {
-	for _, box := range *c.Boxes {
-		err := Destroy(&DestroyOpts{B: &box})
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+func (c *Carton) Destroy(ctx context.Context) error {
+	return c.eachBox(ctx, func(ctx context.Context, box *provision.Box) error {
+		return Destroy(ctx, &DestroyOpts{B: box})
+	})
 }
 
 // moves the state to the desired state
 // changing the boxes state to StatusStateup.
-func (c *Carton) Stateup() error {
-	for _, box := range *c.Boxes {
-		err := ChangeState(&StateChangeOpts{B: &box, Changed: utils.StatusStateupped})
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+func (c *Carton) Stateup(ctx context.Context) error {
+	return c.eachBox(ctx, func(ctx context.Context, box *provision.Box) error {
+		return ChangeState(ctx, &StateChangeOpts{B: box, Changed: utils.StatusStateupped})
+	})
 }
 
 // Available returns true if at least one of N boxes which is started
@@ -158,163 +163,170 @@ func (c *Carton) Available() bool {
 	return false
 }
 
-//upgrade run thru all the ops.
-func (c *Carton) Upgrade() error {
-	for _, box := range *c.Boxes {
-		err := NewUpgradeable(&box).Upgrade()
+// Upgrade rebuilds the git boxes at the commit and replaces what they run.
+func (c *Carton) Upgrade(ctx context.Context, commit string) error {
+	return c.eachBox(ctx, func(ctx context.Context, box *provision.Box) error {
+		u := NewUpgradeable(box)
+		u.Commit = commit
+		err := u.Upgrade(ctx)
 		if err != nil {
 			log.Errorf("Unable to upgrade box : %s", err)
-			return err
 		}
-	}
-	return nil
+		return err
+	})
 }
 
-func (c *Carton) NetworkUpdate() error {
-	for _, box := range *c.Boxes {
-		err := NetworkUpdate(&box)
+func (c *Carton) NetworkUpdate(ctx context.Context) error {
+	return c.eachBox(ctx, func(ctx context.Context, box *provision.Box) error {
+		err := NetworkUpdate(ctx, box)
 		if err != nil {
 			log.Errorf("Unable to upgrade box : %s", err)
-			return err
 		}
+		return err
+	})
+}
+
+// Rollback redeploys the boxes from an earlier deploy.
+func (c *Carton) Rollback(ctx context.Context, deployId string) error {
+	return c.eachBox(ctx, func(ctx context.Context, box *provision.Box) error {
+		return Rollback(ctx, &RollbackOpts{B: box, DeployId: deployId})
+	})
+}
+
+// Resize changes the boxes to the flavor.
+func (c *Carton) Resize(ctx context.Context, flavorId string) error {
+	return c.eachBox(ctx, func(ctx context.Context, box *provision.Box) error {
+		return Resize(ctx, &ResizeOpts{B: box, FlavorId: flavorId})
+	})
+}
+
+// Scale runs the boxes in units containers.
+func (c *Carton) Scale(ctx context.Context, units int) error {
+	return c.eachBox(ctx, func(ctx context.Context, box *provision.Box) error {
+		return Scale(ctx, &ScaleOpts{B: box, Units: units})
+	})
+}
+
+// Migrate moves the boxes to another host.
+func (c *Carton) Migrate(ctx context.Context, to provision.MigrateTo) error {
+	return c.eachBox(ctx, func(ctx context.Context, box *provision.Box) error {
+		return Migrate(ctx, &MigrateOpts{B: box, To: to})
+	})
+}
+
+// Clone launches the boxes of the carton as new vms, from the snapshot or
+// backup id.
+func (c *Carton) Clone(ctx context.Context, kind, id string) error {
+	from, err := NewCloneSource(kind, id, c.AccountId)
+	if err != nil {
+		return err
 	}
-	return nil
+	return c.eachBox(ctx, func(ctx context.Context, box *provision.Box) error {
+		return Clone(ctx, &CloneOpts{B: box, From: *from})
+	})
 }
 
 // starts box
-func (c *Carton) Start() error {
-	for _, box := range *c.Boxes {
-		err := Start(&LifecycleOpts{B: &box})
+func (c *Carton) Start(ctx context.Context) error {
+	return c.eachBox(ctx, func(ctx context.Context, box *provision.Box) error {
+		err := Start(ctx, &LifecycleOpts{B: box})
 		if err != nil {
 			log.Errorf("Unable to start the box  %s", err)
-			return err
 		}
-	}
-	return nil
+		return err
+	})
 }
 
 // stops the box
-func (c *Carton) Stop(hard bool) error {
-	for _, box := range *c.Boxes {
-		err := Stop(&LifecycleOpts{B: &box, Hard: hard})
+func (c *Carton) Stop(ctx context.Context, hard bool) error {
+	return c.eachBox(ctx, func(ctx context.Context, box *provision.Box) error {
+		err := Stop(ctx, &LifecycleOpts{B: box, Hard: hard})
 		if err != nil {
 			log.Errorf("Unable to stop the box %s", err)
-			return err
 		}
-	}
-	return nil
+		return err
+	})
 }
 
 // suspends the box
-func (c *Carton) Suspend() error {
-	for _, box := range *c.Boxes {
-		err := SuspendBox(&LifecycleOpts{B: &box})
+func (c *Carton) Suspend(ctx context.Context) error {
+	return c.eachBox(ctx, func(ctx context.Context, box *provision.Box) error {
+		err := SuspendBox(ctx, &LifecycleOpts{B: box})
 		if err != nil {
 			log.Errorf("Unable to suspend the box %s", err)
-			return err
 		}
-	}
-	return nil
+		return err
+	})
 }
 
 // restarts the box
-func (c *Carton) Restart(hard bool) error {
-	for _, box := range *c.Boxes {
-		err := Restart(&LifecycleOpts{B: &box, Hard: hard})
+func (c *Carton) Restart(ctx context.Context, hard bool) error {
+	return c.eachBox(ctx, func(ctx context.Context, box *provision.Box) error {
+		err := Restart(ctx, &LifecycleOpts{B: box, Hard: hard})
 		if err != nil {
 			log.Errorf("Unable to restart the box %s", err)
-			return err
 		}
-	}
-	return nil
+		return err
+	})
 }
 
 // Backup Create a carton, which creates an image by current state of its box.
 // Create new backup image from public url
-func (c *Carton) CreateImage() error {
-	for _, box := range *c.Boxes {
-		err := CreateImage(&DiskOpts{B: &box})
+func (c *Carton) CreateImage(ctx context.Context) error {
+	return c.eachBox(ctx, func(ctx context.Context, box *provision.Box) error {
+		err := CreateImage(ctx, &DiskOpts{B: box})
 		if err != nil {
 			log.Errorf("Unable to save image the box %s", err)
-			return err
 		}
-	}
-	return nil
+		return err
+	})
 }
 
 // SnapDelete a carton, which removes an existing image created from state of its box.
-func (c *Carton) DeleteImage() error {
-	for _, box := range *c.Boxes {
-		err := DeleteImage(&DiskOpts{B: &box})
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+func (c *Carton) DeleteImage(ctx context.Context) error {
+	return c.eachBox(ctx, func(ctx context.Context, box *provision.Box) error {
+		return DeleteImage(ctx, &DiskOpts{B: box})
+	})
 }
 
 // SnapCreate a carton, which creates an image by current state of its box.
-func (c *Carton) CreateSnapshot() error {
-	for _, box := range *c.Boxes {
-		err := CreateSnapshot(&DiskOpts{B: &box})
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+func (c *Carton) CreateSnapshot(ctx context.Context) error {
+	return c.eachBox(ctx, func(ctx context.Context, box *provision.Box) error {
+		return CreateSnapshot(ctx, &DiskOpts{B: box})
+	})
 }
 
 // SnapCreate a carton, which creates an image by current state of its box.
-func (c *Carton) SnapshotSaveAs() error {
-	for _, box := range *c.Boxes {
-		err := SnapshotSaveAs(&DiskOpts{B: &box})
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+func (c *Carton) SnapshotSaveAs(ctx context.Context) error {
+	return c.eachBox(ctx, func(ctx context.Context, box *provision.Box) error {
+		return SnapshotSaveAs(ctx, &DiskOpts{B: box})
+	})
 }
 
 // SnapDelete a carton, which removes an existing image created from state of its box.
-func (c *Carton) DeleteSnapshot() error {
-	for _, box := range *c.Boxes {
-		err := DeleteSnapshot(&DiskOpts{B: &box})
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+func (c *Carton) DeleteSnapshot(ctx context.Context) error {
+	return c.eachBox(ctx, func(ctx context.Context, box *provision.Box) error {
+		return DeleteSnapshot(ctx, &DiskOpts{B: box})
+	})
 }
 
 // SnapDelete a carton, which removes an existing image created from state of its box.
-func (c *Carton) RestoreSnapshot() error {
-	for _, box := range *c.Boxes {
-		err := RestoreSnapshot(&DiskOpts{B: &box})
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+func (c *Carton) RestoreSnapshot(ctx context.Context) error {
+	return c.eachBox(ctx, func(ctx context.Context, box *provision.Box) error {
+		return RestoreSnapshot(ctx, &DiskOpts{B: box})
+	})
 }
 
 // AttachDisk a carton, which creates a disk storage by current state of its box.
-func (c *Carton) AttachDisk() error {
-	for _, box := range *c.Boxes {
-		err := AttachDisk(&DiskOpts{B: &box})
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+func (c *Carton) AttachDisk(ctx context.Context) error {
+	return c.eachBox(ctx, func(ctx context.Context, box *provision.Box) error {
+		return AttachDisk(ctx, &DiskOpts{B: box})
+	})
 }
 
 // DetachDisk a carton, which removes an existing disk storage by current state of its box.
-func (c *Carton) DetachDisk() error {
-	for _, box := range *c.Boxes {
-		err := DetachDisk(&DiskOpts{B: &box})
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+func (c *Carton) DetachDisk(ctx context.Context) error {
+	return c.eachBox(ctx, func(ctx context.Context, box *provision.Box) error {
+		return DetachDisk(ctx, &DiskOpts{B: box})
+	})
 }
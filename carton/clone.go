@@ -0,0 +1,121 @@
+/*
+** Copyright [2013-2017] [Megam Systems]
+**
+** Licensed under the Apache License, Version 2.0 (the "License");
+** you may not use this file except in compliance with the License.
+** You may obtain a copy of the License at
+**
+** http://www.apache.org/licenses/LICENSE-2.0
+**
+** Unless required by applicable law or agreed to in writing, software
+** distributed under the License is distributed on an "AS IS" BASIS,
+** WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+** See the License for the specific language governing permissions and
+** limitations under the License.
+ */
+package carton
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	log "github.com/Sirupsen/logrus"
+	lw "github.com/megamsys/libgo/writer"
+	lb "github.com/megamsys/vertice/logbox"
+	"github.com/megamsys/vertice/provision"
+)
+
+// the outputs of a cloned assembly that link it to what it was cloned from,
+// and the image made to boot it that goes along with it.
+const (
+	CLONED_FROM      = "cloned_from"
+	CLONED_FROM_TYPE = "cloned_from_type"
+	CLONED_FROM_ASM  = "cloned_from_asm"
+	CLONE_IMAGE_ID   = "clone_image_id"
+)
+
+type CloneOpts struct {
+	B    *provision.Box
+	From provision.CloneSource
+}
+
+// NewCloneSource returns the snapshot or backup id, with the vm and region
+// it was taken in.
+func NewCloneSource(kind, id, email string) (*provision.CloneSource, error) {
+	from := &provision.CloneSource{Kind: kind, Id: id}
+	switch kind {
+	case provision.CLONE_SNAPSHOT:
+		s, err := GetSnap(id, email)
+		if err != nil {
+			return nil, err
+		}
+		if !s.IsAlive() || s.SnapId == "" {
+			return nil, fmt.Errorf("snapshot %s isn't ready to clone, status %s", id, s.Status)
+		}
+		from.Name, from.AssemblyId, from.DiskId, from.SnapId = s.Name, s.AssemblyId, s.DiskId, s.SnapId
+	case provision.CLONE_BACKUP:
+		b, err := GetBackup(id, email)
+		if err != nil {
+			return nil, err
+		}
+		if b.ImageId == "" {
+			return nil, fmt.Errorf("backup %s has no image to clone, status %s", id, b.Status)
+		}
+		from.Name, from.AssemblyId, from.ImageId, from.Region = b.Name, b.AssemblyId, b.ImageId, b.region()
+	default:
+		return nil, fmt.Errorf("can't clone from %s %s", kind, id)
+	}
+	if from.AssemblyId != "" {
+		asm, err := NewAssembly(from.AssemblyId, email, "")
+		if err != nil {
+			return nil, err
+		}
+		from.InstanceId = asm.instanceId()
+		if from.Region == "" {
+			from.Region = asm.region()
+		}
+	}
+	if from.Kind == provision.CLONE_SNAPSHOT && from.InstanceId == "" {
+		return nil, fmt.Errorf("snapshot %s has no vm to clone from", id)
+	}
+	return from, nil
+}
+
+// Clone launches the box as a new vm from opts.From. Once its up the outputs
+// of the box link back to the snapshot or backup.
+func Clone(ctx context.Context, opts *CloneOpts) error {
+	if err := opts.B.Can(provision.CYCLE_SNAP_CLONE); err != nil {
+		return err
+	}
+	cloner, ok := ProvisionerMap[opts.B.Provider].(provision.Cloner)
+	if !ok {
+		return fmt.Errorf("provisioner %s can't clone %s", opts.B.Provider, opts.B.GetFullName())
+	}
+	logWriter := lw.NewLogWriter(opts.B)
+	defer logWriter.Close()
+	writer := io.MultiWriter(&logWriter)
+	fmt.Fprintf(writer, lb.W(lb.DEPLOY, lb.INFO, fmt.Sprintf("--- clone %s from %s", opts.B.GetFullName(), opts.From.String())))
+	if err := cloner.Clone(ctx, opts.B, opts.From, writer); err != nil {
+		fmt.Fprintf(writer, lb.W(lb.DEPLOY, lb.ERROR, fmt.Sprintf("--- clone %s --> %s", opts.B.GetFullName(), err)))
+		return err
+	}
+	asm, err := NewAssembly(opts.B.CartonId, opts.B.AccountId, opts.B.OrgId)
+	if err != nil {
+		return err
+	}
+	if err = asm.NukeAndSetOutputs(cloneOutputs(opts.From)); err != nil {
+		log.Errorf("  failed to link %s to %s : %s", opts.B.GetFullName(), opts.From.String(), err)
+		return err
+	}
+	fmt.Fprintf(writer, lb.W(lb.DEPLOY, lb.INFO, fmt.Sprintf("--- clone %s from %s OK", opts.B.GetFullName(), opts.From.String())))
+	return nil
+}
+
+func cloneOutputs(from provision.CloneSource) map[string][]string {
+	return map[string][]string{
+		CLONED_FROM:      {from.Id},
+		CLONED_FROM_TYPE: {from.Kind},
+		CLONED_FROM_ASM:  {from.AssemblyId},
+	}
+}
@@ -0,0 +1,51 @@
+package carton
+
+import (
+	"context"
+
+	"github.com/megamsys/libgo/utils"
+	"github.com/megamsys/vertice/provision"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestParseSnapClone(c *check.C) {
+	p, err := ParseRequest(&Requests{CatId: "AMS01", Category: SNAPSHOT, Action: SNAPCLONE, BackupId: "BAK01"})
+	c.Assert(err, check.IsNil)
+	c.Assert(p, check.DeepEquals, SnapCloneProcess{Name: "AMS01", BackupId: "BAK01"})
+	c.Assert(p.String(), check.Equals, "SNAPSHOT CLONE CARTON AMS01 FROM backup BAK01")
+	err = SnapCloneProcess{Name: "AMS01"}.Process(context.Background(), nil)
+	c.Assert(err, check.ErrorMatches, "clone of AMS01 needs a snapshot or a backup")
+}
+
+func (s *S) TestCloneNeedsANewBox(c *check.C) {
+	err := Clone(context.Background(), &CloneOpts{B: &provision.Box{State: utils.StateRunning}})
+	c.Assert(provision.IsLifecycleError(err), check.Equals, true)
+	outs := cloneOutputs(provision.CloneSource{Kind: provision.CLONE_SNAPSHOT, Id: "SNP01", AssemblyId: "ASM01"})
+	c.Assert(outs[CLONED_FROM], check.DeepEquals, []string{"SNP01"})
+	c.Assert(outs[CLONED_FROM_TYPE], check.DeepEquals, []string{"snapshot"})
+	c.Assert(outs[CLONED_FROM_ASM], check.DeepEquals, []string{"ASM01"})
+}
+
+func (s *S) TestValuePayloadKeepsTheOperationFields(c *check.C) {
+	p, err := NewPayload([]byte(`{"id":"RER001","action":"snapclone","cat_id":"ASM0012345678","account_id":"info@megam.io","category":"snapshot",
+		"deploy_id":"DEP01","commit":"5f0c2a1","flavor_id":"FLV01","units":3,"host":"kvm-02","cluster_id":"101","live":true,"snap_id":"SNP01","backup_id":"BAK01"}`))
+	c.Assert(err, check.IsNil)
+	r, err := p.Convert()
+	c.Assert(err, check.IsNil)
+	c.Assert(r, check.DeepEquals, &Requests{
+		Id:        "RER001",
+		Action:    SNAPCLONE,
+		Category:  SNAPSHOT,
+		AccountId: "info@megam.io",
+		CatId:     "ASM0012345678",
+		DeployId:  "DEP01",
+		Commit:    "5f0c2a1",
+		FlavorId:  "FLV01",
+		Units:     3,
+		Host:      "kvm-02",
+		ClusterId: "101",
+		Live:      true,
+		SnapId:    "SNP01",
+		BackupId:  "BAK01",
+	})
+}
@@ -37,6 +37,7 @@ const (
 	HOSTIP        = "vnchost"
 	VERTICE       = "vertice"
 	TRUE          = "true"
+	COMMIT        = "commit"
 )
 
 type Artifacts struct {
@@ -121,7 +122,7 @@ func (c *Component) mkBox() (provision.Box, error) {
 		DomainName:  c.domain(),
 		Envs:        c.envs(),
 		Tosca:       c.Tosca,
-		Commit:      "",
+		Commit:      c.Inputs.Match(COMMIT),
 		Provider:    c.provider(),
 		PublicIp:    c.publicIp(),
 		StorageType: c.storageType(),
@@ -154,6 +155,14 @@ func (c *Component) SetState(state utils.State, email string) error {
 	return c.updateComponent(email, c.OrgId)
 }
 
+// SetCommit records the commit of the repo the component runs.
+func (c *Component) SetCommit(commit, email string) error {
+	m := make(map[string][]string, 1)
+	m[COMMIT] = []string{commit}
+	c.Inputs.NukeAndSet(m)
+	return c.updateComponent(email, c.OrgId)
+}
+
 /*func (c *Component) UpdateOpsRun(opsRan upgrade.OperationsRan) error {
 	mutatedOps := make([]*upgrade.Operation, 0, len(opsRan))
 
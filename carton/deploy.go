@@ -18,6 +18,8 @@ package carton
 
 import (
 	"bytes"
+	"context"
+	"fmt"
 	log "github.com/Sirupsen/logrus"
 	"github.com/megamsys/libgo/cmd"
 	"github.com/megamsys/libgo/events/alerts"
@@ -30,35 +32,72 @@ import (
 	"time"
 )
 
-const DOCKER_TYPE = "dockercontainer"
+const (
+	DOCKER_TYPE = "dockercontainer"
 
+	// the origins of a deploy.
+	ORIGIN_GIT      = "git"
+	ORIGIN_IMAGE    = "image"
+	ORIGIN_BACKUP   = "backup"
+	ORIGIN_ROLLBACK = "rollback"
+)
+
+// DeployData is the record of a deploy of a box.
 type DeployData struct {
-	BoxName     string
-	HookId      string
-	PrivateIp   string
-	PublicIp    string
-	Timestamp   time.Time
-	Duration    time.Duration
-	Commit      string
-	Image       string
-	Origin      string
-	CanRollback bool
+	Id          string        `json:"id"`
+	BoxId       string        `json:"box_id"`
+	BoxName     string        `json:"box_name"`
+	CartonId    string        `json:"carton_id"`
+	Provider    string        `json:"provider"`
+	HookId      string        `json:"hook_id,omitempty"`
+	PrivateIp   string        `json:"private_ip,omitempty"`
+	PublicIp    string        `json:"public_ip,omitempty"`
+	Timestamp   time.Time     `json:"timestamp"`
+	Duration    time.Duration `json:"duration"`
+	Commit      string        `json:"commit,omitempty"`
+	Image       string        `json:"image"`
+	Origin      string        `json:"origin"`
+	Log         string        `json:"log"`
+	Error       string        `json:"error,omitempty"`
+	CanRollback bool          `json:"can_rollback"`
+	// Backup deploys launched the box from a backup image.
+	Backup bool `json:"backup,omitempty"`
 }
 
 type DeployOpts struct {
 	B *provision.Box
+	// Image redeploys the box from an image deployed earlier, a backup image
+	// when Backup.
+	Image  string
+	Backup bool
+	Origin string
+}
+
+func (opts *DeployOpts) origin() string {
+	switch {
+	case opts.Origin != "":
+		return opts.Origin
+	case opts.B.Backup:
+		return ORIGIN_BACKUP
+	case opts.B.Repo != nil && opts.B.Repo.Type != repository.IMAGE && !opts.B.Repo.OneClick:
+		return ORIGIN_GIT
+	}
+	return ORIGIN_IMAGE
 }
 
 // Deploy runs a deployment of an application. It will first try to run an
 // image based deploy, and then fallback to the Git based deployment.
-func Deploy(opts *DeployOpts) error {
+func Deploy(ctx context.Context, opts *DeployOpts) error {
+	if err := opts.B.Can(provision.CYCLE_DEPLOY); err != nil {
+		return err
+	}
 	var outBuffer bytes.Buffer
 	start := time.Now()
 	logWriter := lw.LogWriter{Box: opts.B}
 	logWriter.Async()
 	defer logWriter.Close()
 	writer := io.MultiWriter(&outBuffer, &logWriter)
-	imageId, err := deployToProvisioner(opts, writer)
+	imageId, err := deployToProvisioner(ctx, opts, writer)
 	elapsed := time.Since(start)
 	saveErr := saveDeployData(opts, imageId, outBuffer.String(), elapsed, err)
 	if saveErr != nil {
@@ -70,21 +109,30 @@ func Deploy(opts *DeployOpts) error {
 	return nil
 }
 
-func deployToProvisioner(opts *DeployOpts, writer io.Writer) (string, error) {
+func deployToProvisioner(ctx context.Context, opts *DeployOpts, writer io.Writer) (string, error) {
+	if opts.Image != "" {
+		if deployer, ok := ProvisionerMap[opts.B.Provider].(provision.ImageDeployer); ok {
+			if opts.Backup {
+				return deployer.BackupDeploy(ctx, opts.B, opts.Image, writer)
+			}
+			return deployer.ImageDeploy(ctx, opts.B, opts.Image, writer)
+		}
+		return "", fmt.Errorf("provisioner %s can't deploy %s from an image", opts.B.Provider, opts.B.GetFullName())
+	}
 	if opts.B.Backup {
 		if deployer, ok := ProvisionerMap[opts.B.Provider].(provision.ImageDeployer); ok {
-			return deployer.BackupDeploy(opts.B, opts.B.ImageName, writer)
+			return deployer.BackupDeploy(ctx, opts.B, opts.B.ImageName, writer)
 		}
 	}
 
 	if opts.B.Repo == nil || opts.B.Repo.Type == repository.IMAGE || opts.B.Repo.OneClick {
 		if deployer, ok := ProvisionerMap[opts.B.Provider].(provision.ImageDeployer); ok {
-			return deployer.ImageDeploy(opts.B, image(opts.B), writer)
+			return deployer.ImageDeploy(ctx, opts.B, image(opts.B), writer)
 		}
 	}
 
 	if deployer, ok := ProvisionerMap[opts.B.Provider].(provision.GitDeployer); ok {
-		return deployer.GitDeploy(opts.B, writer)
+		return deployer.GitDeploy(ctx, opts.B, writer)
 	}
 
 	return "Deployed in zzz!", nil
@@ -108,38 +156,37 @@ func saveDeployData(opts *DeployOpts, imageId, dlog string, duration time.Durati
 		cmd.Colorfy(opts.B.GetFullName(), "cyan", "", "bold"),
 		cmd.Colorfy(duration.String(), "green", "", "bold"),
 		cmd.Colorfy(dlog, "yellow", "", ""))
-	//if there are deployments to track as follows in outputs: {} then do it here.
-	//Scylla: code to save the status of a deploy (created.)
-	// deploy :
-	//     name:
-	//     status:
-
-	/*deploy := DeployData {
-		App:       opts.App.Name,
-		Timestamp: time.Now(),
-		Duration:  duration,
-		Commit:    opts.Commit,
-		Image:     imageId,
-		Log:       log,
+	h := Deploys()
+	if h == nil {
+		return nil
 	}
-	if opts.Commit != "" {
-		deploy.Origin = "git"
-	} else if opts.Image != "" {
-		deploy.Origin = "rollback"
-	} else {
-		deploy.Origin = "app-deploy"
+	deploy := &DeployData{
+		BoxId:       opts.B.Id,
+		BoxName:     opts.B.GetFullName(),
+		CartonId:    opts.B.CartonId,
+		Provider:    opts.B.Provider,
+		PublicIp:    opts.B.PublicIp,
+		Timestamp:   time.Now(),
+		Duration:    duration,
+		Commit:      opts.B.Commit,
+		Image:       imageId,
+		Origin:      opts.origin(),
+		Log:         dlog,
+		CanRollback: deployError == nil && imageId != "",
+		Backup:      opts.Backup || (opts.Image == "" && opts.B.Backup),
 	}
 	if deployError != nil {
 		deploy.Error = deployError.Error()
 	}
-	return db.Store(compid or assmid, &struct)
-	*/
-	return nil
+	return h.Add(deploy)
 }
 
 // Deploy runs a deployment of an application. It will first try to run an
 // image based deploy, and then fallback to the Git based deployment.
-func Running(opts *DeployOpts) error {
+func Running(ctx context.Context, opts *DeployOpts) error {
+	if err := opts.B.Can(provision.CYCLE_RUNNING); err != nil {
+		return err
+	}
 	var outBuffer bytes.Buffer
 	var err error
 	logWriter := lw.LogWriter{Box: opts.B}
@@ -148,7 +195,7 @@ func Running(opts *DeployOpts) error {
 	writer := io.MultiWriter(&outBuffer, &logWriter)
 	if deployer, ok := ProvisionerMap[opts.B.Provider].(provision.StateChanger); ok {
 		if strings.Contains(opts.B.Tosca, "windows") {
-			err = deployer.SetRunning(opts.B, writer)
+			err = deployer.SetRunning(ctx, opts.B, writer)
 		} else {
 			err = DoneNotify(opts.B, writer, alerts.RUNNING, "")
 		}
@@ -0,0 +1,218 @@
+/*
+** Copyright [2013-2017] [Megam Systems]
+**
+** Licensed under the Apache License, Version 2.0 (the "License");
+** you may not use this file except in compliance with the License.
+** You may obtain a copy of the License at
+**
+** http://www.apache.org/licenses/LICENSE-2.0
+**
+** Unless required by applicable law or agreed to in writing, software
+** distributed under the License is distributed on an "AS IS" BASIS,
+** WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+** See the License for the specific language governing permissions and
+** limitations under the License.
+ */
+package carton
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/satori/go.uuid"
+)
+
+const (
+	DEPLOYS_DIR   = "deploys"
+	DEPLOY_PREFIX = "DPL"
+
+	// maxDeploysPerBox is the number of deploys remembered for a box.
+	maxDeploysPerBox = 50
+
+	// maxDeployLog is the tail of the deploy log kept in a record.
+	maxDeployLog = 4096
+)
+
+var (
+	ErrNoDeployHistory = errors.New("deploy history isn't open")
+	ErrNoRollback      = errors.New("no earlier deploy to rollback to")
+)
+
+// DeployHistory keeps the deploys of every box, as json lines in a file per
+// box under the meta dir.
+type DeployHistory struct {
+	sync.Mutex
+	dir string
+}
+
+var (
+	deploys   *DeployHistory
+	deploysMu sync.Mutex
+)
+
+// OpenDeployHistory makes the history in dir the one deploys are saved to.
+// Its ok to call it from every service, the first one wins.
+func OpenDeployHistory(dir string) (*DeployHistory, error) {
+	deploysMu.Lock()
+	defer deploysMu.Unlock()
+	if deploys != nil {
+		return deploys, nil
+	}
+	h, err := NewDeployHistory(dir)
+	if err != nil {
+		return nil, err
+	}
+	deploys = h
+	return h, nil
+}
+
+// Deploys returns the open deploy history, or nil.
+func Deploys() *DeployHistory {
+	deploysMu.Lock()
+	defer deploysMu.Unlock()
+	return deploys
+}
+
+// NewDeployHistory returns the deploy history stored in dir.
+func NewDeployHistory(dir string) (*DeployHistory, error) {
+	d := filepath.Join(dir, DEPLOYS_DIR)
+	if err := os.MkdirAll(d, 0755); err != nil {
+		return nil, err
+	}
+	return &DeployHistory{dir: d}, nil
+}
+
+func (h *DeployHistory) path(boxId string) string {
+	return filepath.Join(h.dir, filepath.Base(boxId)+".json")
+}
+
+// Add records the deploy, forgetting the oldest ones beyond maxDeploysPerBox.
+func (h *DeployHistory) Add(d *DeployData) error {
+	if d.Id == "" {
+		d.Id = DEPLOY_PREFIX + strings.Replace(uuid.NewV1().String(), "-", "", -1)
+	}
+	if len(d.Log) > maxDeployLog {
+		d.Log = d.Log[len(d.Log)-maxDeployLog:]
+	}
+	h.Lock()
+	defer h.Unlock()
+	list, err := h.read(d.BoxId)
+	if err != nil {
+		return err
+	}
+	if len(list) < maxDeploysPerBox {
+		return h.append(d)
+	}
+	list = append(list[len(list)-maxDeploysPerBox+1:], *d)
+	return h.write(d.BoxId, list)
+}
+
+// List returns the deploys of the box, the latest first.
+func (h *DeployHistory) List(boxId string) ([]DeployData, error) {
+	h.Lock()
+	list, err := h.read(boxId)
+	h.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	// the records are appended as the deploys happen.
+	for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+		list[i], list[j] = list[j], list[i]
+	}
+	return list, nil
+}
+
+// RollbackTo returns the deploy of the box with id. An empty id is the
+// latest deploy that can be rolled back to, other than the current one.
+func (h *DeployHistory) RollbackTo(boxId, id string) (*DeployData, error) {
+	list, err := h.List(boxId)
+	if err != nil {
+		return nil, err
+	}
+	current := true
+	for i := range list {
+		d := &list[i]
+		if id != "" {
+			if d.Id == id {
+				if !d.CanRollback {
+					return nil, errors.New("deploy " + id + " can't be rolled back to")
+				}
+				return d, nil
+			}
+			continue
+		}
+		if d.Error != "" {
+			continue
+		}
+		if current {
+			current = false
+			continue
+		}
+		if d.CanRollback {
+			return d, nil
+		}
+	}
+	if id != "" {
+		return nil, errors.New("deploy " + id + " not found for " + boxId)
+	}
+	return nil, ErrNoRollback
+}
+
+func (h *DeployHistory) read(boxId string) ([]DeployData, error) {
+	f, err := os.Open(h.path(boxId))
+	if os.IsNotExist(err) {
+		return []DeployData{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	list := []DeployData{}
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		d := DeployData{}
+		if err := json.Unmarshal(sc.Bytes(), &d); err != nil {
+			log.Warnf("  skip corrupt deploy record of %s : %s", boxId, err)
+			continue
+		}
+		list = append(list, d)
+	}
+	return list, sc.Err()
+}
+
+func (h *DeployHistory) append(d *DeployData) error {
+	f, err := os.OpenFile(h.path(d.BoxId), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if err = json.NewEncoder(f).Encode(d); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (h *DeployHistory) write(boxId string, list []DeployData) error {
+	tmp := h.path(boxId) + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for i := range list {
+		if err = enc.Encode(&list[i]); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, h.path(boxId))
+}
@@ -0,0 +1,84 @@
+package carton
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/megamsys/vertice/provision"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestDeployHistoryRollbackTo(c *check.C) {
+	dir, err := ioutil.TempDir("", "deploys")
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(dir)
+	h, err := NewDeployHistory(dir)
+	c.Assert(err, check.IsNil)
+	now := time.Now()
+	for _, d := range []*DeployData{
+		{Id: "DPL1", BoxId: "ASM001", Image: "ubuntu-14.04", Timestamp: now, CanRollback: true},
+		{Id: "DPL2", BoxId: "ASM001", Image: "ubuntu-16.04", Timestamp: now.Add(time.Minute), CanRollback: true},
+		{Id: "DPL3", BoxId: "ASM001", Timestamp: now.Add(2 * time.Minute), Error: "no space left"},
+	} {
+		c.Assert(h.Add(d), check.IsNil)
+	}
+	list, err := h.List("ASM001")
+	c.Assert(err, check.IsNil)
+	c.Assert(list, check.HasLen, 3)
+	c.Assert(list[0].Id, check.Equals, "DPL3")
+
+	d, err := h.RollbackTo("ASM001", "")
+	c.Assert(err, check.IsNil)
+	c.Assert(d.Id, check.Equals, "DPL1")
+	d, err = h.RollbackTo("ASM001", "DPL2")
+	c.Assert(err, check.IsNil)
+	c.Assert(d.Image, check.Equals, "ubuntu-16.04")
+	_, err = h.RollbackTo("ASM001", "DPL3")
+	c.Assert(err, check.ErrorMatches, "deploy DPL3 can't be rolled back to")
+	_, err = h.RollbackTo("ASM002", "")
+	c.Assert(err, check.Equals, ErrNoRollback)
+}
+
+func (s *S) TestDeployHistoryKeepsTheLatest(c *check.C) {
+	dir, err := ioutil.TempDir("", "deploys")
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(dir)
+	h, err := NewDeployHistory(dir)
+	c.Assert(err, check.IsNil)
+	for i := 0; i < maxDeploysPerBox+5; i++ {
+		c.Assert(h.Add(&DeployData{BoxId: "ASM001", Image: "ubuntu", Timestamp: time.Now()}), check.IsNil)
+	}
+	list, err := h.List("ASM001")
+	c.Assert(err, check.IsNil)
+	c.Assert(list, check.HasLen, maxDeploysPerBox)
+}
+
+type imageDeployingProvisioner struct {
+	provision.Provisioner
+	deployed []string
+}
+
+func (p *imageDeployingProvisioner) ImageDeploy(ctx context.Context, b *provision.Box, image string, w io.Writer) (string, error) {
+	p.deployed = append(p.deployed, "image:"+image)
+	return image, nil
+}
+
+func (p *imageDeployingProvisioner) BackupDeploy(ctx context.Context, b *provision.Box, image string, w io.Writer) (string, error) {
+	p.deployed = append(p.deployed, "backup:"+image)
+	return image, nil
+}
+
+func (s *S) TestDeployToProvisionerKeepsTheBackupSource(c *check.C) {
+	fake := &imageDeployingProvisioner{}
+	ProvisionerMap["imaging"] = fake
+	defer delete(ProvisionerMap, "imaging")
+	box := &provision.Box{Id: "ASM001", CartonName: "bigbang", Provider: "imaging"}
+	_, err := deployToProvisioner(context.Background(), &DeployOpts{B: box, Image: "bigbang-backup", Backup: true}, ioutil.Discard)
+	c.Assert(err, check.IsNil)
+	_, err = deployToProvisioner(context.Background(), &DeployOpts{B: box, Image: "ubuntu-16.04"}, ioutil.Discard)
+	c.Assert(err, check.IsNil)
+	c.Assert(fake.deployed, check.DeepEquals, []string{"backup:bigbang-backup", "image:ubuntu-16.04"})
+}
@@ -2,6 +2,7 @@ package carton
 
 import (
 	"bytes"
+	"context"
 	log "github.com/Sirupsen/logrus"
 	"github.com/megamsys/libgo/cmd"
 	lw "github.com/megamsys/libgo/writer"
@@ -15,14 +16,17 @@ type DestroyOpts struct {
 }
 
 // ChangeState runs a state increment of a machine or a container.
-func Destroy(opts *DestroyOpts) error {
+func Destroy(ctx context.Context, opts *DestroyOpts) error {
+	if err := opts.B.Can(provision.CYCLE_DESTROY); err != nil {
+		return err
+	}
 	var outBuffer bytes.Buffer
 	start := time.Now()
 	logWriter := lw.LogWriter{Box: opts.B}
 	logWriter.Async()
 	defer logWriter.Close()
 	writer := io.MultiWriter(&outBuffer, &logWriter)
-	err := ProvisionerMap[opts.B.Provider].Destroy(opts.B, writer)
+	err := ProvisionerMap[opts.B.Provider].Destroy(ctx, opts.B, writer)
 	if err != nil {
 		return err
 	}
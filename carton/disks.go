@@ -3,6 +3,7 @@ package carton
 import (
 	"bytes"
 	"code.cloudfoundry.org/bytefmt"
+	"context"
 	"encoding/json"
 	log "github.com/Sirupsen/logrus"
 	"github.com/megamsys/libgo/api"
@@ -46,14 +47,20 @@ func NewDisk(email, org, assembly, id string) *Disks {
 }
 
 // ChangeState runs a state increment of a machine or a container.
-func AttachDisk(opts *DiskOpts) error {
+func AttachDisk(ctx context.Context, opts *DiskOpts) error {
+	if err := opts.B.Can(provision.CYCLE_DISK_ATTACH); err != nil {
+		return err
+	}
+	if err := capable(opts.B, provision.CYCLE_DISK_ATTACH); err != nil {
+		return err
+	}
 	var outBuffer bytes.Buffer
 	start := time.Now()
 	logWriter := lw.LogWriter{Box: opts.B}
 	logWriter.Async()
 	defer logWriter.Close()
 	writer := io.MultiWriter(&outBuffer, &logWriter)
-	err := ProvisionerMap[opts.B.Provider].AttachDisk(opts.B, writer)
+	err := ProvisionerMap[opts.B.Provider].AttachDisk(ctx, opts.B, writer)
 	elapsed := time.Since(start)
 
 	if err != nil {
@@ -68,14 +75,20 @@ func AttachDisk(opts *DiskOpts) error {
 }
 
 // ChangeState runs a state increment of a machine or a container.
-func DetachDisk(opts *DiskOpts) error {
+func DetachDisk(ctx context.Context, opts *DiskOpts) error {
+	if err := opts.B.Can(provision.CYCLE_DISK_DETACH); err != nil {
+		return err
+	}
+	if err := capable(opts.B, provision.CYCLE_DISK_DETACH); err != nil {
+		return err
+	}
 	var outBuffer bytes.Buffer
 	start := time.Now()
 	logWriter := lw.LogWriter{Box: opts.B}
 	logWriter.Async()
 	defer logWriter.Close()
 	writer := io.MultiWriter(&outBuffer, &logWriter)
-	err := ProvisionerMap[opts.B.Provider].DetachDisk(opts.B, writer)
+	err := ProvisionerMap[opts.B.Provider].DetachDisk(ctx, opts.B, writer)
 	elapsed := time.Since(start)
 	if err != nil {
 		return err
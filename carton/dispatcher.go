@@ -0,0 +1,113 @@
+/*
+** Copyright [2013-2017] [Megam Systems]
+**
+** Licensed under the Apache License, Version 2.0 (the "License");
+** you may not use this file except in compliance with the License.
+** You may obtain a copy of the License at
+**
+** http://www.apache.org/licenses/LICENSE-2.0
+**
+** Unless required by applicable law or agreed to in writing, software
+** distributed under the License is distributed on an "AS IS" BASIS,
+** WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+** See the License for the specific language governing permissions and
+** limitations under the License.
+ */
+package carton
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultConcurrency is the number of assemblies worked on in parallel when
+// none is configured.
+const DefaultConcurrency = 10
+
+// Dispatcher runs the requests of one assembly (Requests.CatId) strictly in
+// the order they arrive. Requests of different assemblies run in parallel, upto
+// the configured limit. Every queued request is tracked in the waitgroup, so
+// the owner can wait for the inflight work to drain.
+// A control/cancel request isn't queued, as it has to reach the operation
+// running ahead of it.
+type Dispatcher struct {
+	sync.Mutex
+	wg     *sync.WaitGroup
+	limit  chan struct{}
+	queues map[string][]func()
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewDispatcher returns a dispatcher that works upto limit assemblies at a time.
+func NewDispatcher(limit int, wg *sync.WaitGroup) *Dispatcher {
+	if limit <= 0 {
+		limit = DefaultConcurrency
+	}
+	if wg == nil {
+		wg = &sync.WaitGroup{}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Dispatcher{
+		wg:     wg,
+		limit:  make(chan struct{}, limit),
+		queues: make(map[string][]func()),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Dispatch queues the request to be served by fn after all the earlier
+// requests for the same assembly are done.
+func (d *Dispatcher) Dispatch(r *Requests, fn func(context.Context, *Requests) error) {
+	if journal != nil {
+		journal.Received(r)
+	}
+	d.wg.Add(1)
+	if r.Category == CONTROL && r.Action == CANCEL {
+		go func() {
+			defer d.wg.Done()
+			fn(d.ctx, r)
+		}()
+		return
+	}
+	d.Lock()
+	q, running := d.queues[r.CatId]
+	d.queues[r.CatId] = append(q, func() { fn(d.ctx, r) })
+	d.Unlock()
+	if !running {
+		go d.drain(r.CatId)
+	}
+}
+
+// drain works on the queue of an assembly till its empty, and then forgets it.
+func (d *Dispatcher) drain(key string) {
+	for {
+		d.Lock()
+		q := d.queues[key]
+		if len(q) == 0 {
+			delete(d.queues, key)
+			d.Unlock()
+			return
+		}
+		next := q[0]
+		d.queues[key] = q[1:]
+		d.Unlock()
+
+		d.limit <- struct{}{}
+		next()
+		<-d.limit
+		d.wg.Done()
+	}
+}
+
+// Cancel cancels the context of all the dispatched requests, the ones still
+// queued are handed a cancelled context.
+func (d *Dispatcher) Cancel() {
+	d.cancel()
+}
+
+// Wait blocks till all the dispatched requests are done.
+func (d *Dispatcher) Wait() {
+	d.wg.Wait()
+}
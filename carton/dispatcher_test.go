@@ -0,0 +1,112 @@
+package carton
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestDispatcherKeepsOrderPerAssembly(c *check.C) {
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		order []string
+	)
+	d := NewDispatcher(4, &wg)
+	for _, a := range []string{"stop", "start", "destroy"} {
+		d.Dispatch(&Requests{CatId: "ASM001", Action: a}, func(ctx context.Context, r *Requests) error {
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			order = append(order, r.Action)
+			mu.Unlock()
+			return nil
+		})
+	}
+	wg.Wait()
+	c.Assert(order, check.DeepEquals, []string{"stop", "start", "destroy"})
+}
+
+func (s *S) TestDispatcherRunsAssembliesInParallel(c *check.C) {
+	var wg sync.WaitGroup
+	release := make(chan struct{})
+	started := make(chan string, 2)
+	d := NewDispatcher(2, &wg)
+	for _, id := range []string{"ASM001", "ASM002"} {
+		d.Dispatch(&Requests{CatId: id}, func(ctx context.Context, r *Requests) error {
+			started <- r.CatId
+			<-release
+			return nil
+		})
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-started:
+		case <-time.After(2 * time.Second):
+			c.Fatal("assemblies were not worked on in parallel")
+		}
+	}
+	close(release)
+	d.Wait()
+}
+
+func (s *S) TestDispatcherHonoursLimit(c *check.C) {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		running int
+		max     int
+	)
+	d := NewDispatcher(1, &wg)
+	for _, id := range []string{"ASM001", "ASM002", "ASM003"} {
+		d.Dispatch(&Requests{CatId: id}, func(ctx context.Context, r *Requests) error {
+			mu.Lock()
+			running++
+			if running > max {
+				max = running
+			}
+			mu.Unlock()
+			time.Sleep(5 * time.Millisecond)
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return nil
+		})
+	}
+	d.Wait()
+	c.Assert(max, check.Equals, 1)
+}
+
+func (s *S) TestDispatcherRunsCancelRightAway(c *check.C) {
+	var wg sync.WaitGroup
+	d := NewDispatcher(1, &wg)
+	d.Dispatch(&Requests{CatId: "ASM001", Category: STATE, Action: CREATE}, func(ctx context.Context, r *Requests) error {
+		octx, release := withCancel(ctx, r.CatId)
+		defer release()
+		select {
+		case <-octx.Done():
+		case <-time.After(2 * time.Second):
+			c.Error("create was not cancelled")
+		}
+		return nil
+	})
+	time.Sleep(10 * time.Millisecond)
+	d.Dispatch(&Requests{CatId: "ASM001", Category: CONTROL, Action: CANCEL}, func(ctx context.Context, r *Requests) error {
+		p, err := ParseRequest(r)
+		c.Assert(err, check.IsNil)
+		return p.Process(ctx, nil)
+	})
+	d.Wait()
+}
+
+func (s *S) TestDispatcherCancel(c *check.C) {
+	var wg sync.WaitGroup
+	d := NewDispatcher(1, &wg)
+	d.Dispatch(&Requests{CatId: "ASM001"}, func(ctx context.Context, r *Requests) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	d.Cancel()
+	d.Wait()
+}
@@ -0,0 +1,167 @@
+/*
+** Copyright [2013-2017] [Megam Systems]
+**
+** Licensed under the Apache License, Version 2.0 (the "License");
+** you may not use this file except in compliance with the License.
+** You may obtain a copy of the License at
+**
+** http://www.apache.org/licenses/LICENSE-2.0
+**
+** Unless required by applicable law or agreed to in writing, software
+** distributed under the License is distributed on an "AS IS" BASIS,
+** WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+** See the License for the specific language governing permissions and
+** limitations under the License.
+ */
+package carton
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/megamsys/vertice/provision"
+)
+
+const (
+	// the assembly input that picks how a failing box affects the others.
+	ON_FAILURE = "on_failure"
+
+	// STOP_ON_FIRST cancels the boxes still being worked on, and skips the
+	// ones not started yet, as soon as a box fails. This is the default.
+	STOP_ON_FIRST = "stop"
+
+	// BEST_EFFORT works on every box, whatever happens to the others.
+	BEST_EFFORT = "continue"
+)
+
+// BoxConcurrency is the number of boxes of a carton worked on in parallel.
+var BoxConcurrency = 4
+
+// ErrBoxSkipped is the outcome of a box that wasn't worked on, as an earlier
+// box failed.
+var ErrBoxSkipped = errors.New("skipped, an earlier box failed")
+
+// BoxResult is the outcome of an operation on a box.
+type BoxResult struct {
+	Id   string
+	Name string
+	Err  error
+}
+
+// BoxesError is returned when an operation fails on any box of a carton, and
+// has the outcome of every box.
+type BoxesError []BoxResult
+
+func (e BoxesError) Error() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("boxes failed :")
+	for _, r := range e {
+		_, _ = buf.WriteString("\n  ")
+		_, _ = buf.WriteString(r.Name)
+		_, _ = buf.WriteString(" (")
+		_, _ = buf.WriteString(r.Id)
+		_, _ = buf.WriteString(")")
+		if r.Err != nil {
+			_, _ = buf.WriteString(" : failed, ")
+			_, _ = buf.WriteString(r.Err.Error())
+		} else {
+			_, _ = buf.WriteString(" : ok")
+		}
+	}
+	return buf.String()
+}
+
+// Failed returns the results of the boxes that failed (or were skipped).
+func (e BoxesError) Failed() []BoxResult {
+	failed := make([]BoxResult, 0, len(e))
+	for _, r := range e {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+func (c *Carton) stopOnFirst() bool {
+	return c.Transactional || c.OnFailure != BEST_EFFORT
+}
+
+// served tells if an earlier attempt of the request already worked on the box.
+func (c *Carton) served(box *provision.Box) bool {
+	return journal != nil && journal.Served(c.RequestId, box.Id)
+}
+
+func (c *Carton) serve(box *provision.Box) {
+	if journal != nil {
+		journal.Serve(c.RequestId, box.Id)
+	}
+}
+
+// eachBox runs fn on the boxes of the carton, upto BoxConcurrency at a time.
+// The boxes served by an earlier attempt of the request are skipped. It returns
+// a BoxesError when fn fails on any box.
+func (c *Carton) eachBox(ctx context.Context, fn func(context.Context, *provision.Box) error) error {
+	if c.Boxes == nil || len(*c.Boxes) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	limit := BoxConcurrency
+	if limit <= 0 {
+		limit = 1
+	}
+	sem := make(chan struct{}, limit)
+	results := make(BoxesError, len(*c.Boxes))
+	failed := false
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for i := range *c.Boxes {
+		box := (*c.Boxes)[i]
+		results[i].Id, results[i].Name = box.Id, box.Name
+		if c.served(&box) {
+			continue
+		}
+		sem <- struct{}{}
+		mu.Lock()
+		skip := failed && c.stopOnFirst()
+		mu.Unlock()
+		if skip {
+			<-sem
+			results[i].Err = ErrBoxSkipped
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			<-sem
+			results[i].Err = err
+			continue
+		}
+		wg.Add(1)
+		go func(i int, box provision.Box) {
+			defer func() { <-sem; wg.Done() }()
+			err := fn(ctx, &box)
+			results[i].Err = err
+			if err == nil {
+				c.serve(&box)
+			} else {
+				mu.Lock()
+				failed = true
+				mu.Unlock()
+				if c.stopOnFirst() {
+					cancel()
+				}
+			}
+		}(i, box)
+	}
+	wg.Wait()
+	for _, r := range results {
+		if r.Err != nil {
+			return results
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package carton
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/megamsys/vertice/provision"
+	"gopkg.in/check.v1"
+)
+
+func newFanoutCarton(onFailure string, names ...string) *Carton {
+	boxes := make([]provision.Box, 0, len(names))
+	for _, n := range names {
+		boxes = append(boxes, provision.Box{Id: "COM" + n, Name: n})
+	}
+	return &Carton{Boxes: &boxes, OnFailure: onFailure}
+}
+
+func (s *S) TestEachBoxWorksOnAllBoxes(c *check.C) {
+	var (
+		mu   sync.Mutex
+		seen = make(map[string]bool)
+	)
+	ca := newFanoutCarton("", "a", "b", "c", "d", "e")
+	err := ca.eachBox(context.Background(), func(ctx context.Context, b *provision.Box) error {
+		mu.Lock()
+		seen[b.Name] = true
+		mu.Unlock()
+		return nil
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(seen, check.HasLen, 5)
+}
+
+func (s *S) TestEachBoxBestEffortReportsEveryBox(c *check.C) {
+	ca := newFanoutCarton(BEST_EFFORT, "a", "b", "c")
+	err := ca.eachBox(context.Background(), func(ctx context.Context, b *provision.Box) error {
+		if b.Name == "b" {
+			return errors.New("no space left")
+		}
+		return nil
+	})
+	berr, ok := err.(BoxesError)
+	c.Assert(ok, check.Equals, true)
+	c.Assert(berr, check.HasLen, 3)
+	c.Assert(berr[0].Err, check.IsNil)
+	c.Assert(berr[1].Err, check.ErrorMatches, "no space left")
+	c.Assert(berr[2].Err, check.IsNil)
+	c.Assert(berr.Failed(), check.HasLen, 1)
+	c.Assert(err, check.ErrorMatches, "(?s).*b \\(COMb\\) : failed, no space left.*")
+}
+
+func (s *S) TestEachBoxStopsOnFirstFailure(c *check.C) {
+	old := BoxConcurrency
+	BoxConcurrency = 1
+	defer func() { BoxConcurrency = old }()
+	ca := newFanoutCarton(STOP_ON_FIRST, "a", "b", "c")
+	err := ca.eachBox(context.Background(), func(ctx context.Context, b *provision.Box) error {
+		if b.Name == "a" {
+			return errors.New("quota exceeded")
+		}
+		return nil
+	})
+	berr, ok := err.(BoxesError)
+	c.Assert(ok, check.Equals, true)
+	c.Assert(berr[0].Err, check.ErrorMatches, "quota exceeded")
+	c.Assert(berr[1].Err, check.Equals, ErrBoxSkipped)
+	c.Assert(berr[2].Err, check.Equals, ErrBoxSkipped)
+}
+
+func (s *S) TestEachBoxSkipsBoxesServedBefore(c *check.C) {
+	dir, err := ioutil.TempDir("", "journal")
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(dir)
+	j, err := NewJournal(dir)
+	c.Assert(err, check.IsNil)
+	old := journal
+	journal = j
+	defer func() { journal = old }()
+
+	r := &Requests{Id: "RER001", CatId: "ASM001", Category: STATE, Action: CREATE}
+	c.Assert(begin(j, r), check.Equals, true)
+	ca := newFanoutCarton(BEST_EFFORT, "a", "b", "c")
+	ca.RequestId = r.Id
+	var (
+		mu   sync.Mutex
+		runs = make(map[string]int)
+	)
+	fn := func(ctx context.Context, b *provision.Box) error {
+		mu.Lock()
+		runs[b.Name]++
+		first := runs[b.Name] == 1
+		mu.Unlock()
+		if b.Name == "b" && first {
+			return syscall.ECONNRESET
+		}
+		return nil
+	}
+	err = ca.eachBox(context.Background(), fn)
+	c.Assert(IsTransient(err), check.Equals, true)
+	j.End(r, err)
+
+	c.Assert(begin(j, r), check.Equals, true)
+	c.Assert(ca.eachBox(context.Background(), fn), check.IsNil)
+	c.Assert(runs, check.DeepEquals, map[string]int{"a": 1, "b": 2, "c": 1})
+}
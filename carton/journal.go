@@ -0,0 +1,341 @@
+/*
+** Copyright [2013-2017] [Megam Systems]
+**
+** Licensed under the Apache License, Version 2.0 (the "License");
+** you may not use this file except in compliance with the License.
+** You may obtain a copy of the License at
+**
+** http://www.apache.org/licenses/LICENSE-2.0
+**
+** Unless required by applicable law or agreed to in writing, software
+** distributed under the License is distributed on an "AS IS" BASIS,
+** WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+** See the License for the specific language governing permissions and
+** limitations under the License.
+ */
+package carton
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	JOURNAL_FILE = "requests.journal"
+
+	REQ_RECEIVED = "received"
+	REQ_RUNNING  = "running"
+	REQ_DONE     = "done"
+	REQ_FAILED   = "failed"
+	// REQ_RECONCILE are the requests an earlier run of vertice was working on
+	// when it went down. What they did to the boxes is unknown, so they aren't
+	// tried again till the boxes are reconciled.
+	REQ_RECONCILE = "needs-reconcile"
+
+	// journalTTL is how long the finished requests are remembered.
+	journalTTL = 72 * time.Hour
+)
+
+// JournalEntry is the last known state of a request.
+type JournalEntry struct {
+	Id        string    `json:"id"`
+	CatId     string    `json:"cat_id"`
+	Category  string    `json:"category"`
+	Action    string    `json:"action"`
+	State     string    `json:"state"`
+	Error     string    `json:"error,omitempty"`
+	Served    []string  `json:"served,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Journal is an append only log of the requests (keyed on Requests.Id) kept
+// under the meta dir, so a request redelivered by nsq or seen again after a
+// restart isn't executed twice.
+type Journal struct {
+	sync.Mutex
+	path     string
+	f        *os.File
+	entries  map[string]*JournalEntry
+	inflight map[string]bool
+	opened   time.Time
+}
+
+var (
+	journal   *Journal
+	journalMu sync.Mutex
+
+	// ErrReconcile is returned for a request that was cut short by a crash.
+	ErrReconcile = errors.New("cut short by a restart, reconcile the boxes before trying again")
+)
+
+// OpenJournal loads (and compacts) the journal in dir and makes it the one used by
+// ReqOperator. Its ok to call it from every service, the first one wins.
+func OpenJournal(dir string) (*Journal, error) {
+	journalMu.Lock()
+	defer journalMu.Unlock()
+	if journal != nil {
+		return journal, nil
+	}
+	j, err := NewJournal(dir)
+	if err != nil {
+		return nil, err
+	}
+	journal = j
+	return j, nil
+}
+
+// NewJournal returns the journal stored in dir.
+func NewJournal(dir string) (*Journal, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	j := &Journal{
+		path:     filepath.Join(dir, JOURNAL_FILE),
+		entries:  make(map[string]*JournalEntry),
+		inflight: make(map[string]bool),
+		opened:   time.Now(),
+	}
+	if err := j.load(); err != nil {
+		return nil, err
+	}
+	if err := j.compact(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+func (j *Journal) load() error {
+	f, err := os.Open(j.path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		e := &JournalEntry{}
+		if err := json.Unmarshal(sc.Bytes(), e); err != nil {
+			log.Warnf("  skip corrupt journal entry : %s", err)
+			continue
+		}
+		j.entries[e.Id] = e
+	}
+	return sc.Err()
+}
+
+// compact rewrites the journal with just the last state of every request,
+// forgetting the finished ones older than journalTTL.
+func (j *Journal) compact() error {
+	tmp := j.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for id, e := range j.entries {
+		if (e.State == REQ_DONE || e.State == REQ_FAILED || e.State == REQ_RECONCILE) && time.Since(e.UpdatedAt) > journalTTL {
+			delete(j.entries, id)
+			continue
+		}
+		if err = enc.Encode(e); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, j.path); err != nil {
+		return err
+	}
+	j.f, err = os.OpenFile(j.path, os.O_APPEND|os.O_WRONLY, 0644)
+	return err
+}
+
+// Get returns the last known state of the request id.
+func (j *Journal) Get(id string) (*JournalEntry, bool) {
+	j.Lock()
+	defer j.Unlock()
+	e, ok := j.entries[id]
+	if !ok {
+		return nil, false
+	}
+	c := *e
+	c.Served = append([]string(nil), e.Served...)
+	return &c, true
+}
+
+// Begin tells if the request should be executed, and marks it running when so.
+// Done requests and the ones already running in this process are skipped.
+// Failed ones, and the ones received but not begun by an earlier run of
+// vertice, are tried again. The ones left running by an earlier run aren't, as
+// a create may have happened already; they are marked needs-reconcile and
+// fail with ErrReconcile.
+func (j *Journal) Begin(r *Requests) (bool, error) {
+	if r.Id == "" {
+		return true, nil
+	}
+	j.Lock()
+	defer j.Unlock()
+	if e, ok := j.entries[r.Id]; ok {
+		switch {
+		case e.State == REQ_DONE:
+			log.Infof("  skip request %s (%s/%s), its already done", r.Id, r.Category, r.Action)
+			return false, nil
+		case j.inflight[r.Id]:
+			log.Infof("  skip request %s (%s/%s), its already %s", r.Id, r.Category, r.Action, e.State)
+			return false, nil
+		case e.State == REQ_RECONCILE:
+			return false, fmt.Errorf("request %s (%s/%s) %s", r.Id, r.Category, r.Action, ErrReconcile)
+		case e.State == REQ_RUNNING && e.UpdatedAt.Before(j.opened):
+			log.Warnf("  request %s (%s/%s) was left running, it needs to be reconciled", r.Id, r.Category, r.Action)
+			j.mark(r, REQ_RECONCILE, ErrReconcile)
+			return false, fmt.Errorf("request %s (%s/%s) %s", r.Id, r.Category, r.Action, ErrReconcile)
+		}
+	}
+	j.inflight[r.Id] = true
+	j.mark(r, REQ_RUNNING, nil)
+	return true, nil
+}
+
+// Received records the request as received.
+func (j *Journal) Received(r *Requests) {
+	if r.Id == "" {
+		return
+	}
+	j.Lock()
+	defer j.Unlock()
+	if _, ok := j.entries[r.Id]; !ok {
+		j.mark(r, REQ_RECEIVED, nil)
+	}
+}
+
+// End records the outcome of a request that was begun.
+func (j *Journal) End(r *Requests, err error) {
+	if r.Id == "" {
+		return
+	}
+	j.Lock()
+	defer j.Unlock()
+	delete(j.inflight, r.Id)
+	if err != nil {
+		j.mark(r, REQ_FAILED, err)
+		return
+	}
+	j.mark(r, REQ_DONE, nil)
+}
+
+// Served tells if the box was served by an earlier attempt of the request id.
+func (j *Journal) Served(id, boxId string) bool {
+	if id == "" {
+		return false
+	}
+	j.Lock()
+	defer j.Unlock()
+	if e, ok := j.entries[id]; ok {
+		for _, b := range e.Served {
+			if b == boxId {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Serve records the box as served by the request id, a retry of the request
+// only works on the boxes that weren't.
+func (j *Journal) Serve(id, boxId string) {
+	if id == "" {
+		return
+	}
+	j.Lock()
+	defer j.Unlock()
+	e, ok := j.entries[id]
+	if !ok {
+		return
+	}
+	e.Served = append(e.Served, boxId)
+	e.UpdatedAt = time.Now()
+	j.write(e)
+}
+
+// Unserve forgets the boxes served by the request id, as they were undone.
+func (j *Journal) Unserve(id string, boxIds ...string) {
+	if id == "" {
+		return
+	}
+	j.Lock()
+	defer j.Unlock()
+	e, ok := j.entries[id]
+	if !ok {
+		return
+	}
+	served := make([]string, 0, len(e.Served))
+	for _, b := range e.Served {
+		undone := false
+		for _, u := range boxIds {
+			if b == u {
+				undone = true
+				break
+			}
+		}
+		if !undone {
+			served = append(served, b)
+		}
+	}
+	e.Served = served
+	e.UpdatedAt = time.Now()
+	j.write(e)
+}
+
+func (j *Journal) mark(r *Requests, state string, err error) {
+	e := &JournalEntry{
+		Id:        r.Id,
+		CatId:     r.CatId,
+		Category:  r.Category,
+		Action:    r.Action,
+		State:     state,
+		UpdatedAt: time.Now(),
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	if old, ok := j.entries[r.Id]; ok {
+		e.Served = old.Served
+	}
+	j.entries[r.Id] = e
+	j.write(e)
+}
+
+func (j *Journal) write(e *JournalEntry) {
+	if j.f == nil {
+		return
+	}
+	b, _ := json.Marshal(e)
+	if _, werr := j.f.Write(append(b, '\n')); werr != nil {
+		log.Errorf("  unable to journal request %s : %s", e.Id, werr)
+		return
+	}
+	j.f.Sync()
+}
+
+// Close closes the journal file.
+func (j *Journal) Close() error {
+	j.Lock()
+	defer j.Unlock()
+	if j.f == nil {
+		return nil
+	}
+	err := j.f.Close()
+	j.f = nil
+	return err
+}
@@ -0,0 +1,93 @@
+package carton
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+
+	"gopkg.in/check.v1"
+)
+
+func begin(j *Journal, r *Requests) bool {
+	ok, _ := j.Begin(r)
+	return ok
+}
+
+func (s *S) TestJournalSkipsDone(c *check.C) {
+	dir, err := ioutil.TempDir("", "journal")
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(dir)
+	j, err := NewJournal(dir)
+	c.Assert(err, check.IsNil)
+	r := &Requests{Id: "RER001", CatId: "ASM001", Category: STATE, Action: CREATE}
+	j.Received(r)
+	c.Assert(begin(j, r), check.Equals, true)
+	c.Assert(begin(j, r), check.Equals, false)
+	j.End(r, nil)
+	c.Assert(begin(j, r), check.Equals, false)
+	c.Assert(j.Close(), check.IsNil)
+
+	j, err = NewJournal(dir)
+	c.Assert(err, check.IsNil)
+	e, ok := j.Get("RER001")
+	c.Assert(ok, check.Equals, true)
+	c.Assert(e.State, check.Equals, REQ_DONE)
+	c.Assert(begin(j, r), check.Equals, false)
+}
+
+func (s *S) TestJournalRetriesFailedAndReconcilesRunning(c *check.C) {
+	dir, err := ioutil.TempDir("", "journal")
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(dir)
+	j, err := NewJournal(dir)
+	c.Assert(err, check.IsNil)
+	failed := &Requests{Id: "RER001", CatId: "ASM001", Category: CONTROL, Action: START}
+	c.Assert(begin(j, failed), check.Equals, true)
+	j.End(failed, errors.New("connection refused"))
+	running := &Requests{Id: "RER002", CatId: "ASM002", Category: STATE, Action: CREATE}
+	c.Assert(begin(j, running), check.Equals, true)
+	c.Assert(j.Close(), check.IsNil)
+
+	j, err = NewJournal(dir)
+	c.Assert(err, check.IsNil)
+	c.Assert(begin(j, failed), check.Equals, true)
+	ok, err := j.Begin(running)
+	c.Assert(ok, check.Equals, false)
+	c.Assert(err, check.ErrorMatches, ".*reconcile the boxes.*")
+	e, _ := j.Get("RER002")
+	c.Assert(e.State, check.Equals, REQ_RECONCILE)
+	c.Assert(j.Close(), check.IsNil)
+
+	j, err = NewJournal(dir)
+	c.Assert(err, check.IsNil)
+	ok, err = j.Begin(running)
+	c.Assert(ok, check.Equals, false)
+	c.Assert(err, check.NotNil)
+}
+
+func (s *S) TestJournalRunsReceivedLeftByEarlierRun(c *check.C) {
+	dir, err := ioutil.TempDir("", "journal")
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(dir)
+	j, err := NewJournal(dir)
+	c.Assert(err, check.IsNil)
+	r := &Requests{Id: "RER001", CatId: "ASM001", Category: STATE, Action: CREATE}
+	j.Received(r)
+	c.Assert(j.Close(), check.IsNil)
+
+	j, err = NewJournal(dir)
+	c.Assert(err, check.IsNil)
+	c.Assert(begin(j, r), check.Equals, true)
+}
+
+func (s *S) TestJournalIgnoresRequestsWithoutId(c *check.C) {
+	dir, err := ioutil.TempDir("", "journal")
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(dir)
+	j, err := NewJournal(dir)
+	c.Assert(err, check.IsNil)
+	r := &Requests{CatId: "ASM001", Category: STATE, Action: CREATE}
+	c.Assert(begin(j, r), check.Equals, true)
+	j.End(r, nil)
+	c.Assert(begin(j, r), check.Equals, true)
+}
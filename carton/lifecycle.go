@@ -17,6 +17,7 @@
 package carton
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"time"
@@ -41,15 +42,14 @@ func (cy *LifecycleOpts) setLogger() {
 	cy.writer = io.MultiWriter(&cy.logWriter)
 }
 
-//if the state is in running, started, stopped, restarted then allow it to be lcycled.
-// to-do: allow states that ends with "*ing or *ed" that should fix this generically.
-func (cy *LifecycleOpts) canCycleStart() bool {
-	return cy.B.State == constants.StateStopped
-}
-
-func (cy *LifecycleOpts) canCycleStop() bool {
-	return cy.B.State == constants.StateRunning ||
-		cy.B.State == constants.StatePostError
+// can returns a provision.LifecycleError when op isn't allowed on the box, or
+// a provision.CapabilityError when its provisioner can't do it.
+func (cy *LifecycleOpts) can(op provision.BoxOp) error {
+	if err := cy.B.Can(op); err != nil {
+		log.Warnf("  %s", err)
+		return err
+	}
+	return capable(cy.B, op)
 }
 
 func (cy *LifecycleOpts) process(process string) string {
@@ -60,66 +60,60 @@ func (cy *LifecycleOpts) process(process string) string {
 }
 
 // Starts  the box.
-func Start(cy *LifecycleOpts) error {
+func Start(ctx context.Context, cy *LifecycleOpts) error {
 	log.Debugf("  start cycle for box (%s, %s)", cy.B.Id, cy.B.GetFullName())
+	if err := cy.can(provision.CYCLE_START); err != nil {
+		return err
+	}
 	cy.setLogger()
 	defer cy.logWriter.Close()
-	if cy.canCycleStart() {
-		if err := ProvisionerMap[cy.B.Provider].Start(cy.B, cy.process(constants.START), cy.writer); err != nil {
-			return err
-		}
-	} else {
-		fmt.Printf("start (%s, %s, %s) Unsuccessfull because of lifecycle not allowed\n", cy.B.GetFullName(), cy.B.Status.String(), time.Since(cy.start))
+	if err := ProvisionerMap[cy.B.Provider].Start(ctx, cy.B, cy.process(constants.START), cy.writer); err != nil {
+		return err
 	}
 	fmt.Fprintf(cy.writer, "    start (%s, %s, %s) OK\n", cy.B.GetFullName(), cy.B.Status.String(), time.Since(cy.start))
 	return nil
 }
 
 // Stops the box
-func Stop(cy *LifecycleOpts) error {
+func Stop(ctx context.Context, cy *LifecycleOpts) error {
 	log.Debugf("  stop cycle for box (%s, %s)", cy.B.Id, cy.B.GetFullName())
+	if err := cy.can(provision.CYCLE_STOP); err != nil {
+		return err
+	}
 	cy.setLogger()
 	defer cy.logWriter.Close()
-	if cy.canCycleStop() {
-
-		if err := ProvisionerMap[cy.B.Provider].Stop(cy.B, cy.process(constants.STOP), cy.writer); err != nil {
-			return err
-		}
-	} else {
-		fmt.Printf("start (%s, %s, %s) Unsuccessfull because of lifecycle not allowed\n", cy.B.GetFullName(), cy.B.Status.String(), time.Since(cy.start))
+	if err := ProvisionerMap[cy.B.Provider].Stop(ctx, cy.B, cy.process(constants.STOP), cy.writer); err != nil {
+		return err
 	}
 	fmt.Fprintf(cy.writer, "    stop (%s, %s, %s) OK\n", cy.B.GetFullName(), cy.B.Status.String(), time.Since(cy.start))
 	return nil
 }
 
 // Restart the box.
-func Restart(cy *LifecycleOpts) error {
+func Restart(ctx context.Context, cy *LifecycleOpts) error {
 	log.Debugf("  restart cycle for box (%s, %s)", cy.B.Id, cy.B.GetFullName())
+	if err := cy.can(provision.CYCLE_RESTART); err != nil {
+		return err
+	}
 	cy.setLogger()
 	defer cy.logWriter.Close()
-	if cy.canCycleStop() {
-		if err := ProvisionerMap[cy.B.Provider].Restart(cy.B, cy.process(constants.RESTART), cy.writer); err != nil {
-			return err
-		}
-	} else {
-		fmt.Printf("start (%s, %s, %s) Unsuccessfull because of lifecycle not allowed\n", cy.B.GetFullName(), cy.B.Status.String(), time.Since(cy.start))
+	if err := ProvisionerMap[cy.B.Provider].Restart(ctx, cy.B, cy.process(constants.RESTART), cy.writer); err != nil {
+		return err
 	}
 	fmt.Fprintf(cy.writer, "    restart (%s, %s, %s) OK\n", cy.B.GetFullName(), cy.B.Status.String(), time.Since(cy.start))
 	return nil
 }
 
 // Stops the box
-func SuspendBox(cy *LifecycleOpts) error {
+func SuspendBox(ctx context.Context, cy *LifecycleOpts) error {
 	log.Debugf("  suspend cycle for box (%s, %s)", cy.B.Id, cy.B.GetFullName())
+	if err := cy.can(provision.CYCLE_SUSPEND); err != nil {
+		return err
+	}
 	cy.setLogger()
 	defer cy.logWriter.Close()
-	if cy.canCycleStop() {
-
-		if err := ProvisionerMap[cy.B.Provider].Suspend(cy.B, cy.process(constants.SUSPEND), cy.writer); err != nil {
-			return err
-		}
-	} else {
-		fmt.Printf("start (%s, %s, %s) Unsuccessfull because of lifecycle not allowed\n", cy.B.GetFullName(), cy.B.Status.String(), time.Since(cy.start))
+	if err := ProvisionerMap[cy.B.Provider].Suspend(ctx, cy.B, cy.process(constants.SUSPEND), cy.writer); err != nil {
+		return err
 	}
 	fmt.Fprintf(cy.writer, "    suspend (%s, %s, %s) OK\n", cy.B.GetFullName(), cy.B.Status.String(), time.Since(cy.start))
 	return nil
@@ -0,0 +1,69 @@
+package carton
+
+import (
+	"context"
+
+	"github.com/megamsys/libgo/utils"
+	"github.com/megamsys/vertice/provision"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestStartRefusedWhenRunning(c *check.C) {
+	box := &provision.Box{Id: "COM01", CartonName: "bigbang", DomainName: "megambox.com", State: utils.StateRunning}
+	err := Start(context.Background(), &LifecycleOpts{B: box})
+	c.Assert(provision.IsLifecycleError(err), check.Equals, true)
+	lerr := err.(*provision.LifecycleError)
+	c.Assert(lerr.Op, check.Equals, provision.CYCLE_START)
+	c.Assert(lerr.State, check.Equals, utils.StateRunning)
+}
+
+func (s *S) TestStopAndRestartRefusedWhenStopped(c *check.C) {
+	box := &provision.Box{Id: "COM01", Name: "bigbang", State: utils.StateStopped}
+	err := Stop(context.Background(), &LifecycleOpts{B: box})
+	c.Assert(provision.IsLifecycleError(err), check.Equals, true)
+	err = Restart(context.Background(), &LifecycleOpts{B: box, Hard: true})
+	c.Assert(provision.IsLifecycleError(err), check.Equals, true)
+	err = SuspendBox(context.Background(), &LifecycleOpts{B: box})
+	c.Assert(provision.IsLifecycleError(err), check.Equals, true)
+}
+
+func (s *S) TestBoxCanDiskOpsWhenUpOrDown(c *check.C) {
+	for _, state := range []utils.State{utils.StateRunning, utils.StateStopped} {
+		box := &provision.Box{State: state}
+		c.Assert(box.Can(provision.CYCLE_SNAP_CREATE), check.IsNil)
+		c.Assert(box.Can(provision.CYCLE_BACKUP_CREATE), check.IsNil)
+		c.Assert(box.Can(provision.CYCLE_DISK_ATTACH), check.IsNil)
+	}
+	box := &provision.Box{State: utils.StateInitializing}
+	c.Assert(box.Can(provision.CYCLE_SNAP_CREATE), check.NotNil)
+}
+
+func (s *S) TestBoxCanOnlyDestroyWhenDestroyed(c *check.C) {
+	box := &provision.Box{State: utils.StateDestroyed}
+	c.Assert(box.Can(provision.CYCLE_DESTROY), check.IsNil)
+	c.Assert(box.Can(provision.CYCLE_SNAP_DELETE), check.IsNil)
+	c.Assert(box.Can(provision.CYCLE_DEPLOY), check.NotNil)
+	c.Assert(box.Can(provision.CYCLE_START), check.NotNil)
+}
+
+func (s *S) TestBoxCanLimitedByStatus(c *check.C) {
+	box := &provision.Box{State: utils.StateRunning, Status: utils.StatusInsufficientFund}
+	c.Assert(box.Can(provision.CYCLE_STOP), check.IsNil)
+	c.Assert(box.Can(provision.CYCLE_RESTART), check.NotNil)
+	c.Assert(box.CanCycleStop(), check.Equals, false)
+}
+
+func (s *S) TestBoxCanUnknownState(c *check.C) {
+	box := &provision.Box{}
+	c.Assert(provision.IsLifecycleError(box.Can(provision.CYCLE_DEPLOY)), check.Equals, true)
+	box = &provision.Box{State: utils.State("hibernated")}
+	c.Assert(provision.IsLifecycleError(box.Can(provision.CYCLE_DESTROY)), check.Equals, true)
+	c.Assert(box.CanCycleStart(), check.Equals, false)
+	c.Assert(box.CanCycleStop(), check.Equals, false)
+}
+
+func (s *S) TestBoxCanStatusWithoutLimit(c *check.C) {
+	box := &provision.Box{State: utils.StateStopped, Status: utils.StatusSuspended}
+	c.Assert(box.Can(provision.CYCLE_START), check.IsNil)
+	c.Assert(box.Can(provision.CYCLE_RESTART), check.NotNil)
+}
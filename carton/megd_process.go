@@ -18,6 +18,11 @@ package carton
 
 import (
 	"bytes"
+	"context"
+	"fmt"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/megamsys/vertice/provision"
 )
 
 // CreateProcs represents a command for creating new cartons.
@@ -32,9 +37,9 @@ func (s CreateProcess) String() string {
 	return buf.String()
 }
 
-func (s CreateProcess) Process(ca Cartons) error {
+func (s CreateProcess) Process(ctx context.Context, ca Cartons) error {
 	for _, c := range ca {
-		if err := c.Deploy(); err != nil {
+		if err := c.Deploy(ctx); err != nil {
 			return err
 		}
 	}
@@ -53,9 +58,9 @@ func (s DestroyProcess) String() string {
 	return buf.String()
 }
 
-func (s DestroyProcess) Process(ca Cartons) error {
+func (s DestroyProcess) Process(ctx context.Context, ca Cartons) error {
 	for _, c := range ca {
-		if err := c.Destroy(); err != nil {
+		if err := c.Destroy(ctx); err != nil {
 			return err
 		}
 	}
@@ -74,9 +79,9 @@ func (s StartProcess) String() string {
 	return buf.String()
 }
 
-func (s StartProcess) Process(ca Cartons) error {
+func (s StartProcess) Process(ctx context.Context, ca Cartons) error {
 	for _, c := range ca {
-		if err := c.Start(); err != nil {
+		if err := c.Start(ctx); err != nil {
 			return err
 		}
 	}
@@ -96,9 +101,9 @@ func (s StopProcess) String() string {
 	return buf.String()
 }
 
-func (s StopProcess) Process(ca Cartons) error {
+func (s StopProcess) Process(ctx context.Context, ca Cartons) error {
 	for _, c := range ca {
-		if err := c.Stop(s.Hard); err != nil {
+		if err := c.Stop(ctx, s.Hard); err != nil {
 			return err
 		}
 	}
@@ -117,9 +122,9 @@ func (s SuspendProcess) String() string {
 	return buf.String()
 }
 
-func (s SuspendProcess) Process(ca Cartons) error {
+func (s SuspendProcess) Process(ctx context.Context, ca Cartons) error {
 	for _, c := range ca {
-		if err := c.Suspend(); err != nil {
+		if err := c.Suspend(ctx); err != nil {
 			return err
 		}
 	}
@@ -139,30 +144,161 @@ func (s RestartProcess) String() string {
 	return buf.String()
 }
 
-func (s RestartProcess) Process(ca Cartons) error {
+func (s RestartProcess) Process(ctx context.Context, ca Cartons) error {
 	for _, c := range ca {
-		if err := c.Restart(s.Hard); err != nil {
+		if err := c.Restart(ctx, s.Hard); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// CancelProcess represents a command for cancelling the operation running on cartons.
+type CancelProcess struct {
+	Name string
+}
+
+func (s CancelProcess) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("CANCEL CARTON ")
+	_, _ = buf.WriteString(s.Name)
+	return buf.String()
+}
+
+func (s CancelProcess) Process(ctx context.Context, ca Cartons) error {
+	if !Cancel(s.Name) {
+		log.Infof("  nothing running to cancel on %s", s.Name)
+	}
+	return nil
+}
+
 // UpgradeProcs represents a command for starting  cartons.
 type UpgradeProcess struct {
-	Name string
+	Name   string
+	Commit string
 }
 
 func (s UpgradeProcess) String() string {
 	var buf bytes.Buffer
 	_, _ = buf.WriteString("UPGRADE CARTON ")
 	_, _ = buf.WriteString(s.Name)
+	if s.Commit != "" {
+		_, _ = buf.WriteString(" TO ")
+		_, _ = buf.WriteString(s.Commit)
+	}
 	return buf.String()
 }
 
-func (s UpgradeProcess) Process(ca Cartons) error {
+func (s UpgradeProcess) Process(ctx context.Context, ca Cartons) error {
 	for _, c := range ca {
-		if err := c.Upgrade(); err != nil {
+		if err := c.Upgrade(ctx, s.Commit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RollbackProcess represents a command for redeploying cartons from an
+// earlier deploy.
+type RollbackProcess struct {
+	Name     string
+	DeployId string
+}
+
+func (s RollbackProcess) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("ROLLBACK CARTON ")
+	_, _ = buf.WriteString(s.Name)
+	if s.DeployId != "" {
+		_, _ = buf.WriteString(" TO ")
+		_, _ = buf.WriteString(s.DeployId)
+	}
+	return buf.String()
+}
+
+func (s RollbackProcess) Process(ctx context.Context, ca Cartons) error {
+	for _, c := range ca {
+		if err := c.Rollback(ctx, s.DeployId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResizeProcess represents a command for changing the flavor of cartons.
+type ResizeProcess struct {
+	Name     string
+	FlavorId string
+}
+
+func (s ResizeProcess) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("RESIZE CARTON ")
+	_, _ = buf.WriteString(s.Name)
+	_, _ = buf.WriteString(" TO ")
+	_, _ = buf.WriteString(s.FlavorId)
+	return buf.String()
+}
+
+func (s ResizeProcess) Process(ctx context.Context, ca Cartons) error {
+	if s.FlavorId == "" {
+		return fmt.Errorf("resize of %s needs a flavor", s.Name)
+	}
+	for _, c := range ca {
+		if err := c.Resize(ctx, s.FlavorId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ScaleProcess represents a command for changing the units of cartons.
+type ScaleProcess struct {
+	Name  string
+	Units int
+}
+
+func (s ScaleProcess) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("SCALE CARTON ")
+	_, _ = buf.WriteString(s.Name)
+	_, _ = buf.WriteString(fmt.Sprintf(" TO %d UNITS", s.Units))
+	return buf.String()
+}
+
+func (s ScaleProcess) Process(ctx context.Context, ca Cartons) error {
+	if s.Units < 1 {
+		return fmt.Errorf("scale of %s needs at least one unit", s.Name)
+	}
+	for _, c := range ca {
+		if err := c.Scale(ctx, s.Units); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MigrateProcess represents a command for moving cartons to another host.
+type MigrateProcess struct {
+	Name string
+	To   provision.MigrateTo
+}
+
+func (s MigrateProcess) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("MIGRATE CARTON ")
+	_, _ = buf.WriteString(s.Name)
+	_, _ = buf.WriteString(" TO ")
+	_, _ = buf.WriteString(s.To.String())
+	return buf.String()
+}
+
+func (s MigrateProcess) Process(ctx context.Context, ca Cartons) error {
+	if s.To.Host == "" && s.To.ClusterId == "" {
+		return fmt.Errorf("migrate of %s needs a host or a cluster", s.Name)
+	}
+	for _, c := range ca {
+		if err := c.Migrate(ctx, s.To); err != nil {
 			return err
 		}
 	}
@@ -181,9 +317,9 @@ func (s StateupProcess) String() string {
 	return buf.String()
 }
 
-func (s StateupProcess) Process(ca Cartons) error {
+func (s StateupProcess) Process(ctx context.Context, ca Cartons) error {
 	for _, c := range ca {
-		if err := c.Stateup(); err != nil {
+		if err := c.Stateup(ctx); err != nil {
 			return err
 		}
 	}
@@ -202,9 +338,9 @@ func (s SnapCreateProcess) String() string {
 	return buf.String()
 }
 
-func (s SnapCreateProcess) Process(ca Cartons) error {
+func (s SnapCreateProcess) Process(ctx context.Context, ca Cartons) error {
 	for _, c := range ca {
-		if err := c.CreateSnapshot(); err != nil {
+		if err := c.CreateSnapshot(ctx); err != nil {
 			return err
 		}
 	}
@@ -223,9 +359,9 @@ func (s SnapDestroyProcess) String() string {
 	return buf.String()
 }
 
-func (s SnapDestroyProcess) Process(ca Cartons) error {
+func (s SnapDestroyProcess) Process(ctx context.Context, ca Cartons) error {
 	for _, c := range ca {
-		if err := c.DeleteSnapshot(); err != nil {
+		if err := c.DeleteSnapshot(ctx); err != nil {
 			return err
 		}
 	}
@@ -244,9 +380,9 @@ func (s SnapRestoreProcess) String() string {
 	return buf.String()
 }
 
-func (s SnapRestoreProcess) Process(ca Cartons) error {
+func (s SnapRestoreProcess) Process(ctx context.Context, ca Cartons) error {
 	for _, c := range ca {
-		if err := c.RestoreSnapshot(); err != nil {
+		if err := c.RestoreSnapshot(ctx); err != nil {
 			return err
 		}
 	}
@@ -265,9 +401,46 @@ func (s SnapSaveAsProcess) String() string {
 	return buf.String()
 }
 
-func (s SnapSaveAsProcess) Process(ca Cartons) error {
+func (s SnapSaveAsProcess) Process(ctx context.Context, ca Cartons) error {
+	for _, c := range ca {
+		if err := c.SnapshotSaveAs(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SnapCloneProcess represents a command for launching cartons from a snapshot
+// or a backup of another one.
+type SnapCloneProcess struct {
+	Name     string
+	SnapId   string
+	BackupId string
+}
+
+func (s SnapCloneProcess) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("SNAPSHOT CLONE CARTON ")
+	_, _ = buf.WriteString(s.Name)
+	_, _ = buf.WriteString(" FROM ")
+	if s.SnapId != "" {
+		_, _ = buf.WriteString(provision.CLONE_SNAPSHOT + " " + s.SnapId)
+	} else {
+		_, _ = buf.WriteString(provision.CLONE_BACKUP + " " + s.BackupId)
+	}
+	return buf.String()
+}
+
+func (s SnapCloneProcess) Process(ctx context.Context, ca Cartons) error {
+	kind, id := provision.CLONE_SNAPSHOT, s.SnapId
+	if id == "" {
+		kind, id = provision.CLONE_BACKUP, s.BackupId
+	}
+	if id == "" {
+		return fmt.Errorf("clone of %s needs a snapshot or a backup", s.Name)
+	}
 	for _, c := range ca {
-		if err := c.SnapshotSaveAs(); err != nil {
+		if err := c.Clone(ctx, kind, id); err != nil {
 			return err
 		}
 	}
@@ -286,9 +459,9 @@ func (s ImageCreateProcess) String() string {
 	return buf.String()
 }
 
-func (s ImageCreateProcess) Process(ca Cartons) error {
+func (s ImageCreateProcess) Process(ctx context.Context, ca Cartons) error {
 	for _, c := range ca {
-		if err := c.CreateImage(); err != nil {
+		if err := c.CreateImage(ctx); err != nil {
 			return err
 		}
 	}
@@ -307,9 +480,9 @@ func (s ImageDestroyProcess) String() string {
 	return buf.String()
 }
 
-func (s ImageDestroyProcess) Process(ca Cartons) error {
+func (s ImageDestroyProcess) Process(ctx context.Context, ca Cartons) error {
 	for _, c := range ca {
-		if err := c.DeleteImage(); err != nil {
+		if err := c.DeleteImage(ctx); err != nil {
 			return err
 		}
 	}
@@ -328,9 +501,9 @@ func (s DiskAttachProcess) String() string {
 	return buf.String()
 }
 
-func (s DiskAttachProcess) Process(ca Cartons) error {
+func (s DiskAttachProcess) Process(ctx context.Context, ca Cartons) error {
 	for _, c := range ca {
-		if err := c.AttachDisk(); err != nil {
+		if err := c.AttachDisk(ctx); err != nil {
 			return err
 		}
 	}
@@ -349,9 +522,9 @@ func (s DiskDetachProcess) String() string {
 	return buf.String()
 }
 
-func (s DiskDetachProcess) Process(ca Cartons) error {
+func (s DiskDetachProcess) Process(ctx context.Context, ca Cartons) error {
 	for _, c := range ca {
-		if err := c.DetachDisk(); err != nil {
+		if err := c.DetachDisk(ctx); err != nil {
 			return err
 		}
 	}
@@ -370,9 +543,9 @@ func (s UpdateNetworkProcess) String() string {
 	return buf.String()
 }
 
-func (s UpdateNetworkProcess) Process(ca Cartons) error {
+func (s UpdateNetworkProcess) Process(ctx context.Context, ca Cartons) error {
 	for _, c := range ca {
-		if err := c.NetworkUpdate(); err != nil {
+		if err := c.NetworkUpdate(ctx); err != nil {
 			return err
 		}
 	}
@@ -392,9 +565,9 @@ func (s RunningProcess) String() string {
 	return buf.String()
 }
 
-func (s RunningProcess) Process(ca Cartons) error {
+func (s RunningProcess) Process(ctx context.Context, ca Cartons) error {
 	for _, c := range ca {
-		if err := c.Running(); err != nil {
+		if err := c.Running(ctx); err != nil {
 			return err
 		}
 	}
@@ -413,6 +586,6 @@ func (s FailureProcess) String() string {
 	return buf.String()
 }
 
-func (s FailureProcess) Process(ca Cartons) error {
+func (s FailureProcess) Process(ctx context.Context, ca Cartons) error {
 	return nil
 }
@@ -0,0 +1,55 @@
+/*
+** Copyright [2013-2017] [Megam Systems]
+**
+** Licensed under the Apache License, Version 2.0 (the "License");
+** you may not use this file except in compliance with the License.
+** You may obtain a copy of the License at
+**
+** http://www.apache.org/licenses/LICENSE-2.0
+**
+** Unless required by applicable law or agreed to in writing, software
+** distributed under the License is distributed on an "AS IS" BASIS,
+** WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+** See the License for the specific language governing permissions and
+** limitations under the License.
+ */
+package carton
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	constants "github.com/megamsys/libgo/utils"
+	lw "github.com/megamsys/libgo/writer"
+	lb "github.com/megamsys/vertice/logbox"
+	"github.com/megamsys/vertice/provision"
+)
+
+type MigrateOpts struct {
+	B  *provision.Box
+	To provision.MigrateTo
+}
+
+// Migrate moves the box to the host or cluster of opts.To. Only a running box
+// can be migrated live, a stopped one is always moved cold.
+func Migrate(ctx context.Context, opts *MigrateOpts) error {
+	if err := opts.B.Can(provision.CYCLE_MIGRATE); err != nil {
+		return err
+	}
+	if opts.To.Live && opts.B.State != constants.StateRunning {
+		return fmt.Errorf("%s isn't running, it can only be migrated cold", opts.B.GetFullName())
+	}
+	migrator, ok := ProvisionerMap[opts.B.Provider].(provision.Migrator)
+	if !ok {
+		return fmt.Errorf("provisioner %s can't migrate %s", opts.B.Provider, opts.B.GetFullName())
+	}
+	logWriter := lw.NewLogWriter(opts.B)
+	defer logWriter.Close()
+	writer := io.MultiWriter(&logWriter)
+	if err := migrator.Migrate(ctx, opts.B, opts.To, writer); err != nil {
+		fmt.Fprintf(writer, lb.W(lb.UPDATING, lb.ERROR, fmt.Sprintf("--- migrate %s --> %s", opts.B.GetFullName(), err)))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+package carton
+
+import (
+	"context"
+
+	"github.com/megamsys/libgo/utils"
+	"github.com/megamsys/vertice/provision"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestMigrateLiveRefusedWhenStopped(c *check.C) {
+	box := &provision.Box{Id: "COM01", CartonName: "bigbang", State: utils.StateStopped}
+	err := Migrate(context.Background(), &MigrateOpts{B: box, To: provision.MigrateTo{Host: "node2", Live: true}})
+	c.Assert(err, check.ErrorMatches, "bigbang isn't running, .*")
+	err = Migrate(context.Background(), &MigrateOpts{B: &provision.Box{State: utils.StateDestroyed}})
+	c.Assert(provision.IsLifecycleError(err), check.Equals, true)
+}
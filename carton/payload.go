@@ -31,6 +31,16 @@ type Payload struct {
 	CatType   string    `json:"cattype"`
 	Category  string    `json:"category"`
 	CreatedAt time.Time `json:"created_at"`
+	DryRun    bool      `json:"dry_run"`
+	DeployId  string    `json:"deploy_id,omitempty"`
+	Commit    string    `json:"commit,omitempty"`
+	FlavorId  string    `json:"flavor_id,omitempty"`
+	Units     int       `json:"units,omitempty"`
+	Host      string    `json:"host,omitempty"`
+	ClusterId string    `json:"cluster_id,omitempty"`
+	Live      bool      `json:"live,omitempty"`
+	SnapId    string    `json:"snap_id,omitempty"`
+	BackupId  string    `json:"backup_id,omitempty"`
 }
 
 type PayloadConvertor interface {
@@ -54,11 +64,22 @@ func (p *Payload) Convert() (*Requests, error) {
 		return listReqsById(p.Id, p.AccountId)
 	} else {
 		return &Requests{
+			Id:        p.Id,
 			Action:    p.Action,
 			Category:  p.Category,
 			AccountId: p.AccountId,
 			CatId:     p.CatId,
 			CreatedAt: p.CreatedAt,
+			DryRun:    p.DryRun,
+			DeployId:  p.DeployId,
+			Commit:    p.Commit,
+			FlavorId:  p.FlavorId,
+			Units:     p.Units,
+			Host:      p.Host,
+			ClusterId: p.ClusterId,
+			Live:      p.Live,
+			SnapId:    p.SnapId,
+			BackupId:  p.BackupId,
 		}, nil
 	}
 
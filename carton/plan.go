@@ -0,0 +1,111 @@
+/*
+** Copyright [2013-2017] [Megam Systems]
+**
+** Licensed under the Apache License, Version 2.0 (the "License");
+** you may not use this file except in compliance with the License.
+** You may obtain a copy of the License at
+**
+** http://www.apache.org/licenses/LICENSE-2.0
+**
+** Unless required by applicable law or agreed to in writing, software
+** distributed under the License is distributed on an "AS IS" BASIS,
+** WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+** See the License for the specific language governing permissions and
+** limitations under the License.
+ */
+package carton
+
+import (
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/megamsys/vertice/provision"
+)
+
+// PLAN_TOPIC is where the plans of dry run requests are published.
+const PLAN_TOPIC = "plans"
+
+// Plan is what a request would do to the boxes of its cartons.
+type Plan struct {
+	Request   *Requests           `json:"request"`
+	Boxes     []provision.BoxPlan `json:"boxes"`
+	PlannedAt time.Time           `json:"planned_at"`
+}
+
+// publishPlan sends the plan of a dry run to PLAN_TOPIC.
+var publishPlan = func(plan *Plan) error {
+	return publish(PLAN_TOPIC, plan)
+}
+
+// requestOps are the ops run on the boxes by the requests, keyed on
+// category/action.
+var requestOps = map[string]provision.BoxOp{
+	STATE + "/" + CREATE:              provision.CYCLE_DEPLOY,
+	STATE + "/" + DESTROY:             provision.CYCLE_DESTROY,
+	STATE + "/" + BOOTSTRAPPED:        provision.CYCLE_STATEUP,
+	STATE + "/" + STATEDOWN:           provision.CYCLE_STATEUP,
+	CONTROL + "/" + START:             provision.CYCLE_START,
+	CONTROL + "/" + STOP:              provision.CYCLE_STOP,
+	CONTROL + "/" + HARD_STOP:         provision.CYCLE_STOP,
+	CONTROL + "/" + RESTART:           provision.CYCLE_RESTART,
+	CONTROL + "/" + HARD_RESTART:      provision.CYCLE_RESTART,
+	CONTROL + "/" + SUSPEND:           provision.CYCLE_SUSPEND,
+	OPERATIONS + "/" + UPGRADE:        provision.CYCLE_UPGRADE,
+	OPERATIONS + "/" + NETWORK_UPDATE: provision.CYCLE_NETWORK,
+	OPERATIONS + "/" + ROLLBACK:       provision.CYCLE_ROLLBACK,
+	OPERATIONS + "/" + RESIZE:         provision.CYCLE_RESIZE,
+	OPERATIONS + "/" + SCALE:          provision.CYCLE_SCALE,
+	OPERATIONS + "/" + MIGRATE:        provision.CYCLE_MIGRATE,
+	DONE + "/" + RUNNING:              provision.CYCLE_RUNNING,
+	SNAPSHOT + "/" + SNAPCREATE:       provision.CYCLE_SNAP_CREATE,
+	SNAPSHOT + "/" + SNAPSAVE:         provision.CYCLE_SNAP_CREATE,
+	SNAPSHOT + "/" + SNAPRESTORE:      provision.CYCLE_SNAP_RESTORE,
+	SNAPSHOT + "/" + SNAPDELETE:       provision.CYCLE_SNAP_DELETE,
+	SNAPSHOT + "/" + SNAPCLONE:        provision.CYCLE_SNAP_CLONE,
+	BACKUPS + "/" + IMAGECREATE:       provision.CYCLE_BACKUP_CREATE,
+	BACKUPS + "/" + IMAGEDESTROY:      provision.CYCLE_BACKUP_DELETE,
+	DISKS + "/" + ATTACHDISK:          provision.CYCLE_DISK_ATTACH,
+	DISKS + "/" + DETACHDISK:          provision.CYCLE_DISK_DETACH,
+}
+
+// requestOp returns the op the request runs on the boxes.
+func requestOp(category, action string) (provision.BoxOp, error) {
+	op, ok := requestOps[category+"/"+action]
+	if !ok {
+		return "", newParseError([]string{category, action}, []string{"a request that works on boxes"})
+	}
+	return op, nil
+}
+
+// planCartons returns what op would do to each box of the cartons.
+func planCartons(c Cartons, op provision.BoxOp) []provision.BoxPlan {
+	plans := []provision.BoxPlan{}
+	for _, ca := range c {
+		if ca.Boxes == nil {
+			continue
+		}
+		for i := range *ca.Boxes {
+			box := &(*ca.Boxes)[i]
+			plans = append(plans, provision.PlanBox(ProvisionerMap[box.Provider], box, op))
+		}
+	}
+	return plans
+}
+
+// dryRun publishes what the request would do, without running it.
+func (p *ReqOperator) dryRun() (err error) {
+	op := provision.NewOperation(p.Id, p.CartonsId, p.AccountId, p.Category, p.Action)
+	defer func() { op.Done(err) }()
+	bop, err := requestOp(p.Category, p.Action)
+	if err != nil {
+		return err
+	}
+	c, err := getCartons(p)
+	if err != nil {
+		return err
+	}
+	plan := &Plan{Request: p.request(), Boxes: planCartons(c, bop), PlannedAt: time.Now()}
+	op.RecordPlan(plan.Boxes)
+	log.Debugf("  plan %s (%s/%s) : %d boxes", p.CartonsId, p.Category, p.Action, len(plan.Boxes))
+	return publishPlan(plan)
+}
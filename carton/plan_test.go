@@ -0,0 +1,99 @@
+package carton
+
+import (
+	"context"
+
+	"github.com/megamsys/libgo/utils"
+	"github.com/megamsys/vertice/provision"
+	"gopkg.in/check.v1"
+)
+
+type planningProvisioner struct {
+	provision.Provisioner
+	planned []provision.BoxOp
+}
+
+func (p *planningProvisioner) PlanActions(b *provision.Box, op provision.BoxOp) ([]string, error) {
+	p.planned = append(p.planned, op)
+	return []string{"machine", "destroy-old-machine"}, nil
+}
+
+func (s *S) TestRequestOp(c *check.C) {
+	op, err := requestOp(STATE, DESTROY)
+	c.Assert(err, check.IsNil)
+	c.Assert(op, check.Equals, provision.CYCLE_DESTROY)
+	op, err = requestOp(DISKS, DETACHDISK)
+	c.Assert(err, check.IsNil)
+	c.Assert(op, check.Equals, provision.CYCLE_DISK_DETACH)
+	_, err = requestOp(CONTROL, CANCEL)
+	c.Assert(err, check.NotNil)
+}
+
+func (s *S) TestPlanCartons(c *check.C) {
+	fake := &planningProvisioner{}
+	ProvisionerMap["planning"] = fake
+	defer delete(ProvisionerMap, "planning")
+	boxes := []provision.Box{
+		{Id: "COM01", CartonName: "bigbang", DomainName: "megambox.com", Provider: "planning", Region: "chennai", InstanceId: "1012", State: utils.StateRunning},
+		{Id: "COM02", CartonName: "smallbang", DomainName: "megambox.com", Provider: "planning", Region: "chennai", InstanceId: "1013", State: utils.StateDestroyed},
+		{Id: "COM03", CartonName: "nobang", DomainName: "megambox.com", Provider: "unknown", State: utils.StateRunning},
+	}
+	plans := planCartons(Cartons{&Carton{Boxes: &boxes}}, provision.CYCLE_STOP)
+	c.Assert(plans, check.HasLen, 3)
+	c.Assert(plans[0].Name, check.Equals, "bigbang.megambox.com")
+	c.Assert(plans[0].TargetId, check.Equals, "1012")
+	c.Assert(plans[0].Region, check.Equals, "chennai")
+	c.Assert(plans[0].Actions, check.DeepEquals, []string{"machine", "destroy-old-machine"})
+	c.Assert(plans[0].Error, check.Equals, "")
+	c.Assert(plans[1].Actions, check.HasLen, 0)
+	c.Assert(plans[1].Error, check.Matches, "stop not allowed .*")
+	c.Assert(plans[2].Error, check.Matches, ".*can't plan stop")
+	c.Assert(fake.planned, check.DeepEquals, []provision.BoxOp{provision.CYCLE_STOP})
+}
+
+type runningProcessor struct {
+	ran bool
+}
+
+func (r *runningProcessor) Process(ctx context.Context, c Cartons) error {
+	r.ran = true
+	return nil
+}
+
+func (r *runningProcessor) String() string {
+	return "running"
+}
+
+func (s *S) TestDryRunValuePayloadPlansWithoutRunning(c *check.C) {
+	fake := &planningProvisioner{}
+	ProvisionerMap["planning"] = fake
+	defer delete(ProvisionerMap, "planning")
+	boxes := []provision.Box{
+		{Id: "COM01", CartonName: "bigbang", DomainName: "megambox.com", Provider: "planning", InstanceId: "1012", State: utils.StateRunning},
+	}
+	oldGet, oldPublish := getCartons, publishPlan
+	defer func() { getCartons, publishPlan = oldGet, oldPublish }()
+	getCartons = func(p *ReqOperator) (Cartons, error) {
+		return Cartons{&Carton{Boxes: &boxes}}, nil
+	}
+	var published *Plan
+	publishPlan = func(plan *Plan) error {
+		published = plan
+		return nil
+	}
+
+	p, err := NewPayload([]byte(`{"id":"RER001","action":"destroy","cat_id":"ASM0012345678","account_id":"info@megam.io","category":"state","dry_run":true}`))
+	c.Assert(err, check.IsNil)
+	r, err := p.Convert()
+	c.Assert(err, check.IsNil)
+	c.Assert(r.DryRun, check.Equals, true)
+	run := &runningProcessor{}
+	var mp MegdProcessor = run
+	c.Assert(NewReqOperator(r).Accept(context.Background(), &mp), check.IsNil)
+	c.Assert(run.ran, check.Equals, false)
+	c.Assert(published, check.NotNil)
+	c.Assert(published.Request.DryRun, check.Equals, true)
+	c.Assert(published.Boxes, check.HasLen, 1)
+	c.Assert(published.Boxes[0].Actions, check.DeepEquals, []string{"machine", "destroy-old-machine"})
+	c.Assert(fake.planned, check.DeepEquals, []provision.BoxOp{provision.CYCLE_DESTROY})
+}
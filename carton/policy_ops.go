@@ -17,6 +17,7 @@ package carton
 
 import (
 	"bytes"
+	"context"
 	log "github.com/Sirupsen/logrus"
 	"github.com/megamsys/libgo/cmd"
 	"github.com/megamsys/libgo/pairs"
@@ -64,7 +65,13 @@ func newOperations(typ string) *Operations {
 	}
 }
 
-func NetworkUpdate(box *provision.Box) error {
+func NetworkUpdate(ctx context.Context, box *provision.Box) error {
+	if err := box.Can(provision.CYCLE_NETWORK); err != nil {
+		return err
+	}
+	if err := capable(box, provision.CYCLE_NETWORK); err != nil {
+		return err
+	}
 	var outBuffer bytes.Buffer
 	start := time.Now()
 	logWriter := lw.LogWriter{Box: box}
@@ -72,7 +79,7 @@ func NetworkUpdate(box *provision.Box) error {
 	defer logWriter.Close()
 	writer := io.MultiWriter(&outBuffer, &logWriter)
 
-	err := newOperations(NETWORK).network(box, writer)
+	err := newOperations(NETWORK).network(ctx, box, writer)
 	elapsed := time.Since(start)
 	if err != nil {
 		return err
@@ -84,10 +91,10 @@ func NetworkUpdate(box *provision.Box) error {
 	return nil
 }
 
-func (o *Operations) network(box *provision.Box, w io.Writer) error {
+func (o *Operations) network(ctx context.Context, box *provision.Box, w io.Writer) error {
 	if box.IsPolicyOk() {
 		if deployer, ok := ProvisionerMap[box.Provider].(provision.Network); ok {
-			return deployer.NetworkUpdate(box, w)
+			return deployer.NetworkUpdate(ctx, box, w)
 		}
 	}
 	return nil
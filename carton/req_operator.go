@@ -16,8 +16,11 @@
 package carton
 
 import (
+	"context"
+
 	log "github.com/Sirupsen/logrus"
 	"github.com/megamsys/libgo/cmd"
+	"github.com/megamsys/vertice/provision"
 )
 
 type ReqOperator struct {
@@ -26,22 +29,84 @@ type ReqOperator struct {
 	AccountId string
 	Category  string
 	Action    string
+	DryRun    bool
 }
 
 // NewReqOperator returns a new instance of ReqOperator
 // for the operatable id (Assemblies)
 func NewReqOperator(r *Requests) *ReqOperator {
-	return &ReqOperator{CartonsId: r.CatId, Category: r.Category, Action: r.Action, AccountId: r.AccountId}
+	return &ReqOperator{Id: r.Id, CartonsId: r.CatId, Category: r.Category, Action: r.Action, AccountId: r.AccountId, DryRun: r.DryRun}
 }
 
-func (p *ReqOperator) Accept(r *MegdProcessor) error {
-	c, err := p.Get()
-	if err != nil {
+// Accept runs the processor on the cartons, unless the journal says the
+// request was already served (or is being served). Dry runs only publish
+// the plan of the request.
+func (p *ReqOperator) Accept(ctx context.Context, r *MegdProcessor) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	req := p.request()
+	if p.DryRun {
+		err := p.dryRun()
+		if journal != nil {
+			journal.End(req, err)
+		}
 		return err
 	}
+	if journal != nil {
+		if ok, err := journal.Begin(req); !ok {
+			return err
+		}
+	}
+	err := p.accept(ctx, r)
+	if journal != nil {
+		journal.End(req, err)
+	}
+	return err
+}
+
+// accept records an operation for the request, which the provisioners update
+// as their pipeline actions run on the boxes. The operation can be cancelled
+// by a control/cancel request for the same assembly, which is run right away.
+func (p *ReqOperator) accept(ctx context.Context, r *MegdProcessor) (err error) {
 	md := *r
+	if p.Category == CONTROL && p.Action == CANCEL {
+		log.Debugf(cmd.Colorfy(md.String(), "cyan", "", "bold"))
+		return md.Process(ctx, nil)
+	}
+	op := provision.NewOperation(p.Id, p.CartonsId, p.AccountId, p.Category, p.Action)
+	defer func() { op.Done(err) }()
+	octx, release := withCancel(ctx, p.CartonsId)
+	defer func() {
+		cancelled := release()
+		switch {
+		case err == nil:
+		case cancelled:
+			err = ErrCancelled
+		case ctx.Err() != nil:
+			// vertice is shutting down, the request is to be served again.
+			err = context.Canceled
+		}
+	}()
+	c, err := getCartons(p)
+	if err != nil {
+		return err
+	}
+	for _, ca := range c {
+		ca.RequestId = p.Id
+		ca.track(op)
+	}
 	log.Debugf(cmd.Colorfy(md.String(), "cyan", "", "bold"))
-	return md.Process(c)
+	return md.Process(octx, c)
+}
+
+func (p *ReqOperator) request() *Requests {
+	return &Requests{Id: p.Id, CatId: p.CartonsId, AccountId: p.AccountId, Category: p.Category, Action: p.Action, DryRun: p.DryRun}
+}
+
+// getCartons returns the cartons the request works on.
+var getCartons = func(p *ReqOperator) (Cartons, error) {
+	return p.Get()
 }
 
 func (p *ReqOperator) Get() (Cartons, error) {
@@ -69,6 +134,10 @@ func (p *ReqOperator) Get() (Cartons, error) {
 		return c, nil
 
 	case SNAPSHOT:
+		if p.Action == SNAPCLONE {
+			// the clone is a new assembly, launched from the snapshot.
+			return p.getAssemblies()
+		}
 		s, err := GetSnap(p.CartonsId, p.AccountId)
 		if err != nil {
 			return nil, err
@@ -79,20 +148,20 @@ func (p *ReqOperator) Get() (Cartons, error) {
 		}
 		return c, nil
 	default:
-		a, err := Get(p.CartonsId, p.AccountId)
-		if err != nil {
-			return nil, err
-		}
-		c, err := a.MkCartons(p.AccountId)
-		if err != nil {
-			return nil, err
-		}
-		return c, nil
+		return p.getAssemblies()
+	}
+}
+
+func (p *ReqOperator) getAssemblies() (Cartons, error) {
+	a, err := Get(p.CartonsId, p.AccountId)
+	if err != nil {
+		return nil, err
 	}
+	return a.MkCartons(p.AccountId)
 }
 
 // MegdProcessor represents a single operation in vertice.
 type MegdProcessor interface {
-	Process(c Cartons) error
+	Process(ctx context.Context, c Cartons) error
 	String() string
 }
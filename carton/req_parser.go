@@ -17,6 +17,7 @@ package carton
 
 import (
 	"fmt"
+	"github.com/megamsys/vertice/provision"
 	"gopkg.in/yaml.v2"
 	"strings"
 	"time"
@@ -44,10 +45,15 @@ var (
 	HARD_RESTART = "hard-restart"
 	HARD_STOP    = "hard-stop"
 	SUSPEND      = "suspend"
+	CANCEL       = "cancel"
 
 	//the operation actions is just one called upgrade
 	OPERATIONS = "operations"
 	UPGRADE    = "upgrade"
+	ROLLBACK   = "rollback"
+	RESIZE     = "resize"
+	SCALE      = "scale"
+	MIGRATE    = "migrate"
 
 	//snapshot actions
 	SNAPSHOT    = "snapshot"
@@ -55,6 +61,7 @@ var (
 	SNAPRESTORE = "snaprestore"
 	SNAPDELETE  = "snapremove"
 	SNAPSAVE    = "snapsave"
+	SNAPCLONE   = "snapclone"
 
 	//vmbackup actions
 	BACKUPS      = "backup"
@@ -70,7 +77,14 @@ var (
 )
 
 type ReqParser struct {
-	name string
+	name     string
+	deployId string
+	commit   string
+	flavorId string
+	units    int
+	migrate  provision.MigrateTo
+	snapId   string
+	backupId string
 }
 
 // NewParser returns a new instance of Parser.
@@ -82,7 +96,15 @@ func NewReqParser(n string) *ReqParser {
 // eg: (state, create) => CreateProcess{}
 // After figuring out the process, we operate on it.
 func ParseRequest(r *Requests) (MegdProcessor, error) {
-	return NewReqParser(r.CatId).ParseRequest(r.Category, r.Action)
+	p := NewReqParser(r.CatId)
+	p.deployId = r.DeployId
+	p.commit = r.Commit
+	p.flavorId = r.FlavorId
+	p.units = r.Units
+	p.migrate = provision.MigrateTo{Host: r.Host, ClusterId: r.ClusterId, Live: r.Live}
+	p.snapId = r.SnapId
+	p.backupId = r.BackupId
+	return p.ParseRequest(r.Category, r.Action)
 }
 
 func (p *ReqParser) ParseRequest(category string, action string) (MegdProcessor, error) {
@@ -159,8 +181,13 @@ func (p *ReqParser) parseControl(action string) (MegdProcessor, error) {
 			Hard: true,
 		}, nil
 
+	case CANCEL:
+		return CancelProcess{
+			Name: p.name,
+		}, nil
+
 	default:
-		return nil, newParseError([]string{CONTROL, action}, []string{START, STOP, RESTART})
+		return nil, newParseError([]string{CONTROL, action}, []string{START, STOP, RESTART, CANCEL})
 	}
 }
 
@@ -168,14 +195,35 @@ func (p *ReqParser) parseOperations(action string) (MegdProcessor, error) {
 	switch action {
 	case UPGRADE:
 		return UpgradeProcess{
-			Name: p.name,
+			Name:   p.name,
+			Commit: p.commit,
 		}, nil
 	case NETWORK_UPDATE:
 		return UpdateNetworkProcess{
 			Name: p.name,
 		}, nil
+	case ROLLBACK:
+		return RollbackProcess{
+			Name:     p.name,
+			DeployId: p.deployId,
+		}, nil
+	case RESIZE:
+		return ResizeProcess{
+			Name:     p.name,
+			FlavorId: p.flavorId,
+		}, nil
+	case SCALE:
+		return ScaleProcess{
+			Name:  p.name,
+			Units: p.units,
+		}, nil
+	case MIGRATE:
+		return MigrateProcess{
+			Name: p.name,
+			To:   p.migrate,
+		}, nil
 	default:
-		return nil, newParseError([]string{OPERATIONS, action}, []string{UPGRADE})
+		return nil, newParseError([]string{OPERATIONS, action}, []string{UPGRADE, ROLLBACK, RESIZE, SCALE, MIGRATE})
 	}
 }
 
@@ -227,8 +275,14 @@ func (p *ReqParser) parseSnapshot(action string) (MegdProcessor, error) {
 		return SnapSaveAsProcess{
 			Name: p.name,
 		}, nil
+	case SNAPCLONE:
+		return SnapCloneProcess{
+			Name:     p.name,
+			SnapId:   p.snapId,
+			BackupId: p.backupId,
+		}, nil
 	default:
-		return nil, newParseError([]string{SNAPSHOT, action}, []string{SNAPCREATE, SNAPDELETE})
+		return nil, newParseError([]string{SNAPSHOT, action}, []string{SNAPCREATE, SNAPDELETE, SNAPCLONE})
 	}
 }
 
@@ -271,6 +325,25 @@ type Requests struct {
 	Action    string    `json:"action" cql:"action"`
 	Category  string    `json:"category" cql:"category"`
 	CreatedAt time.Time `json:"created_at" cql:"created_at"`
+	// DryRun requests publish what they would do, instead of doing it.
+	DryRun bool `json:"dry_run" cql:"dry_run"`
+	// DeployId is the deploy an operations/rollback goes back to.
+	DeployId string `json:"deploy_id,omitempty" cql:"deploy_id"`
+	// Commit is the pushed commit an operations/upgrade builds.
+	Commit string `json:"commit,omitempty" cql:"commit"`
+	// FlavorId is the flavor an operations/resize changes the boxes to.
+	FlavorId string `json:"flavor_id,omitempty" cql:"flavor_id"`
+	// Units is the number of containers an operations/scale runs a box in.
+	Units int `json:"units,omitempty" cql:"units"`
+	// Host or ClusterId is where an operations/migrate moves the boxes to,
+	// Live when they keep running meanwhile.
+	Host      string `json:"host,omitempty" cql:"host"`
+	ClusterId string `json:"cluster_id,omitempty" cql:"cluster_id"`
+	Live      bool   `json:"live,omitempty" cql:"live"`
+	// SnapId or BackupId is what a snapshot/snapclone launches the boxes of
+	// CatId from.
+	SnapId   string `json:"snap_id,omitempty" cql:"snap_id"`
+	BackupId string `json:"backup_id,omitempty" cql:"backup_id"`
 }
 
 type ApiRequests struct {
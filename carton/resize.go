@@ -0,0 +1,172 @@
+/*
+** Copyright [2013-2017] [Megam Systems]
+**
+** Licensed under the Apache License, Version 2.0 (the "License");
+** you may not use this file except in compliance with the License.
+** You may obtain a copy of the License at
+**
+** http://www.apache.org/licenses/LICENSE-2.0
+**
+** Unless required by applicable law or agreed to in writing, software
+** distributed under the License is distributed on an "AS IS" BASIS,
+** WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+** See the License for the specific language governing permissions and
+** limitations under the License.
+ */
+package carton
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/megamsys/libgo/events"
+	"github.com/megamsys/libgo/events/alerts"
+	constants "github.com/megamsys/libgo/utils"
+	lw "github.com/megamsys/libgo/writer"
+	lb "github.com/megamsys/vertice/logbox"
+	"github.com/megamsys/vertice/provision"
+)
+
+const (
+	// the allowances of a quota a resized box has to fit in.
+	QUOTA_CPU  = "cpu"
+	QUOTA_RAM  = "ram"
+	QUOTA_DISK = "disk"
+)
+
+type ResizeOpts struct {
+	B        *provision.Box
+	FlavorId string
+}
+
+// Resize changes the cpu, ram and disk of the box to the flavor, and bills it
+// for them from now on.
+func Resize(ctx context.Context, opts *ResizeOpts) error {
+	if err := opts.B.Can(provision.CYCLE_RESIZE); err != nil {
+		return err
+	}
+	if err := capable(opts.B, provision.CYCLE_RESIZE); err != nil {
+		return err
+	}
+	resizer, ok := ProvisionerMap[opts.B.Provider].(provision.Resizer)
+	if !ok {
+		return fmt.Errorf("provisioner %s can't resize %s", opts.B.Provider, opts.B.GetFullName())
+	}
+	flv, err := GetFlavor(opts.B.AccountId, opts.FlavorId)
+	if err != nil {
+		return err
+	}
+	to := flv.compute()
+	if err = canResize(opts.B, to); err != nil {
+		return err
+	}
+	var quota *Quota
+	if opts.B.QuotaId != "" {
+		if quota, err = NewQuota(opts.B.AccountId, opts.B.QuotaId); err != nil {
+			return err
+		}
+		if err = fitsQuota(quota, to); err != nil {
+			return err
+		}
+	}
+
+	logWriter := lw.NewLogWriter(opts.B)
+	defer logWriter.Close()
+	writer := io.MultiWriter(&logWriter)
+	fmt.Fprintf(writer, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- resize %s to %s %s", opts.B.GetFullName(), flv.Name, to.String())))
+	if err = resizer.Resize(ctx, opts.B, to, writer); err != nil {
+		fmt.Fprintf(writer, lb.W(lb.UPDATING, lb.ERROR, fmt.Sprintf("--- resize %s --> %s", opts.B.GetFullName(), err)))
+		return err
+	}
+	opts.B.Compute = to
+
+	asm, err := NewAssembly(opts.B.CartonId, opts.B.AccountId, opts.B.OrgId)
+	if err != nil {
+		return err
+	}
+	if err = asm.NukeAndSetInputs(resizedInputs(asm, flv)); err != nil {
+		return err
+	}
+	if quota != nil {
+		quota.Inputs.NukeAndSet(map[string][]string{FLAVOR_ID: {flv.Id}})
+		if err = quota.Update(); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(writer, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- resize %s OK", opts.B.GetFullName())))
+	return billResize(asm, flv)
+}
+
+// canResize refuses a smaller disk, disks can only grow.
+func canResize(b *provision.Box, to provision.BoxCompute) error {
+	if (&provision.Box{Compute: to}).GetHDD() < b.GetHDD() {
+		return fmt.Errorf("can't shrink the disk of %s to %s", b.GetFullName(), to.HDD)
+	}
+	return nil
+}
+
+// fitsQuota refuses a compute beyond the allowances of the quota. An
+// allowance the quota doesn't have isn't checked.
+func fitsQuota(q *Quota, to provision.BoxCompute) error {
+	want := &provision.Box{Compute: to}
+	allowed := &provision.Box{Compute: provision.BoxCompute{
+		Cpushare: q.Allowed.Match(QUOTA_CPU),
+		Memory:   q.Allowed.Match(QUOTA_RAM),
+		HDD:      q.Allowed.Match(QUOTA_DISK),
+	}}
+	switch {
+	case allowed.Compute.Cpushare != "" && want.GetCpushare() > allowed.GetCpushare(),
+		allowed.Compute.Memory != "" && want.GetMemory() > allowed.GetMemory(),
+		allowed.Compute.HDD != "" && want.GetHDD() > allowed.GetHDD():
+		return fmt.Errorf("%s is beyond the quota %s", to.String(), q.Name)
+	}
+	return nil
+}
+
+// resizedInputs are the inputs of the assembly for the flavor.
+func resizedInputs(asm *Assembly, flv *Flavor) map[string][]string {
+	m := map[string][]string{
+		FLAVOR_ID:     {flv.Id},
+		provision.CPU: {flv.getCpushare()},
+		provision.RAM: {flv.getMemory()},
+		provision.HDD: {flv.getHDD()},
+	}
+	if asm.IsContainer() {
+		m[CONTAINER_CPU_COST] = []string{flv.GetCpuCost()}
+		m[CONTAINER_MEMORY_COST] = []string{flv.GetMemoryCost()}
+	} else {
+		m[VM_CPU_COST] = []string{flv.GetCpuCost()}
+		m[VM_MEMORY_COST] = []string{flv.GetMemoryCost()}
+		m[VM_DISK_COST] = []string{flv.GetHDDCost()}
+	}
+	return m
+}
+
+// billResize starts the billing of the assembly for the resources of the
+// flavor. metrix picks the new flavor from the inputs on its next collection.
+func billResize(asm *Assembly, flv *Flavor) error {
+	now := time.Now().Local()
+	mi := asm.Resources(flv)
+	mi[constants.ACCOUNTID] = asm.AccountId
+	mi[constants.ASSEMBLYID] = asm.Id
+	mi[constants.ASSEMBLYNAME] = asm.GetFullName()
+	mi[constants.QUOTAID] = asm.QuotaId()
+	mi[constants.CONSUMED] = "0"
+	mi[constants.START_TIME] = now.Format(time.RFC3339)
+	mi[constants.END_TIME] = now.Format(time.RFC3339)
+	mi[constants.BILL_TYPE] = string(provision.CYCLE_RESIZE)
+
+	newEvent := events.NewMulti(
+		[]*events.Event{
+			&events.Event{
+				AccountsId:  asm.AccountId,
+				EventAction: alerts.BILLEDHISTORY,
+				EventType:   constants.EventBill,
+				EventData:   alerts.EventData{M: mi},
+				Timestamp:   now,
+			},
+		})
+	return newEvent.Write()
+}
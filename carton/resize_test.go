@@ -0,0 +1,21 @@
+package carton
+
+import (
+	"github.com/megamsys/libgo/pairs"
+	"github.com/megamsys/vertice/provision"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestCanResizeRefusesSmallerDisk(c *check.C) {
+	box := &provision.Box{CartonName: "bigbang", Compute: provision.BoxCompute{Cpushare: "2", Memory: "2GB", HDD: "40GB"}}
+	c.Assert(canResize(box, provision.BoxCompute{Cpushare: "4", Memory: "4GB", HDD: "40GB"}), check.IsNil)
+	c.Assert(canResize(box, provision.BoxCompute{Cpushare: "1", Memory: "1GB", HDD: "20GB"}), check.ErrorMatches, "can't shrink the disk .*")
+}
+
+func (s *S) TestFitsQuota(c *check.C) {
+	q := &Quota{Name: "starter", Allowed: pairs.JsonPairs{}}
+	q.Allowed.NukeAndSet(map[string][]string{QUOTA_CPU: {"2"}, QUOTA_RAM: {"4 GB"}})
+	c.Assert(fitsQuota(q, provision.BoxCompute{Cpushare: "2", Memory: "4GB", HDD: "80GB"}), check.IsNil)
+	c.Assert(fitsQuota(q, provision.BoxCompute{Cpushare: "4", Memory: "4GB", HDD: "80GB"}), check.ErrorMatches, ".* beyond the quota starter")
+	c.Assert(fitsQuota(q, provision.BoxCompute{Cpushare: "2", Memory: "8GB", HDD: "80GB"}), check.NotNil)
+}
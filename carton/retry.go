@@ -0,0 +1,228 @@
+/*
+** Copyright [2013-2017] [Megam Systems]
+**
+** Licensed under the Apache License, Version 2.0 (the "License");
+** you may not use this file except in compliance with the License.
+** You may obtain a copy of the License at
+**
+** http://www.apache.org/licenses/LICENSE-2.0
+**
+** Unless required by applicable law or agreed to in writing, software
+** distributed under the License is distributed on an "AS IS" BASIS,
+** WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+** See the License for the specific language governing permissions and
+** limitations under the License.
+ */
+package carton
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	nsqp "github.com/crackcomm/nsqueue/producer"
+	"github.com/fsouza/go-dockerclient"
+	"github.com/megamsys/vertice/meta"
+	"github.com/megamsys/vertice/toml"
+)
+
+const (
+	// DefaultAttempts is the number of times a request is tried before its
+	// sent to the dead letter topic.
+	DefaultAttempts = 5
+
+	// DefaultBackoff is the delay before the first retry, it doubles for every
+	// attempt after that.
+	DefaultBackoff = 10 * time.Second
+
+	// maxBackoff caps the requeue delay, nsqd refuses anything beyond its
+	// max-req-timeout (1h by default).
+	maxBackoff = 30 * time.Minute
+
+	// touchInterval keeps a message alive in nsqd while its request waits
+	// in the queue or is being worked on.
+	touchInterval = 30 * time.Second
+
+	DEAD_SUFFIX = "_dead"
+)
+
+// transientStatus are the http statuses of the gateways and daemons (docker,
+// rancher, opennebula rpc) that usually go away when tried again.
+var transientStatus = map[int]bool{
+	http.StatusBadGateway:         true,
+	http.StatusServiceUnavailable: true,
+	http.StatusGatewayTimeout:     true,
+}
+
+// transientErrnos are the socket errors of a peer that is restarting or
+// unreachable for a while.
+var transientErrnos = map[syscall.Errno]bool{
+	syscall.ECONNREFUSED: true,
+	syscall.ECONNRESET:   true,
+	syscall.ECONNABORTED: true,
+	syscall.EHOSTUNREACH: true,
+	syscall.ENETUNREACH:  true,
+	syscall.ETIMEDOUT:    true,
+	syscall.EPIPE:        true,
+}
+
+// Retry is the policy for requests that fail.
+type Retry struct {
+	Attempts int           `json:"attempts" toml:"attempts"`
+	Backoff  toml.Duration `json:"backoff" toml:"backoff"`
+}
+
+func NewRetry() Retry {
+	return Retry{
+		Attempts: DefaultAttempts,
+		Backoff:  toml.Duration(DefaultBackoff),
+	}
+}
+
+// Delay returns the exponential backoff for the attempt (starting at 1).
+func (rt Retry) Delay(attempt int) time.Duration {
+	d := time.Duration(rt.Backoff)
+	if d <= 0 {
+		d = DefaultBackoff
+	}
+	for i := 1; i < attempt; i++ {
+		d = d * 2
+		if d >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return d
+}
+
+func (rt Retry) attempts() int {
+	if rt.Attempts <= 0 {
+		return DefaultAttempts
+	}
+	return rt.Attempts
+}
+
+// IsTransient tells if the error is worth trying again. Only the typed errors
+// of the network and the http daemons are, an error message that just happens
+// to have a status code or "eof" in it isn't. A BoxesError is transient when
+// all the boxes that failed did so for a transient reason.
+func IsTransient(err error) bool {
+	switch e := err.(type) {
+	case nil:
+		return false
+	case BoxesError:
+		failed := e.Failed()
+		for _, r := range failed {
+			if r.Err != ErrBoxSkipped && !IsTransient(r.Err) {
+				return false
+			}
+		}
+		return len(failed) > 0
+	case *RollbackError:
+		// nothing is left deployed, the whole carton is tried again.
+		return e.Leftover == nil && IsTransient(e.Cause)
+	case *url.Error:
+		return IsTransient(e.Err)
+	case *net.OpError:
+		if e.Timeout() || e.Temporary() {
+			return true
+		}
+		return IsTransient(e.Err)
+	case *os.SyscallError:
+		return IsTransient(e.Err)
+	case syscall.Errno:
+		return transientErrnos[e]
+	case *docker.Error:
+		return transientStatus[e.Status]
+	case net.Error:
+		return e.Timeout() || e.Temporary()
+	}
+	return err == io.EOF || err == io.ErrUnexpectedEOF
+}
+
+// Message is the queue message a request came in, which gets answered only
+// after the request is served.
+type Message interface {
+	Finish()
+	RequeueWithoutBackoff(delay time.Duration)
+	Touch()
+}
+
+// DeadLetter is what gets published to the dead topic when a request can't
+// be served.
+type DeadLetter struct {
+	Request  *Requests `json:"request"`
+	Error    string    `json:"error"`
+	Attempts int       `json:"attempts"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// Responder answers the queue message of a request once its served.
+// Transient failures are requeued with backoff, and the rest (or the ones
+// that ran out of attempts) are finished and sent to the dead topic. The boxes
+// a requeued request already served are journalled, and aren't worked on again.
+type Responder struct {
+	Topic string
+	Retry Retry
+}
+
+// KeepAlive touches the message till done is closed.
+func (rs *Responder) KeepAlive(m Message, done chan struct{}) {
+	t := time.NewTicker(touchInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			m.Touch()
+		case <-done:
+			return
+		}
+	}
+}
+
+// Respond answers the message for the request r that was tried attempts times.
+func (rs *Responder) Respond(m Message, attempts int, r *Requests, err error) {
+	switch err {
+	case nil, ErrCancelled:
+		m.Finish()
+		return
+	case context.Canceled:
+		// cut short by a shutdown, let another run of vertice serve it.
+		m.RequeueWithoutBackoff(0)
+		return
+	}
+	if IsTransient(err) && attempts < rs.Retry.attempts() {
+		delay := rs.Retry.Delay(attempts)
+		log.Warnf("  requeue %s (%s/%s) in %s, attempt %d : %s", r.CatId, r.Category, r.Action, delay, attempts, err)
+		m.RequeueWithoutBackoff(delay)
+		return
+	}
+	m.Finish()
+	if perr := rs.publishDead(&DeadLetter{Request: r, Error: err.Error(), Attempts: attempts, FailedAt: time.Now()}); perr != nil {
+		log.Errorf("  unable to send %s (%s/%s) to %s%s : %s", r.CatId, r.Category, r.Action, rs.Topic, DEAD_SUFFIX, perr)
+	}
+}
+
+func (rs *Responder) publishDead(d *DeadLetter) error {
+	return publish(rs.Topic+DEAD_SUFFIX, d)
+}
+
+// publish sends v as json to the nsq topic.
+func publish(topic string, v interface{}) error {
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	pons := nsqp.New()
+	if err = pons.Connect(meta.MC.NSQd[0]); err != nil {
+		return err
+	}
+	defer pons.Stop()
+	return pons.Publish(topic, bytes)
+}
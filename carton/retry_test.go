@@ -0,0 +1,86 @@
+package carton
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/megamsys/vertice/toml"
+	"gopkg.in/check.v1"
+)
+
+type fakeMessage struct {
+	finished bool
+	requeued time.Duration
+	touched  int
+}
+
+func (m *fakeMessage) Finish()                                   { m.finished = true }
+func (m *fakeMessage) RequeueWithoutBackoff(delay time.Duration) { m.requeued = delay }
+func (m *fakeMessage) Touch()                                    { m.touched++ }
+
+func (s *S) TestRetryDelayIsExponential(c *check.C) {
+	rt := Retry{Attempts: 5, Backoff: toml.Duration(10 * time.Second)}
+	c.Assert(rt.Delay(1), check.Equals, 10*time.Second)
+	c.Assert(rt.Delay(2), check.Equals, 20*time.Second)
+	c.Assert(rt.Delay(4), check.Equals, 80*time.Second)
+	c.Assert(rt.Delay(20), check.Equals, maxBackoff)
+}
+
+func (s *S) TestIsTransient(c *check.C) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: &os.SyscallError{Syscall: "getsockopt", Err: syscall.ECONNREFUSED}}
+	c.Assert(IsTransient(nil), check.Equals, false)
+	c.Assert(IsTransient(refused), check.Equals, true)
+	c.Assert(IsTransient(&url.Error{Op: "Post", URL: "http://192.168.0.100:2633/RPC2", Err: refused}), check.Equals, true)
+	c.Assert(IsTransient(&docker.Error{Status: http.StatusGatewayTimeout}), check.Equals, true)
+	c.Assert(IsTransient(&docker.Error{Status: http.StatusNotFound}), check.Equals, false)
+	c.Assert(IsTransient(io.EOF), check.Equals, true)
+	c.Assert(IsTransient(errors.New("[one] template not found")), check.Equals, false)
+	c.Assert(IsTransient(errors.New("image 5030 of 504 MB not found")), check.Equals, false)
+	c.Assert(IsTransient(errors.New("unexpected eof in the template")), check.Equals, false)
+}
+
+func (s *S) TestIsTransientBoxes(c *check.C) {
+	c.Assert(IsTransient(BoxesError{{Id: "COM01"}, {Id: "COM02", Err: syscall.ECONNRESET}, {Id: "COM03", Err: ErrBoxSkipped}}), check.Equals, true)
+	c.Assert(IsTransient(BoxesError{{Id: "COM01", Err: syscall.ECONNRESET}, {Id: "COM02", Err: errors.New("quota exceeded")}}), check.Equals, false)
+	c.Assert(IsTransient(BoxesError{{Id: "COM01", Err: ErrBoxSkipped}}), check.Equals, true)
+}
+
+func (s *S) TestRespondFinishesOnSuccess(c *check.C) {
+	m := &fakeMessage{}
+	rs := &Responder{Topic: "vms", Retry: NewRetry()}
+	rs.Respond(m, 1, &Requests{CatId: "ASM001"}, nil)
+	c.Assert(m.finished, check.Equals, true)
+	c.Assert(m.requeued, check.Equals, time.Duration(0))
+}
+
+func (s *S) TestRespondRequeuesTransient(c *check.C) {
+	m := &fakeMessage{}
+	rs := &Responder{Topic: "vms", Retry: Retry{Attempts: 3, Backoff: toml.Duration(time.Second)}}
+	rs.Respond(m, 2, &Requests{CatId: "ASM001"}, syscall.ECONNREFUSED)
+	c.Assert(m.finished, check.Equals, false)
+	c.Assert(m.requeued, check.Equals, 2*time.Second)
+}
+
+func (s *S) TestRespondFinishesCancelled(c *check.C) {
+	m := &fakeMessage{requeued: -1}
+	rs := &Responder{Topic: "vms", Retry: NewRetry()}
+	rs.Respond(m, 1, &Requests{CatId: "ASM001"}, ErrCancelled)
+	c.Assert(m.finished, check.Equals, true)
+	c.Assert(m.requeued, check.Equals, time.Duration(-1))
+}
+
+func (s *S) TestRespondRequeuesOnShutdown(c *check.C) {
+	m := &fakeMessage{requeued: -1}
+	rs := &Responder{Topic: "vms", Retry: NewRetry()}
+	rs.Respond(m, DefaultAttempts, &Requests{CatId: "ASM001"}, context.Canceled)
+	c.Assert(m.finished, check.Equals, false)
+	c.Assert(m.requeued, check.Equals, time.Duration(0))
+}
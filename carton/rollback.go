@@ -0,0 +1,94 @@
+/*
+** Copyright [2013-2017] [Megam Systems]
+**
+** Licensed under the Apache License, Version 2.0 (the "License");
+** you may not use this file except in compliance with the License.
+** You may obtain a copy of the License at
+**
+** http://www.apache.org/licenses/LICENSE-2.0
+**
+** Unless required by applicable law or agreed to in writing, software
+** distributed under the License is distributed on an "AS IS" BASIS,
+** WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+** See the License for the specific language governing permissions and
+** limitations under the License.
+ */
+package carton
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	constants "github.com/megamsys/libgo/utils"
+	lw "github.com/megamsys/libgo/writer"
+	"github.com/megamsys/vertice/provision"
+)
+
+type RollbackOpts struct {
+	B *provision.Box
+	// DeployId is the deploy to rollback to, the one before the current
+	// deploy when empty.
+	DeployId string
+}
+
+// Rollback replaces the unit of the box with one deployed from the image of
+// an earlier deploy. Docker boxes get the image the container ran, and one
+// boxes the image (or the backup) the vm was created from. The quota of the box
+// is checked before its unit is destroyed, and stays held by the box.
+func Rollback(ctx context.Context, opts *RollbackOpts) error {
+	if err := opts.B.Can(provision.CYCLE_ROLLBACK); err != nil {
+		return err
+	}
+	h := Deploys()
+	if h == nil {
+		return ErrNoDeployHistory
+	}
+	d, err := h.RollbackTo(opts.B.Id, opts.DeployId)
+	if err != nil {
+		return err
+	}
+	if err = quotaPaid(opts.B); err != nil {
+		return err
+	}
+	logWriter := lw.LogWriter{Box: opts.B}
+	logWriter.Async()
+	writer := io.MultiWriter(&logWriter)
+	fmt.Fprintf(writer, "    rollback (%s) to deploy %s (%s, %s)\n", opts.B.GetFullName(), d.Id, d.Image, d.Timestamp)
+
+	// the box is replaced and not gone, so the destroy isn't notified and
+	// the quota isn't freed.
+	opts.B.Muted = true
+	opts.B.Replacing = true
+	err = ProvisionerMap[opts.B.Provider].Destroy(ctx, opts.B, writer)
+	opts.B.Muted = false
+	logWriter.Close()
+	if err != nil {
+		opts.B.Replacing = false
+		log.Errorf("  rollback of %s failed removing the current unit : %s", opts.B.GetFullName(), err)
+		return err
+	}
+	opts.B.State = constants.StateInitializing
+	opts.B.Commit = d.Commit
+	err = Deploy(ctx, &DeployOpts{B: opts.B, Image: d.Image, Backup: d.Backup || d.Origin == ORIGIN_BACKUP, Origin: ORIGIN_ROLLBACK})
+	opts.B.Replacing = false
+	return err
+}
+
+// quotaPaid returns an error when the quota the box is on isn't paid, as the
+// deploy of the box would be refused.
+func quotaPaid(b *provision.Box) error {
+	if len(b.QuotaId) == 0 {
+		return nil
+	}
+	q, err := NewQuota(b.AccountId, b.QuotaId)
+	if err != nil {
+		return err
+	}
+	if strings.ToLower(q.Status) != "paid" {
+		return fmt.Errorf("quota %s of %s isn't paid, it can't be deployed again", b.QuotaId, b.GetFullName())
+	}
+	return nil
+}
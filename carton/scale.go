@@ -0,0 +1,56 @@
+/*
+** Copyright [2013-2017] [Megam Systems]
+**
+** Licensed under the Apache License, Version 2.0 (the "License");
+** you may not use this file except in compliance with the License.
+** You may obtain a copy of the License at
+**
+** http://www.apache.org/licenses/LICENSE-2.0
+**
+** Unless required by applicable law or agreed to in writing, software
+** distributed under the License is distributed on an "AS IS" BASIS,
+** WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+** See the License for the specific language governing permissions and
+** limitations under the License.
+ */
+package carton
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	lw "github.com/megamsys/libgo/writer"
+	lb "github.com/megamsys/vertice/logbox"
+	"github.com/megamsys/vertice/provision"
+)
+
+type ScaleOpts struct {
+	B     *provision.Box
+	Units int
+}
+
+// Scale runs the box in opts.Units units, the provisioner adds or removes them
+// to get there.
+func Scale(ctx context.Context, opts *ScaleOpts) error {
+	if err := opts.B.Can(provision.CYCLE_SCALE); err != nil {
+		return err
+	}
+	if opts.Units < 1 {
+		return fmt.Errorf("scale of %s needs at least one unit", opts.B.GetFullName())
+	}
+	scaler, ok := ProvisionerMap[opts.B.Provider].(provision.Scaler)
+	if !ok {
+		return fmt.Errorf("provisioner %s can't scale %s", opts.B.Provider, opts.B.GetFullName())
+	}
+	logWriter := lw.NewLogWriter(opts.B)
+	defer logWriter.Close()
+	writer := io.MultiWriter(&logWriter)
+	fmt.Fprintf(writer, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- scale %s to %d units", opts.B.GetFullName(), opts.Units)))
+	if err := scaler.Scale(ctx, opts.B, opts.Units, writer); err != nil {
+		fmt.Fprintf(writer, lb.W(lb.UPDATING, lb.ERROR, fmt.Sprintf("--- scale %s --> %s", opts.B.GetFullName(), err)))
+		return err
+	}
+	fmt.Fprintf(writer, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- scale %s to %d units OK", opts.B.GetFullName(), opts.Units)))
+	return nil
+}
@@ -2,6 +2,7 @@ package carton
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	log "github.com/Sirupsen/logrus"
 	"github.com/megamsys/libgo/api"
@@ -10,6 +11,7 @@ import (
 	constants "github.com/megamsys/libgo/utils"
 	lw "github.com/megamsys/libgo/writer"
 	"github.com/megamsys/vertice/meta"
+	"github.com/megamsys/vertice/provision"
 	"gopkg.in/yaml.v2"
 	"io"
 	"strings"
@@ -53,14 +55,20 @@ func (s *Snaps) String() string {
 }
 
 // ChangeState runs a state increment of a machine or a container.
-func CreateSnapshot(opts *DiskOpts) error {
+func CreateSnapshot(ctx context.Context, opts *DiskOpts) error {
+	if err := opts.B.Can(provision.CYCLE_SNAP_CREATE); err != nil {
+		return err
+	}
+	if err := capable(opts.B, provision.CYCLE_SNAP_CREATE); err != nil {
+		return err
+	}
 	var outBuffer bytes.Buffer
 	start := time.Now()
 	logWriter := lw.LogWriter{Box: opts.B}
 	logWriter.Async()
 	defer logWriter.Close()
 	writer := io.MultiWriter(&outBuffer, &logWriter)
-	err := ProvisionerMap[opts.B.Provider].CreateSnapshot(opts.B, writer)
+	err := ProvisionerMap[opts.B.Provider].CreateSnapshot(ctx, opts.B, writer)
 	elapsed := time.Since(start)
 
 	if err != nil {
@@ -75,14 +83,20 @@ func CreateSnapshot(opts *DiskOpts) error {
 }
 
 // ChangeState runs a state increment of a machine or a container.
-func RestoreSnapshot(opts *DiskOpts) error {
+func RestoreSnapshot(ctx context.Context, opts *DiskOpts) error {
+	if err := opts.B.Can(provision.CYCLE_SNAP_RESTORE); err != nil {
+		return err
+	}
+	if err := capable(opts.B, provision.CYCLE_SNAP_RESTORE); err != nil {
+		return err
+	}
 	var outBuffer bytes.Buffer
 	start := time.Now()
 	logWriter := lw.LogWriter{Box: opts.B}
 	logWriter.Async()
 	defer logWriter.Close()
 	writer := io.MultiWriter(&outBuffer, &logWriter)
-	err := ProvisionerMap[opts.B.Provider].RestoreSnapshot(opts.B, writer)
+	err := ProvisionerMap[opts.B.Provider].RestoreSnapshot(ctx, opts.B, writer)
 	elapsed := time.Since(start)
 
 	if err != nil {
@@ -97,14 +111,20 @@ func RestoreSnapshot(opts *DiskOpts) error {
 }
 
 // ChangeState runs a state increment of a machine or a container.
-func SnapshotSaveAs(opts *DiskOpts) error {
+func SnapshotSaveAs(ctx context.Context, opts *DiskOpts) error {
+	if err := opts.B.Can(provision.CYCLE_SNAP_CREATE); err != nil {
+		return err
+	}
+	if err := capable(opts.B, provision.CYCLE_SNAP_CREATE); err != nil {
+		return err
+	}
 	var outBuffer bytes.Buffer
 	start := time.Now()
 	logWriter := lw.LogWriter{Box: opts.B}
 	logWriter.Async()
 	defer logWriter.Close()
 	writer := io.MultiWriter(&outBuffer, &logWriter)
-	err := ProvisionerMap[opts.B.Provider].CreateSnapshot(opts.B, writer)
+	err := ProvisionerMap[opts.B.Provider].CreateSnapshot(ctx, opts.B, writer)
 	elapsed := time.Since(start)
 
 	if err != nil {
@@ -119,14 +139,20 @@ func SnapshotSaveAs(opts *DiskOpts) error {
 }
 
 // ChangeState runs a state increment of a machine or a container.
-func DeleteSnapshot(opts *DiskOpts) error {
+func DeleteSnapshot(ctx context.Context, opts *DiskOpts) error {
+	if err := opts.B.Can(provision.CYCLE_SNAP_DELETE); err != nil {
+		return err
+	}
+	if err := capable(opts.B, provision.CYCLE_SNAP_DELETE); err != nil {
+		return err
+	}
 	var outBuffer bytes.Buffer
 	start := time.Now()
 	logWriter := lw.LogWriter{Box: opts.B}
 	logWriter.Async()
 	defer logWriter.Close()
 	writer := io.MultiWriter(&outBuffer, &logWriter)
-	err := ProvisionerMap[opts.B.Provider].DeleteSnapshot(opts.B, writer)
+	err := ProvisionerMap[opts.B.Provider].DeleteSnapshot(ctx, opts.B, writer)
 	elapsed := time.Since(start)
 
 	if err != nil {
@@ -2,6 +2,7 @@ package carton
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"time"
 
@@ -21,14 +22,17 @@ type StateChangeOpts struct {
 }
 
 // ChangeState runs a state increment of a machine or a container.
-func ChangeState(opts *StateChangeOpts) error {
+func ChangeState(ctx context.Context, opts *StateChangeOpts) error {
+	if err := opts.B.Can(provision.CYCLE_STATEUP); err != nil {
+		return err
+	}
 	var outBuffer bytes.Buffer
 	start := time.Now()
 	logWriter := lw.LogWriter{Box: opts.B}
 	logWriter.Async()
 	defer logWriter.Close()
 	writer := io.MultiWriter(&outBuffer, &logWriter)
-	err := moveState(opts, writer)
+	err := moveState(ctx, opts, writer)
 	elapsed := time.Since(start)
 	saveErr := saveStateData(opts, outBuffer.String(), elapsed, err)
 	if saveErr != nil {
@@ -40,10 +44,10 @@ func ChangeState(opts *StateChangeOpts) error {
 	return nil
 }
 
-func moveState(opts *StateChangeOpts, writer io.Writer) error {
+func moveState(ctx context.Context, opts *StateChangeOpts, writer io.Writer) error {
 	if &opts.Changed != nil {
 		if changer, ok := ProvisionerMap[opts.B.Provider].(provision.StateChanger); ok {
-			return changer.SetState(opts.B, writer, opts.Changed)
+			return changer.SetState(ctx, opts.B, writer, opts.Changed)
 		}
 	}
 	return nil
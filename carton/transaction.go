@@ -0,0 +1,107 @@
+/*
+** Copyright [2013-2017] [Megam Systems]
+**
+** Licensed under the Apache License, Version 2.0 (the "License");
+** you may not use this file except in compliance with the License.
+** You may obtain a copy of the License at
+**
+** http://www.apache.org/licenses/LICENSE-2.0
+**
+** Unless required by applicable law or agreed to in writing, software
+** distributed under the License is distributed on an "AS IS" BASIS,
+** WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+** See the License for the specific language governing permissions and
+** limitations under the License.
+ */
+package carton
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/megamsys/libgo/events/alerts"
+	lw "github.com/megamsys/libgo/writer"
+	"github.com/megamsys/vertice/provision"
+)
+
+// RollbackError is returned by a transactional deploy that failed, after the
+// boxes it deployed are destroyed.
+type RollbackError struct {
+	Cause      error
+	RolledBack []string
+	Leftover   error
+}
+
+func (e *RollbackError) Error() string {
+	msg := fmt.Sprintf("deploy rolled back (%d boxes destroyed) : %s", len(e.RolledBack), e.Cause)
+	if e.Leftover != nil {
+		msg = msg + "\n  rollback incomplete : " + e.Leftover.Error()
+	}
+	return msg
+}
+
+// deployOrRollback deploys all the boxes or none. The boxes that were deployed
+// before one of them failed get destroyed, the failed box is cleaned up by the
+// backward actions of its provisioner pipeline.
+func (c *Carton) deployOrRollback(ctx context.Context) error {
+	err := c.eachBox(ctx, func(ctx context.Context, box *provision.Box) error {
+		box.Transactional = true
+		box.Muted = true
+		return Deploy(ctx, &DeployOpts{B: box})
+	})
+	if err == nil {
+		return nil
+	}
+
+	deployed := make([]provision.Box, 0, len(*c.Boxes))
+	if berr, ok := err.(BoxesError); ok {
+		for i, r := range berr {
+			if r.Err == nil {
+				deployed = append(deployed, (*c.Boxes)[i])
+			}
+		}
+	}
+
+	rerr := &RollbackError{Cause: err}
+	for _, b := range deployed {
+		rerr.RolledBack = append(rerr.RolledBack, b.GetFullName())
+	}
+	log.Warnf("  rolling back %s, destroying %v : %s", c.Name, rerr.RolledBack, err)
+
+	// the request may be cancelled, the rollback has to run nonetheless.
+	undo := &Carton{Name: c.Name, Boxes: &deployed, OnFailure: BEST_EFFORT}
+	rerr.Leftover = undo.eachBox(context.Background(), func(ctx context.Context, box *provision.Box) error {
+		box.Muted = true
+		return Destroy(ctx, &DestroyOpts{B: box})
+	})
+	if journal != nil {
+		// a retry deploys all the boxes again.
+		ids := make([]string, 0, len(deployed))
+		for _, b := range deployed {
+			ids = append(ids, b.Id)
+		}
+		journal.Unserve(c.RequestId, ids...)
+	}
+	c.notifyRollback(rerr)
+	return rerr
+}
+
+// notifyRollback records why the deploy rolled back, and sends a single
+// failure event for the carton.
+func (c *Carton) notifyRollback(rerr *RollbackError) {
+	if len(*c.Boxes) == 0 {
+		return
+	}
+	box := (*c.Boxes)[0]
+	box.Muted = false
+	if box.OperationId != "" {
+		provision.RecordRollback(box.OperationId, rerr.Error())
+	}
+	logWriter := lw.LogWriter{Box: &box}
+	logWriter.Async()
+	defer logWriter.Close()
+	if err := DoneNotify(&box, &logWriter, alerts.FAILURE, rerr.Error()); err != nil {
+		log.Errorf("  unable to notify the rollback of %s : %s", c.Name, err)
+	}
+}
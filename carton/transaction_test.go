@@ -0,0 +1,30 @@
+package carton
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/megamsys/libgo/events/alerts"
+	"github.com/megamsys/vertice/provision"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestRollbackError(c *check.C) {
+	err := &RollbackError{Cause: errors.New("quota exceeded"), RolledBack: []string{"web.megambox.com", "db.megambox.com"}}
+	c.Assert(err, check.ErrorMatches, "deploy rolled back \\(2 boxes destroyed\\) : quota exceeded")
+	err.Leftover = errors.New("db.megambox.com : connection refused")
+	c.Assert(err, check.ErrorMatches, "(?s).*rollback incomplete : db.megambox.com : connection refused")
+}
+
+func (s *S) TestDoneNotifySkipsMutedFailures(c *check.C) {
+	var buf bytes.Buffer
+	box := &provision.Box{CartonName: "web", Muted: true}
+	c.Assert(DoneNotify(box, &buf, alerts.FAILURE, "quota exceeded"), check.IsNil)
+	c.Assert(DoneNotify(box, &buf, alerts.DESTROYED, ""), check.IsNil)
+	c.Assert(buf.Len(), check.Equals, 0)
+}
+
+func (s *S) TestTransactionalStopsOnFirst(c *check.C) {
+	ca := &Carton{OnFailure: BEST_EFFORT, Transactional: true}
+	c.Assert(ca.stopOnFirst(), check.Equals, true)
+}
@@ -1,18 +1,28 @@
 package carton
 
 import (
+	"bytes"
+	"context"
+	"fmt"
 	"io"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
 	lw "github.com/megamsys/libgo/writer"
 	"github.com/megamsys/vertice/provision"
+	"github.com/megamsys/vertice/repository"
 )
 
 type Upgradeable struct {
-	B             *provision.Box
-	w             io.Writer
+	B *provision.Box
+	w io.Writer
+	// Commit is the pushed commit of the repo to upgrade to, empty is the
+	// head of the repo.
+	Commit string
+	// ShouldRestart replaces the running unit of the box with the upgraded
+	// one, else the box picks it up when its next deployed.
 	ShouldRestart bool
+	out           bytes.Buffer
 }
 
 func NewUpgradeable(box *provision.Box) *Upgradeable {
@@ -26,45 +36,66 @@ func NewUpgradeable(box *provision.Box) *Upgradeable {
 
 func (u *Upgradeable) register() {}
 
-// this is for CI/BIND for docker only
-func (u *Upgradeable) Upgrade() error {
+// Upgrade rebuilds a box deployed from git at the commit. Docker boxes get a
+// new image, one boxes run their contextualization again.
+func (u *Upgradeable) Upgrade(ctx context.Context) error {
+	if err := u.B.Can(provision.CYCLE_UPGRADE); err != nil {
+		return err
+	}
+	if u.B.Repo == nil || u.B.Repo.Type == repository.IMAGE || u.B.Repo.OneClick {
+		return fmt.Errorf("%s isn't deployed from git, nothing to upgrade", u.B.GetFullName())
+	}
 	logWriter := lw.NewLogWriter(u.B)
 	defer logWriter.Close()
-	writer := io.MultiWriter(&logWriter)
-	err := u.operateBox(writer)
+	writer := io.MultiWriter(&u.out, &logWriter)
+	err := u.operateBox(ctx, writer)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (u *Upgradeable) operateBox(writer io.Writer) error {
+func (u *Upgradeable) operateBox(ctx context.Context, writer io.Writer) error {
 	u.w = writer
+	upgrader, ok := ProvisionerMap[u.B.Provider].(provision.Upgrader)
+	if !ok {
+		return fmt.Errorf("provisioner %s can't upgrade %s", u.B.Provider, u.B.GetFullName())
+	}
+	running := u.B.Commit
+	if u.Commit != "" {
+		u.B.Commit = u.Commit
+	}
 	start := time.Now()
-	/*opsRan, err := upgrade.Run(upgrade.RunArgs{
-		Name:   u.B.GetFullName(),
-		O:      u.B.Operations,
-		Writer: writer,
-		Force:  false,
-	})
-	if err != nil {
-		return err
-	}*/
-
+	imageId, err := upgrader.Upgrade(ctx, u.B, u.ShouldRestart, writer)
 	elapsed := time.Since(start)
 
-	if err := u.saveData(elapsed); err != nil {
-		log.Errorf("WARNING: couldn't save ops data, ops opts: %#v", u)
+	if !u.ShouldRestart {
+		// nothing runs the upgrade yet, so there is no deploy to remember.
+		u.B.Commit = running
 		return err
 	}
 
-	if !u.ShouldRestart {
-		return nil
+	if saveErr := u.saveData(imageId, elapsed, err); saveErr != nil {
+		log.Errorf("WARNING: couldn't save upgrade data, upgrade opts: %#v", u)
 	}
+	if err != nil {
+		u.B.Commit = running
+		return err
+	}
+	return u.saveCommit()
+}
 
-	return nil
+// saveData records the upgrade as a deploy of the box, which can be rolled
+// back to like any other.
+func (u *Upgradeable) saveData(imageId string, duration time.Duration, upgradeError error) error {
+	return saveDeployData(&DeployOpts{B: u.B, Origin: ORIGIN_GIT}, imageId, u.out.String(), duration, upgradeError)
 }
 
-func (u *Upgradeable) saveData(duration time.Duration) error {
-	return nil
+// saveCommit remembers the commit the box runs now.
+func (u *Upgradeable) saveCommit() error {
+	comp, err := NewComponent(u.B.Id, u.B.AccountId, u.B.OrgId)
+	if err != nil {
+		return err
+	}
+	return comp.SetCommit(u.B.Commit, u.B.AccountId)
 }
@@ -0,0 +1,85 @@
+package carton
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/megamsys/libgo/utils"
+	"github.com/megamsys/vertice/provision"
+	"github.com/megamsys/vertice/repository"
+	"gopkg.in/check.v1"
+)
+
+type upgradingProvisioner struct {
+	provision.Provisioner
+	commits []string
+	err     error
+}
+
+func (p *upgradingProvisioner) Upgrade(ctx context.Context, b *provision.Box, restart bool, w io.Writer) (string, error) {
+	p.commits = append(p.commits, b.Commit)
+	if p.err != nil {
+		return "", p.err
+	}
+	return "bigbang.megambox.com:" + b.Commit, nil
+}
+
+func (s *S) TestUpgradeRefusesImageBoxes(c *check.C) {
+	box := &provision.Box{Id: "COM01", CartonName: "bigbang", State: utils.StateRunning,
+		Repo: &repository.Repo{Type: repository.IMAGE}}
+	err := NewUpgradeable(box).Upgrade(context.Background())
+	c.Assert(err, check.ErrorMatches, ".*isn't deployed from git.*")
+}
+
+func (s *S) TestUpgradeFailureKeepsCommit(c *check.C) {
+	dir, err := ioutil.TempDir("", "deploys")
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(dir)
+	h, err := NewDeployHistory(dir)
+	c.Assert(err, check.IsNil)
+	deploysMu.Lock()
+	old := deploys
+	deploys = h
+	deploysMu.Unlock()
+	defer func() {
+		deploysMu.Lock()
+		deploys = old
+		deploysMu.Unlock()
+	}()
+
+	fake := &upgradingProvisioner{err: errors.New("build failed")}
+	ProvisionerMap["upgrading"] = fake
+	defer delete(ProvisionerMap, "upgrading")
+	box := &provision.Box{Id: "COM01", CartonName: "bigbang", Provider: "upgrading", State: utils.StateRunning,
+		Commit: "a1b2c3", Repo: &repository.Repo{Type: repository.GIT, URL: "https://github.com/megamsys/bigbang.git"}}
+	u := NewUpgradeable(box)
+	u.Commit = "d4e5f6"
+	err = u.Upgrade(context.Background())
+	c.Assert(err, check.ErrorMatches, "build failed")
+	c.Assert(fake.commits, check.DeepEquals, []string{"d4e5f6"})
+	c.Assert(box.Commit, check.Equals, "a1b2c3")
+	list, err := h.List("COM01")
+	c.Assert(err, check.IsNil)
+	c.Assert(list, check.HasLen, 1)
+	c.Assert(list[0].Commit, check.Equals, "d4e5f6")
+	c.Assert(list[0].Origin, check.Equals, ORIGIN_GIT)
+	c.Assert(list[0].Error, check.Equals, "build failed")
+	c.Assert(list[0].CanRollback, check.Equals, false)
+}
+
+func (s *S) TestUpgradeWithoutRestartRecordsNothing(c *check.C) {
+	fake := &upgradingProvisioner{}
+	ProvisionerMap["upgrading"] = fake
+	defer delete(ProvisionerMap, "upgrading")
+	box := &provision.Box{Id: "COM01", CartonName: "bigbang", Provider: "upgrading", State: utils.StateRunning,
+		Commit: "a1b2c3", Repo: &repository.Repo{Type: repository.GIT}}
+	u := NewUpgradeable(box)
+	u.Commit = "d4e5f6"
+	u.ShouldRestart = false
+	c.Assert(u.Upgrade(context.Background()), check.IsNil)
+	c.Assert(fake.commits, check.DeepEquals, []string{"d4e5f6"})
+	c.Assert(box.Commit, check.Equals, "a1b2c3")
+}
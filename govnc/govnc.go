@@ -1,24 +1,251 @@
 package govnc
 
 import (
-	//"net"
-	//"time"
-	//"log"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
 	log "github.com/Sirupsen/logrus"
 	vnc "github.com/kward/go-vnc"
-	//"golang.org/x/net/context"
 )
 
+const dialTimeout = 10 * time.Second
+
+// ErrIdle is returned by Proxy when the client sent no input for too long.
+var ErrIdle = errors.New("vnc session idle for too long")
+
 type VncListener struct {
 	B <-chan vnc.ServerMessage
 }
 
+// VncHost is the vnc server of a vm, on the host the vm runs in.
 type VncHost struct {
 	IpAddress string
 	Port      string
 	Password  string
 }
 
-func Connect(vh *VncHost) {
-	log.Debugf("%v", vh)
+func (vh *VncHost) Addr() string {
+	return net.JoinHostPort(vh.IpAddress, vh.Port)
+}
+
+// Connect dials the vnc server.
+func Connect(vh *VncHost) (net.Conn, error) {
+	if vh.IpAddress == "" || vh.Port == "" {
+		return nil, fmt.Errorf("no vnc server at %q", vh.Addr())
+	}
+	log.Debugf("  connecting to vnc server %s", vh.Addr())
+	return net.DialTimeout("tcp", vh.Addr(), dialTimeout)
+}
+
+// Proxy copies the rfb traffic between the client and the server both ways
+// until either of them closes, or the client sends no key, pointer or cut
+// text event for idle. The screen updates of the server, and the update
+// requests noVNC keeps sending, don't keep the session alive. Both are closed
+// once it returns.
+func Proxy(client io.ReadWriteCloser, server io.ReadWriteCloser, idle time.Duration) error {
+	p := &proxy{client: client, server: server}
+	p.touch()
+	errs := make(chan error, 2)
+	go func() { errs <- p.copy(server, client, &inputs{}) }()
+	go func() { errs <- p.copy(client, server, nil) }()
+
+	tick := idle / 4
+	if tick < time.Second {
+		tick = time.Second
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-errs:
+			p.close()
+			if err == io.EOF {
+				err = nil
+			}
+			return err
+		case <-ticker.C:
+			if p.idleFor() >= idle {
+				p.close()
+				return ErrIdle
+			}
+		}
+	}
+}
+
+type proxy struct {
+	client, server io.ReadWriteCloser
+	last           int64
+	once           sync.Once
+}
+
+func (p *proxy) touch() {
+	atomic.StoreInt64(&p.last, time.Now().UnixNano())
+}
+
+func (p *proxy) idleFor() time.Duration {
+	return time.Since(time.Unix(0, atomic.LoadInt64(&p.last)))
+}
+
+// copy copies src to dst, the input events of in reset the idle timer.
+func (p *proxy) copy(dst io.Writer, src io.Reader, in *inputs) error {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			if in != nil && in.feed(buf[:n]) {
+				p.touch()
+			}
+			if _, werr := dst.Write(buf[:n]); werr != nil {
+				return werr
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (p *proxy) close() {
+	p.once.Do(func() {
+		p.client.Close()
+		p.server.Close()
+	})
+}
+
+// the steps of the rfb stream of a client.
+const (
+	rfbVersion = iota
+	rfbSecurity
+	rfbChallenge
+	rfbClientInit
+	rfbMessages
+	// rfbOpaque is a stream inputs can't follow (rfb 3.3, whose security
+	// type is chosen by the server, or a security type other than none and
+	// vnc auth), all of it counts as input.
+	rfbOpaque
+)
+
+// the client to server messages of rfb 3.8.
+const (
+	setPixelFormat           = 0
+	setEncodings             = 2
+	framebufferUpdateRequest = 3
+	keyEvent                 = 4
+	pointerEvent             = 5
+	clientCutText            = 6
+)
+
+// inputs follows the rfb messages a client sends, to tell its input events
+// from the rest.
+type inputs struct {
+	step int
+	buf  []byte
+}
+
+// feed takes the next bytes the client sent, and tells if they complete an
+// input event.
+func (in *inputs) feed(p []byte) bool {
+	if in.step == rfbOpaque {
+		return true
+	}
+	in.buf = append(in.buf, p...)
+	input := false
+	for {
+		n, event := in.next()
+		if n == 0 {
+			break
+		}
+		in.buf = in.buf[n:]
+		input = input || event
+		if in.step == rfbOpaque {
+			in.buf = nil
+			return true
+		}
+	}
+	if len(in.buf) == 0 {
+		in.buf = nil
+	}
+	return input
+}
+
+// next consumes the next whole step or message of the buffer, and returns
+// its length (0 when it isn't all there yet) and if it is an input event.
+func (in *inputs) next() (int, bool) {
+	b := in.buf
+	switch in.step {
+	case rfbVersion:
+		if len(b) < 12 {
+			return 0, false
+		}
+		minor, err := strconv.Atoi(string(b[8:11]))
+		if string(b[:8]) != "RFB 003." || err != nil || minor < 7 {
+			in.step = rfbOpaque
+		} else {
+			in.step = rfbSecurity
+		}
+		return 12, false
+	case rfbSecurity:
+		if len(b) < 1 {
+			return 0, false
+		}
+		switch b[0] {
+		case 1:
+			in.step = rfbClientInit
+		case 2:
+			in.step = rfbChallenge
+		default:
+			in.step = rfbOpaque
+		}
+		return 1, false
+	case rfbChallenge:
+		if len(b) < 16 {
+			return 0, false
+		}
+		in.step = rfbClientInit
+		return 16, false
+	case rfbClientInit:
+		if len(b) < 1 {
+			return 0, false
+		}
+		in.step = rfbMessages
+		return 1, false
+	}
+	if len(b) < 1 {
+		return 0, false
+	}
+	n := 0
+	switch b[0] {
+	case setPixelFormat:
+		n = 20
+	case setEncodings:
+		if len(b) < 4 {
+			return 0, false
+		}
+		n = 4 + 4*int(binary.BigEndian.Uint16(b[2:4]))
+	case framebufferUpdateRequest:
+		n = 10
+	case keyEvent:
+		n = 8
+	case pointerEvent:
+		n = 6
+	case clientCutText:
+		if len(b) < 8 {
+			return 0, false
+		}
+		n = 8 + int(binary.BigEndian.Uint32(b[4:8]))
+	default:
+		in.step = rfbOpaque
+		return len(b), true
+	}
+	if len(b) < n {
+		return 0, false
+	}
+	return n, b[0] == keyEvent || b[0] == pointerEvent || b[0] == clientCutText
 }
@@ -0,0 +1,134 @@
+package govnc
+
+import (
+	"image"
+	"image/color"
+	"net"
+	"testing"
+	"time"
+
+	vnc "github.com/kward/go-vnc"
+)
+
+func TestProxyCopiesBothWays(t *testing.T) {
+	client, clientEnd := net.Pipe()
+	server, serverEnd := net.Pipe()
+	done := make(chan error, 1)
+	go func() { done <- Proxy(clientEnd, serverEnd, time.Minute) }()
+
+	go server.Write([]byte("RFB 003.008\n"))
+	buf := make([]byte, 12)
+	if _, err := client.Read(buf); err != nil || string(buf) != "RFB 003.008\n" {
+		t.Fatalf("client got %q, %v", buf, err)
+	}
+	go client.Write([]byte("RFB 003.008\n"))
+	if _, err := server.Read(buf); err != nil || string(buf) != "RFB 003.008\n" {
+		t.Fatalf("server got %q, %v", buf, err)
+	}
+	client.Close()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("proxy ended with %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("proxy didn't end once the client closed")
+	}
+}
+
+func TestProxyIdle(t *testing.T) {
+	_, clientEnd := net.Pipe()
+	_, serverEnd := net.Pipe()
+	start := time.Now()
+	if err := Proxy(clientEnd, serverEnd, time.Second); err != ErrIdle {
+		t.Fatalf("expected %v, got %v", ErrIdle, err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatal("idle proxy took too long to end")
+	}
+}
+
+func TestInputsTellEventsFromUpdateRequests(t *testing.T) {
+	in := &inputs{}
+	handshake := append([]byte("RFB 003.008\n\x02"), make([]byte, 16)...)
+	if in.feed(append(handshake, 1)) {
+		t.Fatal("the handshake isn't an input")
+	}
+	update := []byte{framebufferUpdateRequest, 1, 0, 0, 0, 0, 0, 4, 0, 3}
+	if in.feed(update) || in.feed([]byte{setEncodings, 0, 0, 1, 0, 0, 0, 7}) {
+		t.Fatal("update requests and encodings aren't inputs")
+	}
+	key := []byte{keyEvent, 1, 0, 0, 0, 0, 0xff, 0x0d}
+	if in.feed(key[:5]) {
+		t.Fatal("half a key event isn't an input")
+	}
+	if !in.feed(append(key[5:], update...)) {
+		t.Fatal("expected the key event to be an input")
+	}
+	if !in.feed([]byte{pointerEvent, 0, 0, 10, 0, 20}) {
+		t.Fatal("expected the pointer event to be an input")
+	}
+}
+
+func TestProxyIdleDespiteScreenUpdates(t *testing.T) {
+	client, clientEnd := net.Pipe()
+	server, serverEnd := net.Pipe()
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			if _, err := server.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			if _, err := client.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+	go func() {
+		client.Write([]byte("RFB 003.008\n\x01\x01"))
+		for {
+			if _, err := client.Write([]byte{framebufferUpdateRequest, 1, 0, 0, 0, 0, 0, 4, 0, 3}); err != nil {
+				return
+			}
+			if _, err := server.Write([]byte{0, 0, 0, 0}); err != nil {
+				return
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}()
+	if err := Proxy(clientEnd, serverEnd, time.Second); err != ErrIdle {
+		t.Fatalf("expected %v, got %v", ErrIdle, err)
+	}
+}
+
+func TestConnectNeedsAServer(t *testing.T) {
+	if _, err := Connect(&VncHost{}); err == nil {
+		t.Fatal("expected an error without a vnc host")
+	}
+}
+
+func TestPaintRawRects(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	red, blue := vnc.Color{R: 0xff}, vnc.Color{B: 0xff}
+	paint(img, []vnc.Rectangle{
+		{X: 1, Y: 1, Width: 2, Height: 2, Enc: &vnc.RawEncoding{Colors: []vnc.Color{red, blue, blue, red}}},
+	})
+	want := map[image.Point]color.RGBA{
+		{0, 0}: {},
+		{1, 1}: {R: 0xff, A: 0xff},
+		{2, 1}: {B: 0xff, A: 0xff},
+		{1, 2}: {B: 0xff, A: 0xff},
+		{2, 2}: {R: 0xff, A: 0xff},
+		{3, 3}: {},
+	}
+	for p, c := range want {
+		if got := img.RGBAAt(p.X, p.Y); got != c {
+			t.Errorf("pixel %v is %v, want %v", p, got, c)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package govnc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+
+	log "github.com/Sirupsen/logrus"
+	vnc "github.com/kward/go-vnc"
+	"github.com/kward/go-vnc/messages"
+	"github.com/kward/go-vnc/rfbflags"
+)
+
+// Screenshot connects to the vnc server as a client, asks for one update of
+// the whole framebuffer and returns it as an image.
+func Screenshot(ctx context.Context, vh *VncHost) (*image.RGBA, error) {
+	nc, err := Connect(vh)
+	if err != nil {
+		return nil, err
+	}
+	if d, ok := ctx.Deadline(); ok {
+		nc.SetDeadline(d)
+	}
+	cfg := vnc.NewClientConfig(vh.Password)
+	vc, err := vnc.Connect(ctx, nc, cfg)
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("vnc handshake with %s failed: %s", vh.Addr(), err)
+	}
+	defer vc.Close()
+	go vc.ListenAndHandle()
+
+	w, h := vc.FramebufferWidth(), vc.FramebufferHeight()
+	log.Debugf("  screenshot of %s %q %dx%d", vh.Addr(), vc.DesktopName(), w, h)
+	if err = vc.FramebufferUpdateRequest(rfbflags.RFBFalse, 0, 0, w, h); err != nil {
+		return nil, err
+	}
+	img := image.NewRGBA(image.Rect(0, 0, int(w), int(h)))
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case msg, ok := <-cfg.ServerMessageCh:
+			if !ok {
+				return nil, fmt.Errorf("vnc server %s closed the connection", vh.Addr())
+			}
+			if msg.Type() != messages.FramebufferUpdate {
+				continue
+			}
+			paint(img, msg.(*vnc.FramebufferUpdate).Rects)
+			return img, nil
+		}
+	}
+}
+
+// ScreenshotPNG is Screenshot encoded as png.
+func ScreenshotPNG(ctx context.Context, vh *VncHost) ([]byte, error) {
+	img, err := Screenshot(ctx, vh)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err = png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// paint draws the raw encoded rectangles of an update on img. The client
+// uses the default pixel format of go-vnc, 8 bits a color.
+func paint(img *image.RGBA, rects []vnc.Rectangle) {
+	for _, r := range rects {
+		raw, ok := r.Enc.(*vnc.RawEncoding)
+		if !ok {
+			continue
+		}
+		for i, c := range raw.Colors {
+			x, y := int(r.X)+i%int(r.Width), int(r.Y)+i/int(r.Width)
+			img.SetRGBA(x, y, color.RGBA{R: uint8(c.R), G: uint8(c.G), B: uint8(c.B), A: 0xff})
+		}
+	}
+}
@@ -145,6 +145,18 @@ type Box struct {
 	Commit       string
 	Envs         []bind.EnvVar
 	Address      *url.URL
+	OperationId  string
+	// Transactional boxes are rolled back by the carton when a deploy fails.
+	Transactional bool
+	// Replacing boxes are destroyed to be deployed again right away, and keep
+	// holding their quota meanwhile.
+	Replacing bool
+	// Muted boxes don't notify their failures and destroys, the carton working
+	// on them notifies once for all of them.
+	Muted bool
+	// Unmounting are the disks being detached from the box, the containers
+	// started for it meanwhile don't mount them.
+	Unmounting []string
 }
 
 type PolicyOps struct {
@@ -205,13 +217,14 @@ func (b *Box) GetPublicIp() string {
 	return b.PublicIp
 }
 
+// CanCycleStop tells if the box is up, and can be stopped or restarted.
 func (b *Box) CanCycleStop() bool {
-	return b.State == constants.StateRunning ||
-		b.State == constants.StatePostError
+	return b.Can(CYCLE_RESTART) == nil
 }
 
+// CanCycleStart tells if the box is down, and can be started.
 func (b *Box) CanCycleStart() bool {
-	return b.State == constants.StateStopped
+	return b.Can(CYCLE_START) == nil
 }
 
 // Available returns true if the unit is available. It will return true
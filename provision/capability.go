@@ -0,0 +1,104 @@
+/*
+** Copyright [2013-2017] [Megam Systems]
+**
+** Licensed under the Apache License, Version 2.0 (the "License");
+** you may not use this file except in compliance with the License.
+** You may obtain a copy of the License at
+**
+** http://www.apache.org/licenses/LICENSE-2.0
+**
+** Unless required by applicable law or agreed to in writing, software
+** distributed under the License is distributed on an "AS IS" BASIS,
+** WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+** See the License for the specific language governing permissions and
+** limitations under the License.
+ */
+package provision
+
+import (
+	"fmt"
+)
+
+// Capability is something a provisioner may or may not be able to do to a
+// box. Every provisioner has the methods of Provisioner, but some of them are
+// stubs that do nothing.
+type Capability string
+
+const (
+	CAP_SNAPSHOTS      Capability = "snapshots"
+	CAP_BACKUPS        Capability = "backups"
+	CAP_DISKS          Capability = "disks"
+	CAP_SHELL          Capability = "shell"
+	CAP_EXEC           Capability = "exec"
+	CAP_SUSPEND        Capability = "suspend"
+	CAP_NETWORK_POLICY Capability = "network policy"
+	CAP_RESIZE         Capability = "resize"
+)
+
+// Capable is a provisioner that declares what it can do. A provisioner that
+// doesn't declare anything is trusted to do everything.
+type Capable interface {
+	Capabilities() []Capability
+}
+
+// opCapabilities are the capabilities the ops need, an op not here needs none.
+var opCapabilities = map[BoxOp]Capability{
+	CYCLE_SNAP_CREATE:   CAP_SNAPSHOTS,
+	CYCLE_SNAP_RESTORE:  CAP_SNAPSHOTS,
+	CYCLE_SNAP_DELETE:   CAP_SNAPSHOTS,
+	CYCLE_BACKUP_CREATE: CAP_BACKUPS,
+	CYCLE_BACKUP_DELETE: CAP_BACKUPS,
+	CYCLE_DISK_ATTACH:   CAP_DISKS,
+	CYCLE_DISK_DETACH:   CAP_DISKS,
+	CYCLE_SUSPEND:       CAP_SUSPEND,
+	CYCLE_NETWORK:       CAP_NETWORK_POLICY,
+	CYCLE_RESIZE:        CAP_RESIZE,
+}
+
+// CapabilityError is returned when a provisioner is asked to do what it
+// can't.
+type CapabilityError struct {
+	Provisioner string
+	Capability  Capability
+}
+
+func (e *CapabilityError) Error() string {
+	return fmt.Sprintf("provisioner %s doesn't support %s: %s", e.Provisioner, e.Capability, ErrNotImplemented)
+}
+
+// IsCapabilityError tells if err is an unsupported capability.
+func IsCapabilityError(err error) bool {
+	_, ok := err.(*CapabilityError)
+	return ok
+}
+
+// Supports tells if p can do c.
+func Supports(p Provisioner, c Capability) bool {
+	capable, ok := p.(Capable)
+	if !ok {
+		return true
+	}
+	for _, cp := range capable.Capabilities() {
+		if cp == c {
+			return true
+		}
+	}
+	return false
+}
+
+// Need returns a CapabilityError when the provisioner name can't do c.
+func Need(name string, p Provisioner, c Capability) error {
+	if !Supports(p, c) {
+		return &CapabilityError{Provisioner: name, Capability: c}
+	}
+	return nil
+}
+
+// NeedFor returns a CapabilityError when the provisioner name can't do what
+// op needs.
+func NeedFor(name string, p Provisioner, op BoxOp) error {
+	if c, ok := opCapabilities[op]; ok {
+		return Need(name, p, c)
+	}
+	return nil
+}
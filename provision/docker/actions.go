@@ -1,6 +1,7 @@
 package docker
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -19,7 +20,9 @@ import (
 )
 
 type runContainerActionsArgs struct {
+	ctx              context.Context
 	box              *provision.Box
+	unit             string
 	imageId          string
 	containerStatus  utils.Status
 	containerState   utils.State
@@ -36,6 +39,7 @@ type containersToAdd struct {
 }
 
 type changeUnitsPipelineArgs struct {
+	ctx         context.Context
 	box         *provision.Box
 	writer      io.Writer
 	toAdd       map[string]*containersToAdd
@@ -21,6 +21,7 @@ const (
 	DOCKER_SWAPSIZE  = "swap"
 	DOCKER_CPUPERIOD = "cpuperiod"
 	DOCKER_CPUQUOTA  = "cpuquota"
+	DOCKER_SCHEDULER = "scheduler"
 
 	BRIDGE_NAME    = "name"
 	BRIDGE_NETWORK = "network"
@@ -91,13 +92,18 @@ type Storage interface {
 // provide methods for interaction with those nodes, like CreateContainer,
 // which creates a container in one node of the cluster.
 type Cluster struct {
-	Healer         Healer
+	Healer Healer
+	// Scheduler orders the nodes of a region a container is created in.
+	// When nil, the scheduler metadata of the nodes names it.
+	Scheduler      Scheduler
 	stor           Storage
 	bridges        Bridges
 	gulp           Gulp
 	VNets          map[string]string
 	monitoringDone chan bool
-	Region         string
+	// Region is the region the nodes are picked from. It is set on a view of
+	// the cluster got with InRegion, never on the shared cluster.
+	Region string
 }
 
 type DockerNodeError struct {
@@ -152,14 +158,59 @@ func New(storage Storage, nodes ...Node) (*Cluster, error) {
 	if len(nodes) > 0 {
 		for _, n := range nodes {
 			err = c.Register(n)
+			if err == ErrDuplicatedNodeAddress {
+				err = c.reconfigure(n)
+			}
 			if err != nil {
 				return &c, err
 			}
 		}
+		err = c.prune(nodes)
 	}
 	return &c, err
 }
 
+// InRegion returns a view of the cluster that works on the nodes of region.
+// Views share the nodes and the storage of c, so requests for boxes in
+// different regions can run at the same time, each with its own view.
+func (c *Cluster) InRegion(region string) *Cluster {
+	v := *c
+	v.Region = region
+	return &v
+}
+
+// reconfigure updates a node a FileStorage kept from the last run to its
+// config, keeping how healthy the node was.
+func (c *Cluster) reconfigure(node Node) error {
+	stored, err := c.storage().RetrieveNode(node.Address)
+	if err != nil {
+		return err
+	}
+	node.keepHealth(stored)
+	return c.storage().UpdateNode(node)
+}
+
+// prune removes the nodes a FileStorage kept from the last run that aren't
+// in the config anymore.
+func (c *Cluster) prune(nodes []Node) error {
+	keep := make(map[string]bool, len(nodes))
+	for _, n := range nodes {
+		keep[n.Address] = true
+	}
+	stored, err := c.storage().RetrieveNodes()
+	if err != nil {
+		return err
+	}
+	for _, n := range stored {
+		if !keep[n.Address] {
+			if err = c.storage().RemoveNode(n.Address); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // Register adds new nodes to the cluster.
 func (c *Cluster) Register(node Node) error {
 	if node.Address == "" {
@@ -12,6 +12,7 @@ import (
 	"github.com/megamsys/vertice/metrix"
 	"net"
 	"net/url"
+	"strings"
 	"sync"
 	//	"time"
 )
@@ -21,61 +22,111 @@ type Container struct {
 	Host string
 }
 
-// CreateContainer creates a container in the specified node. If no node is
-// specified, it will create the container in a node selected by the scheduler.
+// CreateContainer creates a container in a node of the region of the cluster
+// selected by the scheduler.
 //
-// It returns the container, or an error, in case of failures.
+// It returns the address of the node and the container, or an error, in case
+// of failures.
 func (c *Cluster) CreateContainer(opts docker.CreateContainerOptions) (string, *docker.Container, error) {
 	return c.CreateContainerSchedulerOpts(opts)
 }
 
-// Similar to CreateContainer but allows arbritary options to be passed to
-// the scheduler.
+// Similar to CreateContainer but the env of the container can constrain the
+// nodes it is created in (constraint:key==value or constraint:key!=value on
+// the metadata of the nodes). The nodes are tried in the order the scheduler
+// puts them in, until one creates the container.
 func (c *Cluster) CreateContainerSchedulerOpts(opts docker.CreateContainerOptions) (string, *docker.Container, error) {
-	var (
-		addr      string
-		container *docker.Container
-		err       error
-	)
-
-	maxTries := 5
-	for ; maxTries > 0; maxTries-- {
-		nodes, err := c.Nodes()
-		for _, v := range nodes {
-			if v.Metadata[DOCKER_ZONE] == c.Region {
-				addr = v.Address
+	nodes, err := c.regionNodes()
+	if err != nil {
+		return "", nil, err
+	}
+	if opts.Config != nil {
+		cts, env, err := constraintsOf(opts.Config.Env)
+		if err != nil {
+			return "", nil, err
+		}
+		if len(cts) > 0 {
+			if nodes = matchConstraints(nodes, cts); len(nodes) == 0 {
+				return "", nil, fmt.Errorf("CreateContainer: no node in region %s meets %v", c.Region, cts)
 			}
+			config := *opts.Config
+			config.Env = env
+			opts.Config = &config
+		}
+	}
+	candidates := nodes
+	if len(nodes) > 1 {
+		scheduler, err := c.scheduler(nodes)
+		if err != nil {
+			return "", nil, err
 		}
-		if addr == "" {
-			return addr, nil, errors.New("CreateContainer needs a non empty node addr")
+		if candidates, err = scheduler.Schedule(c, opts, nodes); err != nil {
+			return "", nil, err
 		}
+	}
+	if len(candidates) == 0 {
+		return "", nil, fmt.Errorf("CreateContainer: no node available in region %s", c.Region)
+	}
+	if len(candidates) > maxTries {
+		candidates = candidates[:maxTries]
+	}
+	var addr string
+	for _, n := range candidates {
+		addr = n.Address
+		var container *docker.Container
 		container, err = c.createContainerInNode(opts, addr)
 		if err == nil {
 			c.handleNodeSuccess(addr)
-			break
-		} else {
-			log.Errorf("Error trying to create container in node %q: %s. Trying again in another node...", addr, err.Error())
-			shouldIncrementFailures := false
-			if nodeErr, ok := err.(DockerNodeError); ok {
-				baseErr := nodeErr.BaseError()
-				if urlErr, ok := baseErr.(*url.Error); ok {
-					baseErr = urlErr.Err
-				}
-				_, isNetErr := baseErr.(*net.OpError)
-				if isNetErr || baseErr == docker.ErrConnectionRefused || nodeErr.cmd == "createContainer" {
-					shouldIncrementFailures = true
-				}
+			if err = c.storage().StoreContainer(container.ID, addr); err != nil {
+				return addr, container, err
 			}
-			c.handleNodeError(addr, err, shouldIncrementFailures)
+			return addr, container, c.storage().StoreContainerByName(container.ID, container.Name)
+		}
+		// the name is taken in the node, another node would create a second
+		// container of that name.
+		if nodeErr, ok := err.(DockerNodeError); ok && nodeErr.BaseError() == docker.ErrContainerAlreadyExists {
 			return addr, nil, err
 		}
+		log.Errorf("Error trying to create container in node %q: %s. Trying again in another node...", addr, err.Error())
+		c.handleNodeError(addr, err, shouldIncrementFailures(err))
+	}
+	return addr, nil, fmt.Errorf("CreateContainer: no node could create the container, last error: %s", err.Error())
+}
+
+// maxTries is the number of nodes a container is tried in.
+const maxTries = 5
+
+// shouldIncrementFailures tells if the node itself failed, rather than the
+// request.
+func shouldIncrementFailures(err error) bool {
+	nodeErr, ok := err.(DockerNodeError)
+	if !ok {
+		return false
 	}
+	baseErr := nodeErr.BaseError()
+	if urlErr, ok := baseErr.(*url.Error); ok {
+		baseErr = urlErr.Err
+	}
+	_, isNetErr := baseErr.(*net.OpError)
+	return isNetErr || baseErr == docker.ErrConnectionRefused || nodeErr.cmd == "createContainer"
+}
+
+// regionNodes are the nodes of the region of the cluster.
+func (c *Cluster) regionNodes() ([]Node, error) {
+	nodes, err := c.Nodes()
 	if err != nil {
-		return addr, nil, fmt.Errorf("CreateContainer: maximum number of tries exceeded, last error: %s", err.Error())
+		return nil, err
+	}
+	var region []Node
+	for _, n := range nodes {
+		if n.Metadata[DOCKER_ZONE] == c.Region {
+			region = append(region, n)
+		}
 	}
-	err = c.storage().StoreContainer(container.ID, addr)
-	err = c.storage().StoreContainerByName(container.ID, container.Name)
-	return addr, container, err
+	if len(region) == 0 {
+		return nil, fmt.Errorf("CreateContainer: no node available in region %s", c.Region)
+	}
+	return region, nil
 }
 
 func (c *Cluster) createContainerInNode(opts docker.CreateContainerOptions, nodeAddress string) (*docker.Container, error) {
@@ -117,10 +168,9 @@ func (c *Cluster) KillContainer(opts docker.KillContainerOptions) error {
 	return wrapError(node, node.KillContainer(opts))
 }
 
-// ListContainers returns a slice of all containers in the cluster matching the
-// given criteria.
+// ListContainers returns a slice of all containers in the nodes of the region
+// of the cluster matching the given criteria.
 func (c *Cluster) ListContainers(opts docker.ListContainersOptions) ([]docker.APIContainers, error) {
-	var addr string
 	nodes, err := c.Nodes()
 	if err != nil {
 		return nil, err
@@ -129,11 +179,14 @@ func (c *Cluster) ListContainers(opts docker.ListContainersOptions) ([]docker.AP
 	result := make(chan []docker.APIContainers, len(nodes))
 	errs := make(chan error, len(nodes))
 	for _, n := range nodes {
-		if n.Metadata[DOCKER_ZONE] == c.Region {
-			addr = n.Address
+		if n.Metadata[DOCKER_ZONE] != c.Region {
+			continue
+		}
+		client, err := c.getNodeByAddr(n.Address)
+		if err != nil {
+			return nil, err
 		}
 		wg.Add(1)
-		client, _ := c.getNodeByAddr(addr)
 		go func(n node) {
 			defer wg.Done()
 			if containers, err := n.ListContainers(opts); err != nil {
@@ -156,6 +209,45 @@ func (c *Cluster) ListContainers(opts docker.ListContainersOptions) ([]docker.AP
 	}
 }
 
+// RebuildContainers stores the node and the name of the containers of every
+// node in the cluster, for a storage that starts empty while the nodes
+// already have containers. A node that can't list them is skipped, the
+// error is returned after the others are stored.
+func (c *Cluster) RebuildContainers() (int, error) {
+	nodes, err := c.Nodes()
+	if err != nil {
+		return 0, err
+	}
+	var (
+		stored  int
+		lastErr error
+	)
+	for _, n := range nodes {
+		client, err := c.getNodeByAddr(n.Address)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		containers, err := client.ListContainers(docker.ListContainersOptions{All: true})
+		if err != nil {
+			lastErr = wrapError(client, err)
+			continue
+		}
+		for _, a := range containers {
+			if err = c.storage().StoreContainer(a.ID, n.Address); err != nil {
+				return stored, err
+			}
+			if len(a.Names) > 0 {
+				if err = c.storage().StoreContainerByName(a.ID, strings.TrimPrefix(a.Names[0], "/")); err != nil {
+					return stored, err
+				}
+			}
+			stored++
+		}
+	}
+	return stored, lastErr
+}
+
 // RemoveContainer removes a container from the cluster.
 func (c *Cluster) RemoveContainer(opts docker.RemoveContainerOptions) error {
 	return c.removeFromStorage(opts)
@@ -233,6 +325,24 @@ func (c *Cluster) UnpauseContainer(id string) error {
 	return wrapError(node, node.UnpauseContainer(id))
 }
 
+// RenameContainer gives the container a new name.
+func (c *Cluster) RenameContainer(opts docker.RenameContainerOptions) error {
+	node, err := c.getNodeForContainer(opts.ID)
+	if err != nil {
+		return err
+	}
+	return wrapError(node, node.RenameContainer(opts))
+}
+
+// UpdateContainer changes the resources of a running container.
+func (c *Cluster) UpdateContainer(id string, opts docker.UpdateContainerOptions) error {
+	node, err := c.getNodeForContainer(id)
+	if err != nil {
+		return err
+	}
+	return wrapError(node, node.UpdateContainer(id, opts))
+}
+
 // WaitContainer blocks until the given container stops, returning the exit
 // code of the container command.
 func (c *Cluster) WaitContainer(id string) (int, error) {
@@ -467,6 +577,19 @@ func (c *Cluster) GulpPort() string {
 	return gulpPort
 }
 
+// Registry is the docker registry of the region of the cluster, images the
+// region keeps are pushed to it.
+func (c *Cluster) Registry() string {
+	var registry string
+	nodes, _ := c.Nodes()
+	for _, v := range nodes {
+		if v.Metadata[DOCKER_ZONE] == c.Region {
+			registry = v.Metadata[DOCKER_REGISTRY]
+		}
+	}
+	return registry
+}
+
 // Showback returns the metrics of the swarm containers stats
 
 func (c *Cluster) Showback(start int64, end int64, point string) ([]interface{}, error) {
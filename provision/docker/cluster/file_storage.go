@@ -0,0 +1,168 @@
+package cluster
+
+import (
+	"encoding/gob"
+	"sync"
+	"time"
+
+	"github.com/megamsys/vertice/provision"
+)
+
+// FileStorage is a MapStorage kept in a file, so that vertice remembers the
+// node of the containers, where the images are and the health of the nodes
+// after a restart. Every change is written to the file.
+type FileStorage struct {
+	MapStorage
+	path     string
+	migrated bool
+	fMut     sync.Mutex
+}
+
+type fileState struct {
+	Containers map[string]string
+	Images     map[string]*Image
+	Nodes      []Node
+	IPIndex    map[string]*IPIndex
+	// Migrated is set once the containers the nodes had before the file
+	// existed are all stored.
+	Migrated bool
+}
+
+// NewFileStorage loads the storage kept in path, an empty one when there is
+// no such file yet.
+func NewFileStorage(path string) (*FileStorage, error) {
+	s := &FileStorage{path: path}
+	var st fileState
+	ok, err := provision.LoadGob(path, &st)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return s, nil
+	}
+	s.cMap, s.iMap, s.nodes, s.ipindex = st.Containers, st.Images, st.Nodes, st.IPIndex
+	s.migrated = st.Migrated
+	s.updateNodeMap()
+	return s, nil
+}
+
+// Migrated tells if the containers the nodes had before the file existed are
+// all stored in it.
+func (s *FileStorage) Migrated() bool {
+	s.fMut.Lock()
+	defer s.fMut.Unlock()
+	return s.migrated
+}
+
+// SetMigrated marks the containers of the nodes as all stored, for the next
+// runs not to look for them again.
+func (s *FileStorage) SetMigrated() error {
+	s.fMut.Lock()
+	s.migrated = true
+	s.fMut.Unlock()
+	return s.save()
+}
+
+// save writes the whole storage to its file.
+func (s *FileStorage) save() error {
+	s.fMut.Lock()
+	defer s.fMut.Unlock()
+	return provision.SaveGob(s.path, s.encode)
+}
+
+// encode writes the storage to enc, with fMut held by save.
+func (s *FileStorage) encode(enc *gob.Encoder) error {
+	s.cMut.Lock()
+	defer s.cMut.Unlock()
+	s.iMut.Lock()
+	defer s.iMut.Unlock()
+	s.nMut.Lock()
+	defer s.nMut.Unlock()
+	s.ipMut.Lock()
+	defer s.ipMut.Unlock()
+	return enc.Encode(fileState{
+		Containers: s.cMap,
+		Images:     s.iMap,
+		Nodes:      s.nodes,
+		IPIndex:    s.ipindex,
+		Migrated:   s.migrated,
+	})
+}
+
+func (s *FileStorage) StoreContainerByName(containerID, name string) error {
+	if err := s.MapStorage.StoreContainerByName(containerID, name); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+func (s *FileStorage) StoreContainer(containerID, hostID string) error {
+	if err := s.MapStorage.StoreContainer(containerID, hostID); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+func (s *FileStorage) RemoveContainer(containerID string) error {
+	if err := s.MapStorage.RemoveContainer(containerID); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+func (s *FileStorage) StoreNode(node Node) error {
+	if err := s.MapStorage.StoreNode(node); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+func (s *FileStorage) UpdateNode(node Node) error {
+	if err := s.MapStorage.UpdateNode(node); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+func (s *FileStorage) RemoveNode(addr string) error {
+	if err := s.MapStorage.RemoveNode(addr); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+func (s *FileStorage) LockNodeForHealing(address string, isFailure bool, timeout time.Duration) (bool, error) {
+	locked, err := s.MapStorage.LockNodeForHealing(address, isFailure, timeout)
+	if err != nil || !locked {
+		return locked, err
+	}
+	return true, s.save()
+}
+
+func (s *FileStorage) ExtendNodeLock(address string, timeout time.Duration) error {
+	if err := s.MapStorage.ExtendNodeLock(address, timeout); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+func (s *FileStorage) UnlockNode(address string) error {
+	if err := s.MapStorage.UnlockNode(address); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+func (s *FileStorage) StoreImage(repo, id, host string) error {
+	if err := s.MapStorage.StoreImage(repo, id, host); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+func (s *FileStorage) RemoveImage(repo, id, host string) error {
+	if err := s.MapStorage.RemoveImage(repo, id, host); err != nil {
+		return err
+	}
+	return s.save()
+}
@@ -0,0 +1,194 @@
+package cluster
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	dtesting "github.com/fsouza/go-dockerclient/testing"
+)
+
+func tempStoragePath(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "cluster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "clusters", "docker.gob"), func() { os.RemoveAll(dir) }
+}
+
+func TestFileStorageKeepsTheClusterAcrossRestarts(t *testing.T) {
+	path, cleanup := tempStoragePath(t)
+	defer cleanup()
+	s, err := NewFileStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Migrated() {
+		t.Error("a storage without a file should not be migrated")
+	}
+	if err = s.StoreNode(Node{Address: "http://localhost:2375", Metadata: map[string]string{DOCKER_ZONE: "chennai"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.StoreContainer("abc123", "http://localhost:2375"); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.StoreContainerByName("abc123", "tom"); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.StoreImage("megam/busybox", "img1", "http://localhost:2375"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.LockNodeForHealing("http://localhost:2375", true, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = NewFileStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Migrated() {
+		t.Error("a storage loaded from its file should not be migrated till it is marked so")
+	}
+	if err = s.SetMigrated(); err != nil {
+		t.Fatal(err)
+	}
+	s, err = NewFileStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Migrated() {
+		t.Error("the migration mark should be kept")
+	}
+	node, err := s.RetrieveNode("http://localhost:2375")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.Metadata[DOCKER_ZONE] != "chennai" || !node.Healing.IsFailure {
+		t.Errorf("node not kept, got %#v", node)
+	}
+	if host, err := s.RetrieveContainer("abc123"); err != nil || host != "http://localhost:2375" {
+		t.Errorf("container not kept, got %q, %v", host, err)
+	}
+	if id, err := s.RetrieveContainerByName("tom"); err != nil || id != "abc123" {
+		t.Errorf("container name not kept, got %q, %v", id, err)
+	}
+	if img, err := s.RetrieveImage("megam/busybox"); err != nil || img.LastId != "img1" {
+		t.Errorf("image not kept, got %#v, %v", img, err)
+	}
+}
+
+func TestNewKeepsTheHealthOfStoredNodes(t *testing.T) {
+	path, cleanup := tempStoragePath(t)
+	defer cleanup()
+	s, err := NewFileStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := "http://localhost:2375"
+	stored := Node{Address: addr, Metadata: map[string]string{
+		DOCKER_ZONE: "chennai",
+		"Failures":  "3",
+		"LastError": "connection refused",
+	}}
+	if _, err = New(s, stored); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = NewFileStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configured := Node{Address: addr, Metadata: map[string]string{DOCKER_ZONE: "sydney"}}
+	if _, err = New(s, configured); err != nil {
+		t.Fatal(err)
+	}
+	node, err := s.RetrieveNode(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.Metadata[DOCKER_ZONE] != "sydney" {
+		t.Errorf("node not reconfigured, zone is %q", node.Metadata[DOCKER_ZONE])
+	}
+	if node.FailureCount() != 3 || node.Metadata["LastError"] != "connection refused" {
+		t.Errorf("health of the node lost, got %#v", node.Metadata)
+	}
+}
+
+func TestNewPrunesNodesNotInTheConfig(t *testing.T) {
+	path, cleanup := tempStoragePath(t)
+	defer cleanup()
+	s, err := NewFileStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chennai := Node{Address: "http://chennai:2375", Metadata: map[string]string{DOCKER_ZONE: "chennai"}}
+	tokyo := Node{Address: "http://tokyo:2375", Metadata: map[string]string{DOCKER_ZONE: "tokyo"}}
+	if _, err = New(s, chennai, tokyo); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = NewFileStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := New(s, tokyo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes, err := c.UnfilteredNodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1 || nodes[0].Address != tokyo.Address {
+		t.Errorf("expected only %s to be kept, got %#v", tokyo.Address, nodes)
+	}
+}
+
+func TestRebuildContainers(t *testing.T) {
+	server, err := dtesting.NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := client.CreateContainer(docker.CreateContainerOptions{
+		Name:   "tom",
+		Config: &docker.Config{Image: "megam/busybox"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, cleanup := tempStoragePath(t)
+	defer cleanup()
+	s, err := NewFileStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := New(s, Node{Address: server.URL(), Metadata: map[string]string{DOCKER_ZONE: "chennai"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := c.RebuildContainers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("RebuildContainers stored %d containers, want 1", n)
+	}
+	s, err = NewFileStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host, err := s.RetrieveContainer(created.ID); err != nil || host != server.URL() {
+		t.Errorf("container not stored, got %q, %v", host, err)
+	}
+	if id, err := s.RetrieveContainerByName("tom"); err != nil || id != created.ID {
+		t.Errorf("container name not stored, got %q, %v", id, err)
+	}
+}
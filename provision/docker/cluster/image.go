@@ -3,6 +3,7 @@ package cluster
 import (
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"strings"
@@ -214,6 +215,56 @@ func (c *Cluster) ImportImage(opts docker.ImportImageOptions) error {
 	return err
 }
 
+// ExportImage writes the image, with its layers and config, as a tarball to
+// opts.OutputStream.
+func (c *Cluster) ExportImage(opts docker.ExportImageOptions) error {
+	img, err := c.storage().RetrieveImage(opts.Name)
+	if err != nil {
+		return err
+	}
+	node, err := c.getNodeByAddr(img.LastNode)
+	if err != nil {
+		return err
+	}
+	return wrapError(node, node.ExportImage(opts))
+}
+
+// LoadImage loads the tarball of the image name, as ExportImage writes it, in
+// every node of the region, or in one of them when all is false (to push it
+// from there). The tarball is opened by in for every node.
+func (c *Cluster) LoadImage(name string, in func() (io.ReadCloser, error), all bool) error {
+	nodes, err := c.regionNodes()
+	if err != nil {
+		return err
+	}
+	if !all {
+		nodes = nodes[:1]
+	}
+	for _, n := range nodes {
+		node, err := c.getNodeByAddr(n.Address)
+		if err != nil {
+			return err
+		}
+		r, err := in()
+		if err != nil {
+			return err
+		}
+		err = node.LoadImage(docker.LoadImageOptions{InputStream: r})
+		r.Close()
+		if err != nil {
+			return wrapError(node, err)
+		}
+		img, err := node.InspectImage(name)
+		if err != nil {
+			return wrapError(node, err)
+		}
+		if err = c.storage().StoreImage(name, img.ID, node.addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 //BuildImage build an image and pushes it to registry
 func (c *Cluster) BuildImage(buildOptions docker.BuildImageOptions) error {
 	nodes, err := c.Nodes()
@@ -0,0 +1,67 @@
+package cluster
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/fsouza/go-dockerclient"
+	dtesting "github.com/fsouza/go-dockerclient/testing"
+)
+
+func TestCreateContainerInRegionsInParallel(t *testing.T) {
+	c, err := New(&MapStorage{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	servers := map[string]*dtesting.DockerServer{}
+	for _, zone := range []string{"chennai", "tokyo"} {
+		server, err := dtesting.NewServer("127.0.0.1:0", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer server.Stop()
+		servers[zone] = server
+		if err = c.Register(Node{Address: server.URL(), Metadata: map[string]string{DOCKER_ZONE: zone}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	const deploys = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, deploys*len(servers))
+	for i := 0; i < deploys; i++ {
+		for zone := range servers {
+			wg.Add(1)
+			go func(zone string, i int) {
+				defer wg.Done()
+				opts := docker.CreateContainerOptions{
+					Name:   fmt.Sprintf("%s-%d", zone, i),
+					Config: &docker.Config{Image: "megam/busybox"},
+				}
+				addr, _, err := c.InRegion(zone).CreateContainer(opts)
+				if err != nil {
+					errs <- err
+				} else if addr != servers[zone].URL() {
+					errs <- fmt.Errorf("container %s created in %s, want %s", opts.Name, addr, servers[zone].URL())
+				}
+			}(zone, i)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if c.Region != "" {
+		t.Errorf("the region of the cluster changed to %q", c.Region)
+	}
+	for zone := range servers {
+		containers, err := c.InRegion(zone).ListContainers(docker.ListContainersOptions{All: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(containers) != deploys {
+			t.Errorf("%s runs %d containers, want %d", zone, len(containers), deploys)
+		}
+	}
+}
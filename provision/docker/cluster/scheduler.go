@@ -0,0 +1,221 @@
+package cluster
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/fsouza/go-dockerclient"
+	constants "github.com/megamsys/libgo/utils"
+)
+
+// the schedulers a region can pick with the scheduler metadata of its nodes.
+const (
+	SCHEDULER_MEMORY     = "memory"
+	SCHEDULER_CONTAINERS = "containers"
+	SCHEDULER_SPREAD     = "spread"
+)
+
+// CONSTRAINT_PREFIX starts the env of a container that constrains the nodes it
+// can be created in, like swarm does: constraint:disk==ssd or
+// constraint:disk!=hdd matches the metadata of the nodes. constraint:node==
+// matches the address of the node.
+const (
+	CONSTRAINT_PREFIX = "constraint:"
+	NODE_CONSTRAINT   = "node"
+)
+
+// Scheduler orders the nodes a container can be created in, the best first.
+// The cluster tries them in that order until one creates the container.
+type Scheduler interface {
+	Schedule(c *Cluster, opts docker.CreateContainerOptions, nodes []Node) ([]Node, error)
+}
+
+var (
+	schedulers = map[string]Scheduler{
+		SCHEDULER_MEMORY:     leastMemory{},
+		SCHEDULER_CONTAINERS: fewestContainers{},
+		SCHEDULER_SPREAD:     spreadByAssembly{},
+	}
+	sMut sync.RWMutex
+)
+
+// RegisterScheduler makes a scheduler available to the regions by name.
+func RegisterScheduler(name string, s Scheduler) {
+	sMut.Lock()
+	defer sMut.Unlock()
+	schedulers[name] = s
+}
+
+// scheduler is the Scheduler of the cluster when it has one, else the one the
+// nodes of the region name, spread by default.
+func (c *Cluster) scheduler(nodes []Node) (Scheduler, error) {
+	if c.Scheduler != nil {
+		return c.Scheduler, nil
+	}
+	name := SCHEDULER_SPREAD
+	for _, n := range nodes {
+		if s := n.Metadata[DOCKER_SCHEDULER]; s != "" {
+			name = s
+		}
+	}
+	sMut.RLock()
+	s, ok := schedulers[name]
+	sMut.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown scheduler %q in region %s", name, c.Region)
+	}
+	return s, nil
+}
+
+// leastMemory prefers the nodes with the least memory committed to their
+// containers.
+type leastMemory struct{}
+
+func (leastMemory) Schedule(c *Cluster, opts docker.CreateContainerOptions, nodes []Node) ([]Node, error) {
+	return rankNodes(nodes, c.committedMemory), nil
+}
+
+// fewestContainers prefers the nodes that have the fewest containers.
+type fewestContainers struct{}
+
+func (fewestContainers) Schedule(c *Cluster, opts docker.CreateContainerOptions, nodes []Node) ([]Node, error) {
+	return rankNodes(nodes, func(n Node) (int64, error) {
+		return c.countContainers(n, nil)
+	}), nil
+}
+
+// spreadByAssembly prefers the nodes that have the fewest units of the same
+// box, so that a node going down takes as few of them as it can. Among those,
+// the nodes with the fewest containers come first.
+type spreadByAssembly struct{}
+
+func (spreadByAssembly) Schedule(c *Cluster, opts docker.CreateContainerOptions, nodes []Node) ([]Node, error) {
+	ranked, _ := fewestContainers{}.Schedule(c, opts, nodes)
+	if opts.Config == nil || opts.Config.Labels[constants.ASSEMBLY_ID] == "" {
+		return ranked, nil
+	}
+	filter := map[string][]string{"label": {constants.ASSEMBLY_ID + "=" + opts.Config.Labels[constants.ASSEMBLY_ID]}}
+	return rankNodes(ranked, func(n Node) (int64, error) {
+		return c.countContainers(n, filter)
+	}), nil
+}
+
+// rankNodes sorts nodes by score, the lowest first, keeping the order of the
+// nodes that score the same. A node that can't be scored goes last, creating
+// the container in it may still work.
+func rankNodes(nodes []Node, score func(Node) (int64, error)) []Node {
+	scores := make(map[string]int64, len(nodes))
+	for _, n := range nodes {
+		s, err := score(n)
+		if err != nil {
+			s = math.MaxInt64
+		}
+		scores[n.Address] = s
+	}
+	ranked := make([]Node, len(nodes))
+	copy(ranked, nodes)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return scores[ranked[i].Address] < scores[ranked[j].Address]
+	})
+	return ranked
+}
+
+func (c *Cluster) countContainers(n Node, filters map[string][]string) (int64, error) {
+	client, err := c.getNodeByAddr(n.Address)
+	if err != nil {
+		return 0, err
+	}
+	containers, err := client.ListContainers(docker.ListContainersOptions{All: true, Filters: filters})
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(containers)), nil
+}
+
+// committedMemory is the memory the containers of the node can use. Stopped
+// containers count too, they get it back when started.
+func (c *Cluster) committedMemory(n Node) (int64, error) {
+	client, err := c.getNodeByAddr(n.Address)
+	if err != nil {
+		return 0, err
+	}
+	containers, err := client.ListContainers(docker.ListContainersOptions{All: true})
+	if err != nil {
+		return 0, err
+	}
+	var mem int64
+	for _, a := range containers {
+		cont, err := client.InspectContainer(a.ID)
+		if err != nil {
+			return 0, err
+		}
+		if cont.HostConfig != nil {
+			mem += cont.HostConfig.Memory
+		}
+	}
+	return mem, nil
+}
+
+type constraint struct {
+	key, value string
+	equal      bool
+}
+
+func (ct constraint) String() string {
+	if ct.equal {
+		return ct.key + "==" + ct.value
+	}
+	return ct.key + "!=" + ct.value
+}
+
+// constraintsOf splits the env of a container in the constraints on its node
+// and the rest.
+func constraintsOf(env []string) ([]constraint, []string, error) {
+	var (
+		cts  []constraint
+		rest []string
+	)
+	for _, e := range env {
+		if !strings.HasPrefix(e, CONSTRAINT_PREFIX) {
+			rest = append(rest, e)
+			continue
+		}
+		expr := strings.TrimPrefix(e, CONSTRAINT_PREFIX)
+		ct := constraint{equal: true}
+		i := strings.Index(expr, "==")
+		if j := strings.Index(expr, "!="); j >= 0 && (i < 0 || j < i) {
+			i, ct.equal = j, false
+		}
+		if i <= 0 {
+			return nil, nil, fmt.Errorf("invalid constraint %q", e)
+		}
+		ct.key, ct.value = expr[:i], expr[i+2:]
+		cts = append(cts, ct)
+	}
+	return cts, rest, nil
+}
+
+// matchConstraints returns the nodes whose metadata meets every constraint.
+func matchConstraints(nodes []Node, cts []constraint) []Node {
+	var matched []Node
+	for _, n := range nodes {
+		ok := true
+		for _, ct := range cts {
+			value := n.Metadata[ct.key]
+			if ct.key == NODE_CONSTRAINT {
+				value = n.Address
+			}
+			if (value == ct.value) != ct.equal {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			matched = append(matched, n)
+		}
+	}
+	return matched
+}
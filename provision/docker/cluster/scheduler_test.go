@@ -0,0 +1,174 @@
+package cluster
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/fsouza/go-dockerclient"
+	dtesting "github.com/fsouza/go-dockerclient/testing"
+)
+
+// inOrder schedules the nodes in the order of their addresses in it.
+type inOrder []string
+
+func (o inOrder) Schedule(c *Cluster, opts docker.CreateContainerOptions, nodes []Node) ([]Node, error) {
+	var ordered []Node
+	for _, addr := range o {
+		for _, n := range nodes {
+			if n.Address == addr {
+				ordered = append(ordered, n)
+			}
+		}
+	}
+	return ordered, nil
+}
+
+func newRegionServers(t *testing.T, c *Cluster, metadata ...map[string]string) []*dtesting.DockerServer {
+	var servers []*dtesting.DockerServer
+	for _, m := range metadata {
+		server, err := dtesting.NewServer("127.0.0.1:0", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		servers = append(servers, server)
+		m[DOCKER_ZONE] = "chennai"
+		if err = c.Register(Node{Address: server.URL(), Metadata: m}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return servers
+}
+
+func TestConstraintsOf(t *testing.T) {
+	cts, env, err := constraintsOf([]string{"PATH=/bin", "constraint:disk==ssd", "constraint:gpu!=none"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []constraint{{key: "disk", value: "ssd", equal: true}, {key: "gpu", value: "none"}}
+	if !reflect.DeepEqual(cts, want) {
+		t.Errorf("constraints are %v, want %v", cts, want)
+	}
+	if !reflect.DeepEqual(env, []string{"PATH=/bin"}) {
+		t.Errorf("env is %v", env)
+	}
+	if _, _, err = constraintsOf([]string{"constraint:disk"}); err == nil {
+		t.Error("expected an invalid constraint")
+	}
+	nodes := []Node{
+		{Address: "http://a", Metadata: map[string]string{"disk": "ssd", "gpu": "none"}},
+		{Address: "http://b", Metadata: map[string]string{"disk": "ssd", "gpu": "tesla"}},
+		{Address: "http://c", Metadata: map[string]string{"disk": "hdd", "gpu": "tesla"}},
+	}
+	if got := matchConstraints(nodes, cts); len(got) != 1 || got[0].Address != "http://b" {
+		t.Errorf("matched %v, want http://b", got)
+	}
+	cts, _, _ = constraintsOf([]string{"constraint:node==http://c"})
+	if got := matchConstraints(nodes, cts); len(got) != 1 || got[0].Address != "http://c" {
+		t.Errorf("matched %v, want http://c", got)
+	}
+}
+
+func TestRankNodes(t *testing.T) {
+	nodes := []Node{{Address: "http://a"}, {Address: "http://b"}, {Address: "http://c"}, {Address: "http://d"}}
+	scores := map[string]int64{"http://a": 3, "http://b": 1, "http://d": 1}
+	ranked := rankNodes(nodes, func(n Node) (int64, error) {
+		s, ok := scores[n.Address]
+		if !ok {
+			return 0, docker.ErrConnectionRefused
+		}
+		return s, nil
+	})
+	var got []string
+	for _, n := range ranked {
+		got = append(got, n.Address)
+	}
+	want := []string{"http://b", "http://d", "http://a", "http://c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ranked %v, want %v", got, want)
+	}
+}
+
+func TestCreateContainerFailsOver(t *testing.T) {
+	c, err := New(&MapStorage{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	servers := newRegionServers(t, c, map[string]string{}, map[string]string{})
+	defer servers[1].Stop()
+	dead := servers[0].URL()
+	servers[0].Stop()
+	c.Scheduler = inOrder{dead, servers[1].URL()}
+	addr, cont, err := c.InRegion("chennai").CreateContainer(docker.CreateContainerOptions{
+		Name:   "bigbang.megambox.com",
+		Config: &docker.Config{Image: "megam/busybox"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr != servers[1].URL() || cont == nil {
+		t.Errorf("container created in %s, want %s", addr, servers[1].URL())
+	}
+}
+
+func TestCreateContainerConstraints(t *testing.T) {
+	c, err := New(&MapStorage{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	servers := newRegionServers(t, c, map[string]string{"disk": "hdd"}, map[string]string{"disk": "ssd"})
+	for _, s := range servers {
+		defer s.Stop()
+	}
+	region := c.InRegion("chennai")
+	addr, cont, err := region.CreateContainer(docker.CreateContainerOptions{
+		Name:   "bigbang.megambox.com",
+		Config: &docker.Config{Image: "megam/busybox", Env: []string{"constraint:disk==ssd", "PATH=/bin"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr != servers[1].URL() {
+		t.Errorf("container created in %s, want %s", addr, servers[1].URL())
+	}
+	inspect, err := region.InspectContainer(cont.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(inspect.Config.Env, []string{"PATH=/bin"}) {
+		t.Errorf("the container got env %v", inspect.Config.Env)
+	}
+	_, _, err = region.CreateContainer(docker.CreateContainerOptions{
+		Config: &docker.Config{Image: "megam/busybox", Env: []string{"constraint:disk==nvme"}},
+	})
+	if err == nil {
+		t.Error("expected no node to meet disk==nvme")
+	}
+}
+
+func TestFewestContainersScheduler(t *testing.T) {
+	c, err := New(&MapStorage{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	servers := newRegionServers(t, c, map[string]string{}, map[string]string{})
+	for _, s := range servers {
+		defer s.Stop()
+	}
+	c.Scheduler = fewestContainers{}
+	region := c.InRegion("chennai")
+	for i := 0; i < 4; i++ {
+		if _, _, err = region.CreateContainer(docker.CreateContainerOptions{Config: &docker.Config{Image: "megam/busybox"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, s := range servers {
+		client, _ := docker.NewClient(s.URL())
+		containers, err := client.ListContainers(docker.ListContainersOptions{All: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(containers) != 2 {
+			t.Errorf("%s runs %d containers, want 2", s.URL(), len(containers))
+		}
+	}
+}
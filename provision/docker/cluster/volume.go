@@ -0,0 +1,83 @@
+package cluster
+
+import (
+	"github.com/fsouza/go-dockerclient"
+)
+
+// VOLUME_NODE labels a volume with the address of the node it is in. Volumes
+// are local to their node, the containers that mount them are created there.
+const VOLUME_NODE = "volume_node"
+
+// CreateVolume creates a named volume in the node at addr, or in the first
+// node of the region of the cluster when addr is blank.
+func (c *Cluster) CreateVolume(opts docker.CreateVolumeOptions, addr string) (*docker.Volume, error) {
+	if addr == "" {
+		nodes, err := c.regionNodes()
+		if err != nil {
+			return nil, err
+		}
+		addr = nodes[0].Address
+	}
+	node, err := c.getNodeByAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	labels := map[string]string{VOLUME_NODE: addr}
+	for k, v := range opts.Labels {
+		labels[k] = v
+	}
+	opts.Labels = labels
+	vol, err := node.CreateVolume(opts)
+	return vol, wrapError(node, err)
+}
+
+// ListVolumes returns the volumes in the nodes of the region of the cluster
+// matching opts.
+func (c *Cluster) ListVolumes(opts docker.ListVolumesOptions) ([]docker.Volume, error) {
+	nodes, err := c.regionNodes()
+	if err != nil {
+		return nil, err
+	}
+	var vols []docker.Volume
+	for _, n := range nodes {
+		node, err := c.getNodeByAddr(n.Address)
+		if err != nil {
+			return nil, err
+		}
+		found, err := node.ListVolumes(opts)
+		if err != nil {
+			return nil, wrapError(node, err)
+		}
+		vols = append(vols, found...)
+	}
+	return vols, nil
+}
+
+// RemoveVolume removes the named volume from the nodes of the region of the
+// cluster it is in. A volume still mounted by a container isn't removed.
+func (c *Cluster) RemoveVolume(name string) error {
+	nodes, err := c.regionNodes()
+	if err != nil {
+		return err
+	}
+	for _, n := range nodes {
+		node, err := c.getNodeByAddr(n.Address)
+		if err != nil {
+			return err
+		}
+		err = node.RemoveVolume(name)
+		if err != nil && err != docker.ErrNoSuchVolume {
+			return wrapError(node, err)
+		}
+	}
+	return nil
+}
+
+// ContainerNode returns the address of the node the container is in.
+func (c *Cluster) ContainerNode(id string) (string, error) {
+	node, err := c.getNodeForContainer(id)
+	if err != nil {
+		return "", err
+	}
+	return node.addr, nil
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"net"
 	"net/url"
+	"strings"
 	"time"
 	//	"os"
 	//	"encoding/json"
@@ -90,12 +91,21 @@ func (c *Container) Create(args *CreateArgs) error {
 		Memory:       int64(args.Box.ConGetMemory()),
 		MemorySwap:   int64(args.Box.ConGetMemory() + args.Box.GetSwap()),
 		CPUShares:    int64(args.Box.GetCpushare()),
+		Env:          constraints(args.Box),
 		Labels: map[string]string{utils.ASSEMBLY_ID: args.Box.CartonId, utils.ASSEMBLY_NAME: c.BoxName,
 			utils.ASSEMBLIES_ID: args.Box.CartonsId, utils.ACCOUNT_ID: args.Box.AccountId, utils.QUOTA_ID: args.Box.QuotaId},
 	}
-	opts := docker.CreateContainerOptions{Name: c.BoxName, Config: &config}
 	cl := args.Provisioner.Cluster()
-	cl.VNets = args.Box.Vnets
+	vols, err := Volumes(cl, args.Box)
+	if err != nil {
+		log.Errorf("Error on listing the disks of %s - %s", c.BoxName, err)
+		return err
+	}
+	// the volumes are local to their node, the container goes there.
+	if node := VolumeNode(vols); node != "" {
+		config.Env = append(config.Env, cluster.CONSTRAINT_PREFIX+cluster.NODE_CONSTRAINT+"=="+node)
+	}
+	opts := docker.CreateContainerOptions{Name: c.BoxName, Config: &config, HostConfig: &docker.HostConfig{Binds: binds(args.Box, vols)}}
 	addr, cont, err := cl.CreateContainerSchedulerOpts(opts)
 	if err != nil {
 		log.Errorf("Error on creating container in docker %s - %s", c.BoxName, err)
@@ -214,8 +224,9 @@ func (c *Container) Remove(p DockerProvisioner) error {
 	log.Debugf("Removing container %s from docker", c.BoxName)
 
 	//this will be removed. containerID will be stored upon create in riak
-	id, _ := p.Cluster().PreStopAction(c.BoxName)
-	c.Id = id
+	if id, err := p.Cluster().PreStopAction(c.BoxName); err == nil && id != "" {
+		c.Id = id
+	}
 	err := c.Stop(p)
 	if err != nil {
 		log.Errorf("error on stop unit %s - %s", c.Id, err)
@@ -242,10 +253,15 @@ func (c *Container) Start(args *StartArgs) error {
 		return err
 	}
 
+	binds, err := Binds(args.Provisioner.Cluster(), args.Box)
+	if err != nil {
+		return err
+	}
 	hostConfig := docker.HostConfig{
 		Memory:     int64(args.Box.ConGetMemory()),
 		MemorySwap: int64(args.Box.ConGetMemory() + args.Box.GetSwap()),
 		CPUShares:  int64(args.Box.GetCpushare()),
+		Binds:      binds,
 	}
 
 	err = args.Provisioner.Cluster().StartContainer(c.Id, &hostConfig)
@@ -275,6 +291,54 @@ func (c *Container) Stop(p DockerProvisioner) error {
 	return nil
 }
 
+// Suspend pauses the processes of the container, it keeps its memory. The box
+// is stopped the same as a suspended vm.
+func (c *Container) Suspend(p DockerProvisioner) error {
+	err := p.Cluster().PauseContainer(c.Id)
+	if err != nil {
+		log.Errorf("error on pause container %s: %s", c.Id, err)
+		return err
+	}
+	if err = c.SetStatus(constants.StatusSuspended); err != nil {
+		return err
+	}
+	return c.SetMileStone(constants.StateStopped)
+}
+
+// Resume unpauses a suspended container.
+func (c *Container) Resume(p DockerProvisioner) error {
+	err := p.Cluster().UnpauseContainer(c.Id)
+	if err != nil {
+		log.Errorf("error on unpause container %s: %s", c.Id, err)
+		return err
+	}
+	if err = c.SetMileStone(constants.StateRunning); err != nil {
+		return err
+	}
+	return c.SetStatus(constants.StatusContainerStarted)
+}
+
+// Paused tells if the container is paused.
+func (c *Container) Paused(p DockerProvisioner) bool {
+	cont, err := p.Cluster().InspectContainer(c.Id)
+	return err == nil && cont.State.Paused
+}
+
+// Resize changes the memory and cpu shares of the container to the compute.
+func (c *Container) Resize(p DockerProvisioner, to provision.BoxCompute) error {
+	box := &provision.Box{Compute: to}
+	err := p.Cluster().UpdateContainer(c.Id, docker.UpdateContainerOptions{
+		Memory:     int(box.ConGetMemory()),
+		MemorySwap: int(box.ConGetMemory() + box.GetSwap()),
+		CPUShares:  int(box.GetCpushare()),
+	})
+	if err != nil {
+		log.Errorf("error on resize container %s: %s", c.Id, err)
+		return err
+	}
+	return nil
+}
+
 type waitResult struct {
 	status int
 	err    error
@@ -486,3 +550,21 @@ func getPort() (string, error) {
 	*/
 	return "", nil
 }
+
+// constraints are the envs of the box named constraint:<label>, as the
+// constraints on the labels of the node its containers are created in. A
+// value starting with ! is a label the node mustn't have.
+func constraints(box *provision.Box) []string {
+	var env []string
+	for _, e := range box.Envs {
+		if !strings.HasPrefix(e.Name, cluster.CONSTRAINT_PREFIX) {
+			continue
+		}
+		if strings.HasPrefix(e.Value, "!") {
+			env = append(env, e.Name+"!="+e.Value[1:])
+		} else {
+			env = append(env, e.Name+"=="+e.Value)
+		}
+	}
+	return env
+}
@@ -0,0 +1,74 @@
+package container
+
+import (
+	"strings"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/megamsys/libgo/utils"
+	"github.com/megamsys/vertice/provision"
+	"github.com/megamsys/vertice/provision/docker/cluster"
+)
+
+// The disks of a box are named volumes labelled with its assembly id and the
+// id of the disk. They outlive the containers of the box, each container
+// started for the box mounts all of them.
+const (
+	DISK_ID   = "disk_id"
+	DISK_SIZE = "disk_size"
+)
+
+// VolumeName is the volume of the disk diskId of the box.
+func VolumeName(box *provision.Box, diskId string) string {
+	return strings.ToLower(box.GetFullName() + "-" + diskId)
+}
+
+// MountPath is where the disk diskId is mounted in the containers.
+func MountPath(diskId string) string {
+	return "/mnt/" + strings.ToLower(diskId)
+}
+
+// Volumes returns the volumes of the disks of the box.
+func Volumes(cl *cluster.Cluster, box *provision.Box) ([]docker.Volume, error) {
+	return cl.InRegion(box.Region).ListVolumes(docker.ListVolumesOptions{
+		Filters: map[string][]string{"label": {utils.ASSEMBLY_ID + "=" + box.CartonId}},
+	})
+}
+
+// Binds mounts the volumes of the disks of the box in a container, but the
+// ones of the disks being unmounted.
+func Binds(cl *cluster.Cluster, box *provision.Box) ([]string, error) {
+	vols, err := Volumes(cl, box)
+	if err != nil {
+		return nil, err
+	}
+	return binds(box, vols), nil
+}
+
+func binds(box *provision.Box, vols []docker.Volume) []string {
+	binds := make([]string, 0, len(vols))
+	for _, v := range vols {
+		if id := v.Labels[DISK_ID]; id != "" && !unmounting(box, id) {
+			binds = append(binds, v.Name+":"+MountPath(id))
+		}
+	}
+	return binds
+}
+
+func unmounting(box *provision.Box, diskId string) bool {
+	for _, id := range box.Unmounting {
+		if id == diskId {
+			return true
+		}
+	}
+	return false
+}
+
+// VolumeNode is the node the volumes of the box are in, blank when it has none.
+func VolumeNode(vols []docker.Volume) string {
+	for _, v := range vols {
+		if addr := v.Labels[cluster.VOLUME_NODE]; addr != "" {
+			return addr
+		}
+	}
+	return ""
+}
@@ -0,0 +1,81 @@
+package docker
+
+import (
+	"github.com/megamsys/libgo/action"
+	constants "github.com/megamsys/libgo/utils"
+	"github.com/megamsys/vertice/provision"
+)
+
+func deployActions() []*action.Action {
+	return []*action.Action{
+		&updateStatusInScylla,
+		&createContainer,
+		&updateContainerIdInScylla,
+		&MileStoneUpdate,
+		&updateStatusInScylla,
+		&startContainer,
+		&MileStoneUpdate,
+		&updateStatusInScylla,
+		&setNetworkInfo,
+		&updateStatusInScylla,
+		&followLogsAndCommit,
+		&MileStoneUpdate,
+		&updateStatusInScylla,
+	}
+}
+
+func destroyActions() []*action.Action {
+	return []*action.Action{
+		&destroyOldContainers,
+		&removeOldRoutes,
+	}
+}
+
+// PlanActions returns the names of the actions op would run on the box. Ops
+// that aren't pipelines are named by the calls they make, start, stop,
+// suspend and resize make them on every container of the box.
+func (p *dockerProvisioner) PlanActions(box *provision.Box, op provision.BoxOp) ([]string, error) {
+	switch op {
+	case provision.CYCLE_DEPLOY:
+		return provision.ActionNames(deployActions()), nil
+	case provision.CYCLE_DESTROY:
+		return append(provision.ActionNames(destroyActions()), "dockerProvisioner.removeVolumes"), nil
+	case provision.CYCLE_ROLLBACK:
+		return provision.ActionNames(append(destroyActions(), deployActions()...)), nil
+	case provision.CYCLE_UPGRADE:
+		return append([]string{"Cluster.BuildImage"}, provision.ActionNames(replaceActions())...), nil
+	case provision.CYCLE_START:
+		if box.Status == constants.StatusSuspended {
+			return []string{"Container.Resume"}, nil
+		}
+		return []string{"Container.Start", "dockerProvisioner.fixContainer"}, nil
+	case provision.CYCLE_STOP:
+		return []string{"Container.Stop"}, nil
+	case provision.CYCLE_SUSPEND:
+		return []string{"Container.Suspend"}, nil
+	case provision.CYCLE_RESIZE:
+		return []string{"Container.Resize"}, nil
+	case provision.CYCLE_SCALE:
+		// a scale up runs these for every unit added, a scale down only removes units.
+		return append(provision.ActionNames(unitActions()), "dockerProvisioner.removeUnits"), nil
+	case provision.CYCLE_SNAP_CREATE:
+		return []string{"dockerProvisioner.commitUnit", "Snaps.UpdateSnap", "makeActiveSnapshot"}, nil
+	case provision.CYCLE_SNAP_RESTORE:
+		return append(provision.ActionNames(replaceActions()), "makeActiveSnapshot"), nil
+	case provision.CYCLE_SNAP_DELETE:
+		return []string{"dockerProvisioner.cleanImage", "Snaps.RemoveSnap"}, nil
+	case provision.CYCLE_BACKUP_CREATE:
+		return []string{"dockerProvisioner.saveUnit", "Backups.UpdateBackup"}, nil
+	case provision.CYCLE_BACKUP_DELETE:
+		return []string{"dockerProvisioner.cleanImage", "Backups.RemoveBackup"}, nil
+	case provision.CYCLE_DISK_ATTACH:
+		names := append([]string{"Cluster.CreateVolume"}, provision.ActionNames(replaceActions())...)
+		return append(names, "Disks.UpdateDisk"), nil
+	case provision.CYCLE_DISK_DETACH:
+		return append(provision.ActionNames(replaceActions()), "Cluster.RemoveVolume"), nil
+	case provision.CYCLE_RESTART:
+		// nothing is done to containers for it yet.
+		return []string{}, nil
+	}
+	return nil, provision.ErrNotImplemented
+}
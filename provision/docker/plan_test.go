@@ -0,0 +1,25 @@
+package docker
+
+import (
+	constants "github.com/megamsys/libgo/utils"
+	"github.com/megamsys/vertice/provision"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestPlanStartSuspended(c *check.C) {
+	p := &dockerProvisioner{}
+	box := &provision.Box{CartonName: "bigbang", State: constants.StateStopped, Status: constants.StatusSuspended}
+	names, err := p.PlanActions(box, provision.CYCLE_START)
+	c.Assert(err, check.IsNil)
+	c.Assert(names, check.DeepEquals, []string{"Container.Resume"})
+	box.Status = constants.StatusContainerStopped
+	names, err = p.PlanActions(box, provision.CYCLE_START)
+	c.Assert(err, check.IsNil)
+	c.Assert(names, check.DeepEquals, []string{"Container.Start", "dockerProvisioner.fixContainer"})
+}
+
+func (s *S) TestPlanUpgradeReplacesUnits(c *check.C) {
+	names, err := (&dockerProvisioner{}).PlanActions(&provision.Box{CartonName: "bigbang"}, provision.CYCLE_UPGRADE)
+	c.Assert(err, check.IsNil)
+	c.Assert(names, check.DeepEquals, []string{"Cluster.BuildImage", "rename-old-units", "unroute-old-units", "deploy-new-units", "remove-old-units"})
+}
@@ -2,6 +2,7 @@ package docker
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -41,6 +42,9 @@ type dockerProvisioner struct {
 type Docker struct {
 	Enabled bool     `json:"enabled" toml:"enabled"`
 	Regions []Region `json:"region" toml:"region"`
+	// Storage keeps the cluster in memory, or in a file when it is file, so
+	// that the nodes of the containers and their health survive a restart.
+	Storage string `json:"storage" toml:"storage"`
 }
 
 type Region struct {
@@ -50,6 +54,11 @@ type Region struct {
 	Registry       string        `json:"registry" toml:"registry"`
 	CPUPeriod      toml.Duration `json:"cpu_period" toml:"cpu_period"`
 	CPUQuota       toml.Duration `json:"cpu_quota" toml:"cpu_quota"`
+	// Scheduler picks the node of the region a container goes to, when it
+	// has more than one: memory, containers or spread (the default).
+	Scheduler string `json:"scheduler" toml:"scheduler"`
+	// Labels of the node, containers constrain the nodes they go to with them.
+	Labels map[string]string `json:"labels" toml:"labels"`
 }
 
 func (p *dockerProvisioner) Cluster() *cluster.Cluster {
@@ -59,6 +68,17 @@ func (p *dockerProvisioner) Cluster() *cluster.Cluster {
 	return p.cluster
 }
 
+// inRegion is p working on the view of the cluster for the region of the
+// box, with the networks of the box.
+func (p *dockerProvisioner) inRegion(box *provision.Box) *dockerProvisioner {
+	v := *p
+	v.cluster = p.Cluster().InRegion(box.Region)
+	if len(box.Vnets) > 0 {
+		v.cluster.VNets = box.Vnets
+	}
+	return &v
+}
+
 func (p *dockerProvisioner) String() string {
 	if p.cluster == nil {
 		return "✗ docker cluster"
@@ -66,19 +86,35 @@ func (p *dockerProvisioner) String() string {
 	return "ready"
 }
 
+// Capabilities of docker are a shell and exec in the containers, resizing
+// and pausing them in place, snapshots and backups of their images, and disks
+// as docker volumes.
+func (p *dockerProvisioner) Capabilities() []provision.Capability {
+	return []provision.Capability{
+		provision.CAP_SHELL,
+		provision.CAP_EXEC,
+		provision.CAP_RESIZE,
+		provision.CAP_SNAPSHOTS,
+		provision.CAP_BACKUPS,
+		provision.CAP_DISKS,
+		provision.CAP_SUSPEND,
+	}
+}
+
 func (p *dockerProvisioner) Initialize(m interface{}) error {
 	return p.initDockerCluster(m)
 }
 
 func (p *dockerProvisioner) initDockerCluster(i interface{}) error {
 	var err error
+	w, ok := i.(Docker)
 	if p.storage == nil {
-		p.storage, err = buildClusterStorage()
+		p.storage, err = buildClusterStorage(w.Storage)
 		if err != nil {
 			return err
 		}
 	}
-	if w, ok := i.(Docker); ok {
+	if ok {
 		var nodes []cluster.Node
 		for i := 0; i < len(w.Regions); i++ {
 			m := w.Regions[i].toMap()
@@ -94,25 +130,53 @@ func (p *dockerProvisioner) initDockerCluster(i interface{}) error {
 		if err != nil {
 			return err
 		}
+		if fs, ok := p.storage.(*cluster.FileStorage); ok && !fs.Migrated() {
+			p.rebuildContainers(fs)
+		}
 	}
 	return nil
 }
 
+// rebuildContainers fills a new FileStorage with the containers the nodes
+// already have, those created while the cluster was kept in memory. It is
+// tried again on the next start till all the nodes could be listed.
+func (p *dockerProvisioner) rebuildContainers(fs *cluster.FileStorage) {
+	n, err := p.cluster.RebuildContainers()
+	log.Debugf("  %d containers of the docker cluster stored", n)
+	if err != nil {
+		log.Errorf("  rebuilding the containers of the docker cluster: %s", err)
+		return
+	}
+	if err = fs.SetMigrated(); err != nil {
+		log.Errorf("  marking the containers of the docker cluster as stored: %s", err)
+	}
+}
+
 //convert the config to just a map.
 
 func (c Region) toMap() map[string]string {
 	m := make(map[string]string)
+	for k, v := range c.Labels {
+		m[k] = v
+	}
 	m[cluster.DOCKER_ZONE] = c.DockerZone
 	m[cluster.DOCKER_SWARM] = c.SwarmEndPoint
 	m[cluster.DOCKER_GULP] = c.DockerGulpPort
 	m[cluster.DOCKER_REGISTRY] = c.Registry
 	m[cluster.DOCKER_CPUPERIOD] = c.CPUPeriod.String()
 	m[cluster.DOCKER_CPUQUOTA] = c.CPUQuota.String()
+	m[cluster.DOCKER_SCHEDULER] = c.Scheduler
 	return m
 }
 
-func buildClusterStorage() (cluster.Storage, error) {
-	return &cluster.MapStorage{}, nil
+func buildClusterStorage(kind string) (cluster.Storage, error) {
+	switch kind {
+	case "", provision.STORAGE_MEMORY:
+		return &cluster.MapStorage{}, nil
+	case provision.STORAGE_FILE:
+		return cluster.NewFileStorage(provision.ClusterStoragePath("docker"))
+	}
+	return nil, fmt.Errorf("unknown docker cluster storage %q, it is %s or %s", kind, provision.STORAGE_MEMORY, provision.STORAGE_FILE)
 }
 
 func getRouterForBox(box *provision.Box) (router.Router, error) {
@@ -134,19 +198,19 @@ func (p *dockerProvisioner) StartupMessage() (string, error) {
 	return strings.TrimSpace(b.String()), nil
 }
 
-func (p *dockerProvisioner) GitDeploy(box *provision.Box, w io.Writer) (string, error) {
+func (p *dockerProvisioner) GitDeploy(ctx context.Context, box *provision.Box, w io.Writer) (string, error) {
 	imageId, err := p.gitDeploy(box.Repo, box.ImageVersion, w)
 	if err != nil {
 		return "", err
 	}
-	return p.deployPipeline(box, imageId, w)
+	return p.deployPipeline(ctx, box, imageId, w)
 }
 
 func (p *dockerProvisioner) gitDeploy(re *repository.Repo, version string, w io.Writer) (string, error) {
 	return p.getBuildImage(re, version), nil
 }
 
-func (p *dockerProvisioner) ImageDeploy(box *provision.Box, imageId string, w io.Writer) (string, error) {
+func (p *dockerProvisioner) ImageDeploy(ctx context.Context, box *provision.Box, imageId string, w io.Writer) (string, error) {
 	isValid, err := isValidBoxImage(box.GetFullName(), imageId)
 	if err != nil {
 		return "", err
@@ -154,30 +218,16 @@ func (p *dockerProvisioner) ImageDeploy(box *provision.Box, imageId string, w io
 	if !isValid {
 		return "", fmt.Errorf("invalid image for box %s: %s", box.GetFullName(), imageId)
 	}
-	return p.deployPipeline(box, imageId, w)
+	return p.deployPipeline(ctx, box, imageId, w)
 }
 
-func (p *dockerProvisioner) deployPipeline(box *provision.Box, imageId string, w io.Writer) (string, error) {
+func (p *dockerProvisioner) deployPipeline(ctx context.Context, box *provision.Box, imageId string, w io.Writer) (string, error) {
+	p = p.inRegion(box)
 	fmt.Fprintf(w, lb.W(lb.DEPLOY, lb.INFO, fmt.Sprintf("--- deploy box (%s, image:%s)", box.GetFullName(), imageId)))
-	actions := []*action.Action{
-		&updateStatusInScylla,
-		&createContainer,
-		&updateContainerIdInScylla,
-		&MileStoneUpdate,
-		&updateStatusInScylla,
-		&startContainer,
-		&MileStoneUpdate,
-		&updateStatusInScylla,
-		&setNetworkInfo,
-		&updateStatusInScylla,
-		&followLogsAndCommit,
-		&MileStoneUpdate,
-		&updateStatusInScylla,
-	}
-	p.Cluster().Region = box.Region
-	pipeline := action.NewPipeline(actions...)
+	pipeline := action.NewPipeline(provision.TrackActions(box, deployActions())...)
 
 	args := runContainerActionsArgs{
+		ctx:             ctx,
 		box:             box,
 		imageId:         imageId,
 		writer:          w,
@@ -196,11 +246,8 @@ func (p *dockerProvisioner) deployPipeline(box *provision.Box, imageId string, w
 	return imageId, nil
 }
 
-func (p *dockerProvisioner) BackupDeploy(box *provision.Box, imageId string, w io.Writer) (string, error) {
-	return "", nil
-}
-
-func (p *dockerProvisioner) Destroy(box *provision.Box, w io.Writer) error {
+func (p *dockerProvisioner) Destroy(ctx context.Context, box *provision.Box, w io.Writer) error {
+	p = p.inRegion(box)
 
 	fmt.Fprintf(w, lb.W(lb.DESTORYING, lb.INFO, fmt.Sprintf("\n--- destroying box (%s) ----", box.GetFullName())))
 	containers, err := p.listContainersByBox(box)
@@ -208,33 +255,34 @@ func (p *dockerProvisioner) Destroy(box *provision.Box, w io.Writer) error {
 		fmt.Fprintf(w, lb.W(lb.DESTORYING, lb.ERROR, fmt.Sprintf("Failed to list box containers (%s) --> %s", box.GetFullName(), err)))
 		return err
 	}
-	p.Cluster().Region = box.Region
 	args := changeUnitsPipelineArgs{
+		ctx:         ctx,
 		box:         box,
 		toRemove:    containers,
 		writer:      ioutil.Discard,
 		provisioner: p,
 		boxDestroy:  true,
 	}
-	pipeline := action.NewPipeline(
-		&destroyOldContainers,
-		&removeOldRoutes,
-	)
+	pipeline := action.NewPipeline(provision.TrackActions(box, destroyActions())...)
 	err = pipeline.Execute(args)
 	if err != nil {
 		return err
 	}
-	return nil
+	return p.removeVolumes(box, w)
 }
 
-func (p *dockerProvisioner) Start(box *provision.Box, process string, w io.Writer) error {
+func (p *dockerProvisioner) Start(ctx context.Context, box *provision.Box, process string, w io.Writer) error {
+	p = p.inRegion(box)
 	containers, err := p.listContainersByBox(box)
 	if err != nil {
 		fmt.Fprintf(w, lb.W(lb.STARTING, lb.ERROR, fmt.Sprintf("Failed to list box containers (%s) --> %s", box.GetFullName(), err)))
 		return err
 	}
-	p.Cluster().Region = box.Region
 	return runInContainers(containers, func(c *container.Container, _ chan *container.Container) error {
+		// a suspended box is paused, it only needs to carry on.
+		if c.Paused(p) {
+			return c.Resume(p)
+		}
 		err := c.Start(&container.StartArgs{
 			Provisioner: p,
 			Box:         box,
@@ -250,13 +298,13 @@ func (p *dockerProvisioner) Start(box *provision.Box, process string, w io.Write
 	}, nil, true)
 }
 
-func (p *dockerProvisioner) Stop(box *provision.Box, process string, w io.Writer) error {
+func (p *dockerProvisioner) Stop(ctx context.Context, box *provision.Box, process string, w io.Writer) error {
+	p = p.inRegion(box)
 	containers, err := p.listContainersByBox(box)
 	if err != nil {
 		fmt.Fprintf(w, lb.W(lb.STOPPING, lb.ERROR, fmt.Sprintf("Failed to list box containers (%s) --> %s", box.GetFullName(), err)))
 		return err
 	}
-	p.Cluster().Region = box.Region
 	return runInContainers(containers, func(c *container.Container, _ chan *container.Container) error {
 		err := c.Stop(p)
 		if err != nil {
@@ -266,7 +314,50 @@ func (p *dockerProvisioner) Stop(box *provision.Box, process string, w io.Writer
 	}, nil, true)
 }
 
-func (p *dockerProvisioner) Restart(box *provision.Box, process string, w io.Writer) error {
+// Suspend pauses every container of the box. They keep their memory, and
+// carry on from where they were when the box is started.
+func (p *dockerProvisioner) Suspend(ctx context.Context, box *provision.Box, process string, w io.Writer) error {
+	p = p.inRegion(box)
+	fmt.Fprintf(w, lb.W(lb.STOPPING, lb.INFO, fmt.Sprintf("--- suspending box (%s)", box.GetFullName())))
+	containers, err := p.listContainersByBox(box)
+	if err != nil {
+		fmt.Fprintf(w, lb.W(lb.STOPPING, lb.ERROR, fmt.Sprintf("Failed to list box containers (%s) --> %s", box.GetFullName(), err)))
+		return err
+	}
+	err = runInContainers(containers, func(c *container.Container, _ chan *container.Container) error {
+		err := c.Suspend(p)
+		if err != nil {
+			log.Errorf("Failed to suspend %q: %s", box.GetFullName(), err)
+		}
+		return err
+	}, nil, true)
+	if err != nil {
+		fmt.Fprintf(w, lb.W(lb.STOPPING, lb.ERROR, fmt.Sprintf("--- suspending box (%s)-->%s", box.GetFullName(), err)))
+		return err
+	}
+	fmt.Fprintf(w, lb.W(lb.STOPPING, lb.INFO, fmt.Sprintf("--- suspending box (%s) OK", box.GetFullName())))
+	return nil
+}
+
+// Resize updates the containers of the box in place, they keep running.
+func (p *dockerProvisioner) Resize(ctx context.Context, box *provision.Box, to provision.BoxCompute, w io.Writer) error {
+	p = p.inRegion(box)
+	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- resizing box (%s) to %s", box.GetFullName(), to.String())))
+	containers, err := p.listContainersByBox(box)
+	if err != nil {
+		fmt.Fprintf(w, lb.W(lb.UPDATING, lb.ERROR, fmt.Sprintf("Failed to list box containers (%s) --> %s", box.GetFullName(), err)))
+		return err
+	}
+	return runInContainers(containers, func(c *container.Container, _ chan *container.Container) error {
+		err := c.Resize(p, to)
+		if err != nil {
+			log.Errorf("Failed to resize %q: %s", box.GetFullName(), err)
+		}
+		return err
+	}, nil, true)
+}
+
+func (p *dockerProvisioner) Restart(ctx context.Context, box *provision.Box, process string, w io.Writer) error {
 	return nil
 }
 
@@ -285,15 +376,16 @@ func (*dockerProvisioner) Addr(box *provision.Box) (string, error) {
 }
 
 func (p *dockerProvisioner) SetBoxStatus(box *provision.Box, w io.Writer, status utils.Status) error {
+	p = p.inRegion(box)
 
 	fmt.Fprintf(w, lb.W(lb.DEPLOY, lb.INFO, fmt.Sprintf("---- status %s box %s ----", box.GetFullName(), status.String())))
 	actions := []*action.Action{
 		&updateStatusInScylla,
 	}
-	p.Cluster().Region = box.Region
-	pipeline := action.NewPipeline(actions...)
+	pipeline := action.NewPipeline(provision.TrackActions(box, actions)...)
 
 	args := runContainerActionsArgs{
+		ctx:             context.Background(),
 		box:             box,
 		writer:          w,
 		containerStatus: status,
@@ -374,19 +466,8 @@ func (p *dockerProvisioner) PlatformRemove(name string) error {
 	return err
 }
 
-func (p *dockerProvisioner) CreateSnapshot(b *provision.Box, io io.Writer) error {
-	return nil
-}
-
-func (p *dockerProvisioner) DeleteSnapshot(b *provision.Box, io io.Writer) error {
-	return nil
-}
-
-func (p *dockerProvisioner) RestoreSnapshot(b *provision.Box, io io.Writer) error {
-	return nil
-}
-
 func (p *dockerProvisioner) Shell(opts provision.ShellOptions) error {
+	p = p.inRegion(opts.Box)
 	var (
 		c   *container.Container
 		err error
@@ -400,7 +481,7 @@ func (p *dockerProvisioner) Shell(opts provision.ShellOptions) error {
 }
 
 func (p *dockerProvisioner) ExecuteCommandOnce(stdout, stderr io.Writer, box *provision.Box, cmd string, args ...string) error {
-	p.Cluster().Region = box.Region
+	p = p.inRegion(box)
 	container, err := p.GetContainerByBox(box)
 	if err != nil {
 		return err
@@ -420,26 +501,6 @@ func (p *dockerProvisioner) MetricEnvs(start, end int64, point string, w io.Writ
 	return res, nil
 }
 
-func (p *dockerProvisioner) Suspend(box *provision.Box, process string, w io.Writer) error {
-	return nil
-}
-
-func (p *dockerProvisioner) SaveImage(box *provision.Box, w io.Writer) error {
-	return nil
-}
-
-func (p *dockerProvisioner) DeleteImage(box *provision.Box, w io.Writer) error {
-	return nil
-}
-
-func (p *dockerProvisioner) AttachDisk(box *provision.Box, w io.Writer) error {
-	return nil
-}
-
-func (p *dockerProvisioner) DetachDisk(box *provision.Box, w io.Writer) error {
-	return nil
-}
-
 func (p *dockerProvisioner) TriggerBills(account_id, cat_id, name string) error {
 	return nil
 }
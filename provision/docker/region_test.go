@@ -0,0 +1,22 @@
+package docker
+
+import (
+	"github.com/megamsys/vertice/provision"
+	"github.com/megamsys/vertice/provision/docker/cluster"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestInRegionKeepsTheSharedCluster(c *check.C) {
+	cl, err := cluster.New(&cluster.MapStorage{})
+	c.Assert(err, check.IsNil)
+	p := &dockerProvisioner{cluster: cl}
+	vnets := map[string]string{"privateipv4": "true"}
+	chennai := p.inRegion(&provision.Box{Region: "chennai", Vnets: vnets})
+	tokyo := p.inRegion(&provision.Box{Region: "tokyo"})
+	c.Assert(chennai.Cluster().Region, check.Equals, "chennai")
+	c.Assert(chennai.Cluster().VNets, check.DeepEquals, vnets)
+	c.Assert(tokyo.Cluster().Region, check.Equals, "tokyo")
+	c.Assert(tokyo.Cluster().VNets, check.IsNil)
+	c.Assert(p.Cluster().Region, check.Equals, "")
+	c.Assert(p.Cluster().VNets, check.IsNil)
+}
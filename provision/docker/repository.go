@@ -26,12 +26,13 @@ func (p *dockerProvisioner) GetContainerByBox(box *provision.Box) (*container.Co
 }
 
 func (p *dockerProvisioner) listContainersByBox(box *provision.Box) ([]container.Container, error) {
+	units, err := p.listUnits(box)
+	if err == nil && len(units) > 0 {
+		return units, nil
+	}
+	//the box isn't labelled on its nodes (an older deploy) - fall back to the
+	//container of its instance id.
 	list := make([]container.Container, 1)
-	//
-	//do a query on the name to riak, and call GetContainerByBox(box)
-	//
-
-	//This is a temporary hack - sending []container.Container to assign n workers
 	nx, _ := p.GetContainerByBox(box)
 	list[0] = *nx
 	return list, nil
@@ -0,0 +1,374 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/fsouza/go-dockerclient"
+	constants "github.com/megamsys/libgo/utils"
+	"github.com/megamsys/vertice/carton"
+	lb "github.com/megamsys/vertice/logbox"
+	"github.com/megamsys/vertice/meta"
+	"github.com/megamsys/vertice/provision"
+	"github.com/megamsys/vertice/provision/docker/container"
+)
+
+// CreateSnapshot commits unit 0 of the box as an image tagged with the id of
+// the snapshot, and pushes it to the registry of the region.
+func (p *dockerProvisioner) CreateSnapshot(ctx context.Context, box *provision.Box, w io.Writer) error {
+	p = p.inRegion(box)
+	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- creating snapshot box (%s)", box.GetFullName())))
+	snp, err := carton.GetSnap(box.CartonsId, box.AccountId)
+	if err != nil {
+		return err
+	}
+	c, err := p.snapUnit(box)
+	if err != nil {
+		return err
+	}
+	repo, tag := snapImage(p.Cluster().Registry(), box, snp.Id)
+	if err = p.updateSnapStatus(snp, box, w, constants.StatusSnapCreating); err != nil {
+		return err
+	}
+	image, err := p.commitUnit(c, repo, tag, w)
+	if err != nil {
+		fmt.Fprintf(w, lb.W(lb.UPDATING, lb.ERROR, fmt.Sprintf("--- creating snapshot box (%s)--> %s", box.GetFullName(), err)))
+		p.updateSnapStatus(snp, box, w, constants.StatusError)
+		return err
+	}
+	snp.SnapId = image
+	snp.DiskId = "0"
+	snp.Status = "created"
+	if err = snp.UpdateSnap(); err != nil {
+		return err
+	}
+	if err = makeActiveSnapshot(snp); err != nil {
+		return err
+	}
+	if len(box.QuotaId) > 0 {
+		if err = updateSnapQuotas(box, -1); err != nil {
+			return err
+		}
+	}
+	if err = p.SetBoxStatus(box, w, constants.StatusSnapCreated); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- creating snapshot box (%s, %s)OK", box.GetFullName(), image)))
+	return nil
+}
+
+// RestoreSnapshot replaces the units of the box by ones started from the
+// image of the snapshot.
+func (p *dockerProvisioner) RestoreSnapshot(ctx context.Context, box *provision.Box, w io.Writer) error {
+	p = p.inRegion(box)
+	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- restore snapshot box (%s)", box.GetFullName())))
+	snp, err := carton.GetSnap(box.CartonsId, box.AccountId)
+	if err != nil {
+		return err
+	}
+	if snp.SnapId == "" {
+		return fmt.Errorf("snapshot %s of box %s has no image to restore", snp.Id, box.GetFullName())
+	}
+	if err = p.updateSnapStatus(snp, box, w, constants.StatusSnapRestoring); err != nil {
+		return err
+	}
+	old, err := p.listContainersByBox(box)
+	if err != nil {
+		return err
+	}
+	if err = p.replaceUnits(ctx, box, snp.SnapId, old, w); err != nil {
+		fmt.Fprintf(w, lb.W(lb.UPDATING, lb.ERROR, fmt.Sprintf("--- restore snapshot box (%s)--> %s", box.GetFullName(), err)))
+		p.updateSnapStatus(snp, box, w, constants.StatusError)
+		return err
+	}
+	if err = makeActiveSnapshot(snp); err != nil {
+		return err
+	}
+	if err = p.SetBoxStatus(box, w, constants.StatusSnapRestored); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- restore snapshot box (%s, %s)OK", box.GetFullName(), snp.SnapId)))
+	return nil
+}
+
+// DeleteSnapshot removes the image of the snapshot from the nodes and the
+// registry, then the snapshot.
+func (p *dockerProvisioner) DeleteSnapshot(ctx context.Context, box *provision.Box, w io.Writer) error {
+	p = p.inRegion(box)
+	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- removing snapshot box (%s)", box.GetFullName())))
+	snp, err := carton.GetSnap(box.CartonsId, box.AccountId)
+	if err != nil {
+		return err
+	}
+	if err = p.SetBoxStatus(box, w, constants.StatusSnapDeleting); err != nil {
+		return err
+	}
+	if snp.SnapId != "" {
+		p.cleanImage(box.GetFullName(), snp.SnapId)
+	}
+	if err = snp.RemoveSnap(); err != nil {
+		fmt.Fprintf(w, lb.W(lb.UPDATING, lb.ERROR, fmt.Sprintf("--- removing snapshot box (%s)--> %s", box.GetFullName(), err)))
+		return err
+	}
+	if snp.IsQuota() {
+		if err = updateSnapQuotas(box, 1); err != nil {
+			return err
+		}
+	}
+	if err = p.SetBoxStatus(box, w, constants.StatusSnapDeleted); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- removing snapshot box (%s)OK", box.GetFullName())))
+	return nil
+}
+
+// SaveImage backs up the box by committing unit 0, and saving the image with
+// its config as a tarball in the backups dir of vertice. BackupDeploy loads
+// it back.
+func (p *dockerProvisioner) SaveImage(ctx context.Context, box *provision.Box, w io.Writer) error {
+	p = p.inRegion(box)
+	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- creating backup box (%s)", box.GetFullName())))
+	if box.Tosca == constants.BACKUP_NEW {
+		return fmt.Errorf("box %s can't upload a new backup image to docker", box.GetFullName())
+	}
+	bk, err := carton.GetBackup(box.CartonsId, box.AccountId)
+	if err != nil {
+		return err
+	}
+	c, err := p.snapUnit(box)
+	if err != nil {
+		return err
+	}
+	if err = updateBackupStatus(bk, constants.StatusBackupCreating); err != nil {
+		return err
+	}
+	path := backupPath(box, bk.Id)
+	if err = p.saveUnit(c, box, bk.Id, path, w); err != nil {
+		fmt.Fprintf(w, lb.W(lb.UPDATING, lb.ERROR, fmt.Sprintf("--- creating backup box (%s)--> %s", box.GetFullName(), err)))
+		updateBackupStatus(bk, constants.StatusError)
+		return err
+	}
+	bk.Outputs.NukeAndSet(map[string][]string{constants.SOURCE_PATH: {path}})
+	bk.ImageId = path
+	bk.Status = constants.StatusBackupCreated.String()
+	if err = bk.UpdateBackup(); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- creating backup box (%s, %s)OK", box.GetFullName(), path)))
+	return nil
+}
+
+// BackupDeploy deploys the box from the tarball of a backup. The image is
+// loaded in a node and pushed to the registry of the region, or loaded in
+// every node when the region has no registry, and the units of the box are
+// replaced by ones of it.
+func (p *dockerProvisioner) BackupDeploy(ctx context.Context, box *provision.Box, imageId string, w io.Writer) (string, error) {
+	p = p.inRegion(box)
+	id := strings.TrimSuffix(filepath.Base(imageId), filepath.Ext(imageId))
+	repo, tag := backupImage(box, id)
+	name := repo + ":" + tag
+	fmt.Fprintf(w, lb.W(lb.DEPLOY, lb.INFO, fmt.Sprintf("--- loading backup %s of box (%s)", id, box.GetFullName())))
+	regRepo, _ := snapImage(p.Cluster().Registry(), box, id)
+	pushed := strings.Contains(regRepo, "/")
+	err := p.Cluster().LoadImage(name, func() (io.ReadCloser, error) {
+		return os.Open(imageId)
+	}, !pushed)
+	if err != nil {
+		fmt.Fprintf(w, lb.W(lb.DEPLOY, lb.ERROR, fmt.Sprintf("--- loading backup %s of box (%s)--> %s", id, box.GetFullName(), err)))
+		return "", err
+	}
+	if pushed {
+		if err = p.Cluster().TagImage(name, docker.TagImageOptions{Repo: regRepo, Tag: tag, Force: true}); err != nil {
+			return "", err
+		}
+		fmt.Fprintf(w, lb.W(lb.DEPLOY, lb.INFO, fmt.Sprintf(" ---> Sending image %s:%s to registry", regRepo, tag)))
+		if err = p.PushImage(regRepo, tag); err != nil {
+			return "", fmt.Errorf("error in push image %s:%s: %s", regRepo, tag, err)
+		}
+		name = regRepo + ":" + tag
+	}
+	old, err := p.listUnits(box)
+	if err != nil {
+		return "", err
+	}
+	if len(old) == 0 {
+		return p.deployPipeline(ctx, box, name, w)
+	}
+	if err = p.replaceUnits(ctx, box, name, old, w); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// DeleteImage removes the tarball of the backup and the images loaded from
+// it, then the backup.
+func (p *dockerProvisioner) DeleteImage(ctx context.Context, box *provision.Box, w io.Writer) error {
+	p = p.inRegion(box)
+	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- removing backup box (%s)", box.GetFullName())))
+	bk, err := carton.GetBackup(box.CartonsId, box.AccountId)
+	if err != nil {
+		return err
+	}
+	if err = updateBackupStatus(bk, constants.StatusBackupDeleting); err != nil {
+		return err
+	}
+	if bk.ImageId != "" {
+		if err = os.Remove(bk.ImageId); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(w, lb.W(lb.UPDATING, lb.ERROR, fmt.Sprintf("--- removing backup box (%s)--> %s", box.GetFullName(), err)))
+			return err
+		}
+		repo, tag := backupImage(box, bk.Id)
+		p.cleanImage(box.GetFullName(), repo+":"+tag)
+		if regRepo, _ := snapImage(p.Cluster().Registry(), box, bk.Id); strings.Contains(regRepo, "/") {
+			p.cleanImage(box.GetFullName(), regRepo+":"+tag)
+		}
+	}
+	if err = bk.RemoveBackup(); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- removing backup box (%s)OK", box.GetFullName())))
+	return nil
+}
+
+// snapUnit is the unit a snapshot or a backup is taken of, unit 0 when the
+// box has one.
+func (p *dockerProvisioner) snapUnit(box *provision.Box) (*container.Container, error) {
+	units, err := p.listContainersByBox(box)
+	if err != nil {
+		return nil, err
+	}
+	for i := range units {
+		if unitIndex(box, units[i].BoxName) == 0 {
+			return &units[i], nil
+		}
+	}
+	if len(units) == 0 || units[0].Id == "" {
+		return nil, fmt.Errorf("box %s has no container to snapshot", box.GetFullName())
+	}
+	return &units[0], nil
+}
+
+// commitUnit commits the container as repo:tag, and pushes it when repo is in
+// a registry.
+func (p *dockerProvisioner) commitUnit(c *container.Container, repo, tag string, w io.Writer) (string, error) {
+	image, err := p.Cluster().CommitContainer(docker.CommitContainerOptions{Container: c.Id, Repository: repo, Tag: tag})
+	if err != nil {
+		return "", fmt.Errorf("error in commit container %s: %s", c.ShortId(), err)
+	}
+	log.Debugf("  image %s committed from container %s", image.ID, c.ShortId())
+	name := repo + ":" + tag
+	if strings.Contains(repo, "/") {
+		fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf(" ---> Sending image %s to registry", name)))
+		if err = p.PushImage(repo, tag); err != nil {
+			p.Cluster().RemoveImage(name)
+			return "", fmt.Errorf("error in push image %s: %s", name, err)
+		}
+	}
+	return name, nil
+}
+
+// saveUnit commits the container as the image of the backup id, and writes
+// the image as a tarball to path. The committed image isn't kept in the node.
+func (p *dockerProvisioner) saveUnit(c *container.Container, box *provision.Box, id, path string, w io.Writer) error {
+	repo, tag := backupImage(box, id)
+	name, err := p.commitUnit(c, repo, tag, w)
+	if err != nil {
+		return err
+	}
+	defer p.Cluster().RemoveImage(name)
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if err = p.Cluster().ExportImage(docker.ExportImageOptions{Name: name, OutputStream: out}); err != nil {
+		os.Remove(path)
+		return fmt.Errorf("error in save image %s: %s", name, err)
+	}
+	return nil
+}
+
+func (p *dockerProvisioner) updateSnapStatus(snp *carton.Snaps, box *provision.Box, w io.Writer, status constants.Status) error {
+	snp.Status = status.String()
+	if err := snp.UpdateSnap(); err != nil {
+		return err
+	}
+	return p.SetBoxStatus(box, w, status)
+}
+
+// snapImage is the repository and tag of the image of a snapshot, in the
+// registry when the region has one.
+func snapImage(registry string, box *provision.Box, snapId string) (string, string) {
+	repo := strings.ToLower(box.GetFullName())
+	if host := registryHost(registry); host != "" {
+		repo = host + "/" + repo
+	}
+	return repo, strings.ToLower(snapId)
+}
+
+// registryHost is the host[:port] images of the registry are named after.
+func registryHost(registry string) string {
+	if u, err := url.Parse(registry); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return strings.TrimSuffix(registry, "/")
+}
+
+// backupImage is the repository and tag of the image a backup is saved from
+// and loaded as, in the nodes only.
+func backupImage(box *provision.Box, backupId string) (string, string) {
+	return strings.ToLower(box.GetFullName()), "bk-" + strings.ToLower(backupId)
+}
+
+func backupPath(box *provision.Box, id string) string {
+	return filepath.Join(meta.MC.Dir, "backups", box.AccountId, id+".tar")
+}
+
+// makeActiveSnapshot makes snp the active snapshot of its assembly, the one
+// that was active before isn't anymore.
+func makeActiveSnapshot(snp *carton.Snaps) error {
+	snaps, err := carton.GetAsmSnaps(snp.AssemblyId, snp.AccountId)
+	if err != nil {
+		return err
+	}
+	for _, v := range snaps {
+		if v.Id == snp.Id {
+			v.Status = constants.ACTIVESNAP
+		} else if v.Status == constants.ACTIVESNAP {
+			v.Status = constants.DEACTIVESNAP
+		} else {
+			continue
+		}
+		if err = v.UpdateSnap(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func updateBackupStatus(bk *carton.Backups, status constants.Status) error {
+	bk.Status = status.String()
+	return bk.UpdateBackup()
+}
+
+// updateSnapQuotas gives back (n > 0) or takes (n < 0) snapshots of the quota
+// of the box.
+func updateSnapQuotas(box *provision.Box, n int) error {
+	quota, err := carton.NewQuota(box.AccountId, box.QuotaId)
+	if err != nil {
+		return err
+	}
+	count, _ := strconv.Atoi(quota.AllowedSnaps())
+	quota.Allowed.NukeAndSet(map[string][]string{"no_of_units": {strconv.Itoa(count + n)}})
+	return quota.Update()
+}
@@ -0,0 +1,24 @@
+package docker
+
+import (
+	"github.com/megamsys/vertice/provision"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestSnapImage(c *check.C) {
+	box := &provision.Box{CartonName: "BigBang", DomainName: "megambox.com"}
+	repo, tag := snapImage("https://registry.megam.io:5000", box, "SNP0123")
+	c.Assert(repo, check.Equals, "registry.megam.io:5000/bigbang.megambox.com")
+	c.Assert(tag, check.Equals, "snp0123")
+	repo, _ = snapImage("registry.megam.io/", box, "SNP0123")
+	c.Assert(repo, check.Equals, "registry.megam.io/bigbang.megambox.com")
+	repo, _ = snapImage("", box, "SNP0123")
+	c.Assert(repo, check.Equals, "bigbang.megambox.com")
+}
+
+func (s *S) TestBackupImage(c *check.C) {
+	box := &provision.Box{CartonName: "BigBang", DomainName: "megambox.com"}
+	repo, tag := backupImage(box, "BAK0123")
+	c.Assert(repo, check.Equals, "bigbang.megambox.com")
+	c.Assert(tag, check.Equals, "bk-bak0123")
+}
@@ -0,0 +1,504 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/fsouza/go-dockerclient"
+	"github.com/megamsys/libgo/action"
+	constants "github.com/megamsys/libgo/utils"
+	lb "github.com/megamsys/vertice/logbox"
+	"github.com/megamsys/vertice/provision"
+	"github.com/megamsys/vertice/provision/docker/container"
+	"github.com/megamsys/vertice/router"
+)
+
+// maxUnits is the most containers a box can be scaled to.
+const maxUnits = 32
+
+// A box runs in units, the containers labelled with its assembly id. Unit 0 is
+// the container the box is deployed in and is named after the box, the others
+// are named <carton>-<n>.<domain> and are routed on their own.
+
+func unitName(box *provision.Box, n int) string {
+	if n == 0 {
+		return box.GetFullName()
+	}
+	name := fmt.Sprintf("%s-%d", box.CartonName, n)
+	if len(strings.TrimSpace(box.DomainName)) > 0 {
+		name += "." + box.DomainName
+	}
+	return name
+}
+
+// unitIndex returns n of the unit named name, or -1 when it isn't a unit of box.
+func unitIndex(box *provision.Box, name string) int {
+	if name == box.GetFullName() {
+		return 0
+	}
+	if len(strings.TrimSpace(box.DomainName)) > 0 {
+		if !strings.HasSuffix(name, "."+box.DomainName) {
+			return -1
+		}
+		name = strings.TrimSuffix(name, "."+box.DomainName)
+	}
+	if !strings.HasPrefix(name, box.CartonName+"-") {
+		return -1
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(name, box.CartonName+"-"))
+	if err != nil || n < 1 {
+		return -1
+	}
+	return n
+}
+
+type unitsByIndex struct {
+	box   *provision.Box
+	units []container.Container
+}
+
+func (u unitsByIndex) Len() int      { return len(u.units) }
+func (u unitsByIndex) Swap(i, j int) { u.units[i], u.units[j] = u.units[j], u.units[i] }
+func (u unitsByIndex) Less(i, j int) bool {
+	return unitIndex(u.box, u.units[i].BoxName) < unitIndex(u.box, u.units[j].BoxName)
+}
+
+// listUnits returns the units of the box on every swarm node, unit 0 first.
+func (p *dockerProvisioner) listUnits(box *provision.Box) ([]container.Container, error) {
+	found, err := p.Cluster().InRegion(box.Region).ListContainers(docker.ListContainersOptions{
+		All:     true,
+		Filters: map[string][]string{"label": {constants.ASSEMBLY_ID + "=" + box.CartonId}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(found))
+	units := make([]container.Container, 0, len(found))
+	for _, a := range found {
+		if seen[a.ID] || len(a.Names) == 0 {
+			continue
+		}
+		seen[a.ID] = true
+		// swarm names the containers /<node>/<name>.
+		name := path.Base(a.Names[0])
+		if unitIndex(box, name) < 0 {
+			continue
+		}
+		c, _ := p.GetContainerByBox(box)
+		c.Id = a.ID
+		c.BoxName = name
+		c.Image = a.Image
+		c.PublicIp = unitIp(a)
+		units = append(units, *c)
+	}
+	sort.Sort(unitsByIndex{box: box, units: units})
+	return units, nil
+}
+
+func unitIp(a docker.APIContainers) string {
+	if n, ok := a.Networks.Networks["bridge"]; ok && n.IPAddress != "" {
+		return n.IPAddress
+	}
+	for _, n := range a.Networks.Networks {
+		if n.IPAddress != "" {
+			return n.IPAddress
+		}
+	}
+	return ""
+}
+
+// Scale adds or removes units of the box until it runs in units containers.
+// The units added run the image of the first unit, on the nodes swarm picks.
+func (p *dockerProvisioner) Scale(ctx context.Context, box *provision.Box, units int, w io.Writer) error {
+	p = p.inRegion(box)
+	if units < 1 || units > maxUnits {
+		return fmt.Errorf("box %s can run in 1 to %d units, not %d", box.GetFullName(), maxUnits, units)
+	}
+	current, err := p.listUnits(box)
+	if err != nil {
+		fmt.Fprintf(w, lb.W(lb.UPDATING, lb.ERROR, fmt.Sprintf("Failed to list box containers (%s) --> %s", box.GetFullName(), err)))
+		return err
+	}
+	if len(current) == 0 {
+		return fmt.Errorf("box %s has no containers to scale", box.GetFullName())
+	}
+	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- scaling box (%s) from %d to %d units", box.GetFullName(), len(current), units)))
+	switch {
+	case units > len(current):
+		info, err := p.Cluster().InspectContainer(current[0].Id)
+		if err != nil {
+			return err
+		}
+		return p.addUnits(ctx, box, current, info.Config.Image, units-len(current), w)
+	case units < len(current):
+		return p.removeUnits(box, current[units:], w)
+	}
+	return nil
+}
+
+// addUnits starts n more units of the box in the first free names.
+func (p *dockerProvisioner) addUnits(ctx context.Context, box *provision.Box, current []container.Container, imageId string, n int, w io.Writer) error {
+	taken := make(map[int]bool, len(current))
+	for _, c := range current {
+		taken[unitIndex(box, c.BoxName)] = true
+	}
+	for i := 1; n > 0; i++ {
+		if taken[i] {
+			continue
+		}
+		args := runContainerActionsArgs{
+			ctx:             ctx,
+			box:             box,
+			unit:            unitName(box, i),
+			imageId:         imageId,
+			writer:          w,
+			containerState:  constants.StateInitializing,
+			containerStatus: constants.StatusContainerLaunching,
+			provisioner:     p,
+		}
+		pipeline := action.NewPipeline(provision.TrackActions(box, unitActions())...)
+		if err := pipeline.Execute(args); err != nil {
+			fmt.Fprintf(w, lb.W(lb.UPDATING, lb.ERROR, fmt.Sprintf("--- add unit %s --> %s", args.unit, err)))
+			return err
+		}
+		n--
+	}
+	return nil
+}
+
+// redeployUnits deploys unit 0 of the box again from the image, then scales
+// the box back to n units. The old units must have been removed or renamed.
+func (p *dockerProvisioner) redeployUnits(ctx context.Context, box *provision.Box, imageId string, n int, w io.Writer) error {
+	if _, err := p.deployPipeline(ctx, box, imageId, w); err != nil {
+		return err
+	}
+	if n > 1 {
+		return p.Scale(ctx, box, n, w)
+	}
+	return nil
+}
+
+// replaceUnitsArgs are the params of the pipeline that replaces the units of
+// a box by ones of another image.
+type replaceUnitsArgs struct {
+	ctx         context.Context
+	box         *provision.Box
+	imageId     string
+	old         []container.Container
+	writer      io.Writer
+	provisioner *dockerProvisioner
+}
+
+// replaceUnits deploys the units of the box again from the image. The old
+// units are renamed aside and keep running till the new ones are up, the
+// router is then switched to the new units and the old ones are removed. When
+// the new units fail, the old ones get their names and routes back.
+func (p *dockerProvisioner) replaceUnits(ctx context.Context, box *provision.Box, imageId string, old []container.Container, w io.Writer) error {
+	args := replaceUnitsArgs{
+		ctx:         ctx,
+		box:         box,
+		imageId:     imageId,
+		old:         old,
+		writer:      w,
+		provisioner: p,
+	}
+	pipeline := action.NewPipeline(provision.TrackActions(box, replaceActions())...)
+	if err := pipeline.Execute(args); err != nil {
+		fmt.Fprintf(w, lb.W(lb.UPDATING, lb.ERROR, fmt.Sprintf("--- replace units of box (%s) --> %s", box.GetFullName(), err)))
+		return err
+	}
+	return nil
+}
+
+func replaceActions() []*action.Action {
+	return []*action.Action{
+		&renameOldUnits,
+		&unrouteOldUnits,
+		&deployNewUnits,
+		&removeOldUnits,
+	}
+}
+
+// asideName is the name an old unit runs under while it is replaced, it
+// isn't the name of a unit of the box.
+func asideName(c *container.Container) string {
+	return fmt.Sprintf("%s-old-%s", c.BoxName, c.ShortId())
+}
+
+var renameOldUnits = action.Action{
+	Name: "rename-old-units",
+	Forward: func(ctx action.FWContext) (action.Result, error) {
+		args := ctx.Params[0].(replaceUnitsArgs)
+		renamed := make([]container.Container, 0, len(args.old))
+		for _, c := range args.old {
+			err := args.provisioner.Cluster().RenameContainer(docker.RenameContainerOptions{ID: c.Id, Name: asideName(&c)})
+			if err != nil {
+				restoreNames(args, renamed)
+				return nil, err
+			}
+			renamed = append(renamed, c)
+		}
+		return renamed, nil
+	},
+	Backward: func(ctx action.BWContext) {
+		args := ctx.Params[0].(replaceUnitsArgs)
+		restoreNames(args, ctx.FWResult.([]container.Container))
+	},
+	MinParams: 1,
+}
+
+func restoreNames(args replaceUnitsArgs, renamed []container.Container) {
+	for _, c := range renamed {
+		err := args.provisioner.Cluster().RenameContainer(docker.RenameContainerOptions{ID: c.Id, Name: c.BoxName})
+		if err != nil {
+			log.Errorf("---- [rename-old-units:Backward] (%s, %s)\n    %s", c.BoxName, c.ShortId(), err.Error())
+		}
+	}
+}
+
+// unrouteOldUnits switches the router away from the old units, the new ones
+// take their names in it as they come up.
+var unrouteOldUnits = action.Action{
+	Name: "unroute-old-units",
+	Forward: func(ctx action.FWContext) (action.Result, error) {
+		args := ctx.Params[0].(replaceUnitsArgs)
+		r, err := getRouterForBox(args.box)
+		if err != nil {
+			return nil, err
+		}
+		unrouted := make([]container.Container, 0, len(args.old))
+		for _, c := range args.old {
+			if c.PublicIp == "" {
+				continue
+			}
+			if err = r.UnsetCName(c.BoxName, c.PublicIp); err != nil && err != router.ErrCNameNotFound {
+				log.Errorf("---- [unroute-old-units] (%s, %s)\n    %s", c.BoxName, c.PublicIp, err.Error())
+				continue
+			}
+			unrouted = append(unrouted, c)
+		}
+		return unrouted, nil
+	},
+	Backward: func(ctx action.BWContext) {
+		args := ctx.Params[0].(replaceUnitsArgs)
+		r, err := getRouterForBox(args.box)
+		if err != nil {
+			log.Errorf("---- [unroute-old-units:Backward]\n     %s", err.Error())
+			return
+		}
+		for _, c := range ctx.FWResult.([]container.Container) {
+			if err = r.SetCName(c.BoxName, c.PublicIp); err != nil {
+				log.Errorf("---- [unroute-old-units:Backward] (%s, %s)\n    %s", c.BoxName, c.PublicIp, err.Error())
+			}
+		}
+	},
+	MinParams: 1,
+}
+
+var deployNewUnits = action.Action{
+	Name: "deploy-new-units",
+	Forward: func(ctx action.FWContext) (action.Result, error) {
+		args := ctx.Params[0].(replaceUnitsArgs)
+		n := len(args.old)
+		if n < 1 {
+			n = 1
+		}
+		// a failed scale leaves the new units it started, the pipeline only
+		// rolls back the actions before this one.
+		if err := args.provisioner.redeployUnits(args.ctx, args.box, args.imageId, n, args.writer); err != nil {
+			removeNewUnits(args)
+			return nil, err
+		}
+		return nil, nil
+	},
+	Backward: func(ctx action.BWContext) {
+		removeNewUnits(ctx.Params[0].(replaceUnitsArgs))
+	},
+	MinParams: 1,
+}
+
+// removeNewUnits removes the units deployed in place of the old ones, which
+// run under other names meanwhile.
+func removeNewUnits(args replaceUnitsArgs) {
+	units, err := args.provisioner.listUnits(args.box)
+	if err != nil {
+		log.Errorf("---- [deploy-new-units:Backward]\n     %s", err.Error())
+		return
+	}
+	r, err := getRouterForBox(args.box)
+	if err != nil {
+		log.Errorf("---- [deploy-new-units:Backward]\n     %s", err.Error())
+		return
+	}
+	for i := range units {
+		c := &units[i]
+		if err = r.UnsetCName(c.BoxName, c.PublicIp); err != nil && err != router.ErrCNameNotFound {
+			log.Errorf("---- [deploy-new-units:Backward] (%s, %s)\n    %s", c.BoxName, c.PublicIp, err.Error())
+		}
+		if err = args.provisioner.removeUnit(c); err != nil {
+			log.Errorf("---- [deploy-new-units:Backward] (%s, %s)\n    %s", c.BoxName, c.ShortId(), err.Error())
+		}
+	}
+}
+
+var removeOldUnits = action.Action{
+	Name: "remove-old-units",
+	Forward: func(ctx action.FWContext) (action.Result, error) {
+		args := ctx.Params[0].(replaceUnitsArgs)
+		for i := range args.old {
+			c := &args.old[i]
+			// the new units serve the box already, an old one left over only
+			// takes room on its node.
+			if err := args.provisioner.removeUnit(c); err != nil {
+				log.Errorf("Ignored error trying to remove old unit %q: %s", c.Id, err)
+				continue
+			}
+			fmt.Fprintf(args.writer, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf(" ---> Removed old unit (%s, %s)", c.BoxName, c.ShortId())))
+		}
+		if err := args.provisioner.SetBoxStatus(args.box, args.writer, constants.StatusContainerRunning); err != nil {
+			log.Errorf("error on status of box %s - %s", args.box.GetFullName(), err)
+		}
+		return nil, nil
+	},
+	Backward: func(ctx action.BWContext) {
+	},
+	MinParams: 1,
+}
+
+// removeUnits takes the units out of the router, then removes them.
+func (p *dockerProvisioner) removeUnits(box *provision.Box, units []container.Container, w io.Writer) error {
+	r, err := getRouterForBox(box)
+	if err != nil {
+		return err
+	}
+	for i := range units {
+		c := &units[i]
+		if unitIndex(box, c.BoxName) > 0 {
+			if err = r.UnsetCName(c.BoxName, c.PublicIp); err != nil && err != router.ErrCNameNotFound {
+				return err
+			}
+		}
+		if err = p.removeUnit(c); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf(" ---> Removed unit (%s, %s)", c.BoxName, c.ShortId())))
+	}
+	return p.SetBoxStatus(box, w, constants.StatusContainerRunning)
+}
+
+// removeUnit stops and removes the container of a unit. Unlike Container.Remove
+// it leaves the state of the box alone, as the box keeps running.
+func (p *dockerProvisioner) removeUnit(c *container.Container) error {
+	if err := p.Cluster().StopContainer(c.Id, 10); err != nil {
+		log.Errorf("error on stop unit %s - %s", c.BoxName, err)
+	}
+	return p.Cluster().RemoveContainer(docker.RemoveContainerOptions{ID: c.Id, Force: true})
+}
+
+func unitActions() []*action.Action {
+	return []*action.Action{
+		&newUnit,
+		&createContainer,
+		&startContainer,
+		&setUnitAddress,
+		&addUnitRoute,
+		&setUnitStatus,
+	}
+}
+
+var newUnit = action.Action{
+	Name: "new-unit",
+	Forward: func(ctx action.FWContext) (action.Result, error) {
+		args := ctx.Params[0].(runContainerActionsArgs)
+		c, err := args.provisioner.GetContainerByBox(args.box)
+		if err != nil {
+			return nil, err
+		}
+		c.Id = ""
+		c.BoxName = args.unit
+		c.Image = args.imageId
+		c.State = args.containerState
+		c.Status = args.containerStatus
+		fmt.Fprintf(args.writer, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf(" adding unit %s (image:%s)", c.BoxName, c.Image)))
+		return *c, nil
+	},
+	Backward: func(ctx action.BWContext) {
+	},
+}
+
+var setUnitAddress = action.Action{
+	Name: "set-unit-address",
+	Forward: func(ctx action.FWContext) (action.Result, error) {
+		c := ctx.Previous.(container.Container)
+		args := ctx.Params[0].(runContainerActionsArgs)
+		info, err := args.provisioner.Cluster().InspectContainer(c.Id)
+		if err != nil {
+			return nil, err
+		}
+		if info.NetworkSettings != nil {
+			c.PublicIp = info.NetworkSettings.IPAddress
+		}
+		if info.Node != nil {
+			c.HostAddr = info.Node.IP
+		}
+		if c.PublicIp == "" {
+			return nil, fmt.Errorf("unit %s has no ip", c.BoxName)
+		}
+		return c, nil
+	},
+	Backward: func(ctx action.BWContext) {
+	},
+}
+
+var addUnitRoute = action.Action{
+	Name: "add-unit-route",
+	Forward: func(ctx action.FWContext) (action.Result, error) {
+		c := ctx.Previous.(container.Container)
+		args := ctx.Params[0].(runContainerActionsArgs)
+		r, err := getRouterForBox(args.box)
+		if err != nil {
+			return nil, err
+		}
+		if err = r.SetCName(c.BoxName, c.PublicIp); err != nil {
+			return nil, err
+		}
+		c.Routable = true
+		fmt.Fprintf(args.writer, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("---> Added route to unit (%s, %s)", c.BoxName, c.PublicIp)))
+		return c, nil
+	},
+	Backward: func(ctx action.BWContext) {
+		c := ctx.FWResult.(container.Container)
+		args := ctx.Params[0].(runContainerActionsArgs)
+		r, err := getRouterForBox(args.box)
+		if err != nil {
+			log.Errorf("---- [add-unit-route:Backward]\n     %s", err.Error())
+			return
+		}
+		if err = r.UnsetCName(c.BoxName, c.PublicIp); err != nil {
+			log.Errorf("---- [add-unit-route:Backward] (%s, %s)\n    %s", c.BoxName, c.PublicIp, err.Error())
+		}
+	},
+}
+
+var setUnitStatus = action.Action{
+	Name: "set-unit-status",
+	Forward: func(ctx action.FWContext) (action.Result, error) {
+		c := ctx.Previous.(container.Container)
+		args := ctx.Params[0].(runContainerActionsArgs)
+		fmt.Fprintf(args.writer, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("---> unit (%s, %s) running on %s", c.BoxName, c.ShortId(), c.HostAddr)))
+		if err := args.provisioner.SetBoxStatus(args.box, args.writer, constants.StatusContainerRunning); err != nil {
+			return nil, err
+		}
+		c.State = constants.StateRunning
+		c.Status = constants.StatusContainerRunning
+		return c, nil
+	},
+	Backward: func(ctx action.BWContext) {
+	},
+}
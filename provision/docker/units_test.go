@@ -0,0 +1,28 @@
+package docker
+
+import (
+	"github.com/megamsys/vertice/provision"
+	"github.com/megamsys/vertice/provision/docker/container"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestUnitNames(c *check.C) {
+	box := &provision.Box{CartonName: "bigbang", DomainName: "megambox.com"}
+	c.Assert(unitName(box, 0), check.Equals, "bigbang.megambox.com")
+	c.Assert(unitName(box, 3), check.Equals, "bigbang-3.megambox.com")
+	c.Assert(unitIndex(box, "bigbang.megambox.com"), check.Equals, 0)
+	c.Assert(unitIndex(box, "bigbang-3.megambox.com"), check.Equals, 3)
+	c.Assert(unitIndex(box, "bigbang-x.megambox.com"), check.Equals, -1)
+	c.Assert(unitIndex(box, "smallbang-1.megambox.com"), check.Equals, -1)
+	c.Assert(unitIndex(box, "bigbang-1.megam.io"), check.Equals, -1)
+}
+
+func (s *S) TestAsideNameIsNotAUnit(c *check.C) {
+	box := &provision.Box{CartonName: "bigbang", DomainName: "megambox.com"}
+	for _, name := range []string{"bigbang.megambox.com", "bigbang-3.megambox.com"} {
+		cont := &container.Container{Id: "4e5a0cf9a1b2c3d4", BoxName: name}
+		c.Assert(unitIndex(box, asideName(cont)), check.Equals, -1)
+	}
+	box.DomainName = ""
+	c.Assert(unitIndex(box, asideName(&container.Container{Id: "4e5a0cf9a1", BoxName: "bigbang-1"})), check.Equals, -1)
+}
@@ -0,0 +1,64 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fsouza/go-dockerclient"
+	lb "github.com/megamsys/vertice/logbox"
+	"github.com/megamsys/vertice/provision"
+)
+
+// Upgrade builds the image of the box from its repo at box.Commit. When
+// restart is set the units of the box are replaced by ones of the new image,
+// the old units are removed only once the new ones are up.
+func (p *dockerProvisioner) Upgrade(ctx context.Context, box *provision.Box, restart bool, w io.Writer) (string, error) {
+	p = p.inRegion(box)
+	if box.Repo == nil || box.Repo.Gitr() == "" {
+		return "", fmt.Errorf("box %s has no repo to upgrade from", box.GetFullName())
+	}
+	remote := box.Repo.Gitr()
+	if box.Commit != "" {
+		remote += "#" + box.Commit
+	}
+	imageId := upgradeImageName(box)
+	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- building image %s from %s", imageId, remote)))
+	err := p.Cluster().BuildImage(docker.BuildImageOptions{
+		Name:           imageId,
+		Remote:         remote,
+		RmTmpContainer: true,
+		OutputStream:   w,
+	})
+	if err != nil {
+		fmt.Fprintf(w, lb.W(lb.UPDATING, lb.ERROR, fmt.Sprintf("--- building image %s --> %s", imageId, err)))
+		return "", err
+	}
+	if !restart {
+		fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- built image %s, box (%s) runs it when deployed again", imageId, box.GetFullName())))
+		return imageId, nil
+	}
+
+	old, err := p.listContainersByBox(box)
+	if err != nil {
+		return "", err
+	}
+	if err = p.replaceUnits(ctx, box, imageId, old, w); err != nil {
+		return "", err
+	}
+	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- upgraded box (%s) to %s", box.GetFullName(), imageId)))
+	return imageId, nil
+}
+
+// upgradeImageName is the image built for the box at its commit.
+func upgradeImageName(box *provision.Box) string {
+	tag := "latest"
+	if box.Commit != "" {
+		tag = strings.Replace(box.Commit, "/", "-", -1)
+		if len(tag) > 12 {
+			tag = tag[:12]
+		}
+	}
+	return fmt.Sprintf("%s:%s", strings.ToLower(box.GetFullName()), tag)
+}
@@ -0,0 +1,128 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/fsouza/go-dockerclient"
+	constants "github.com/megamsys/libgo/utils"
+	"github.com/megamsys/vertice/carton"
+	lb "github.com/megamsys/vertice/logbox"
+	"github.com/megamsys/vertice/provision"
+	"github.com/megamsys/vertice/provision/docker/container"
+)
+
+// AttachDisk creates a volume for the disk of the box, in the node the other
+// volumes or the units of the box are in. A container can't mount a volume
+// once created, so a deployed box gets its units deployed again there with
+// the volume mounted.
+func (p *dockerProvisioner) AttachDisk(ctx context.Context, box *provision.Box, w io.Writer) error {
+	p = p.inRegion(box)
+	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- adding new storage to box (%s)", box.GetFullName())))
+	dsk, err := carton.GetDisks(box.CartonsId, box.AccountId)
+	if err != nil {
+		return err
+	}
+	node, err := p.volumeNode(box)
+	if err != nil {
+		return err
+	}
+	vol, err := p.Cluster().CreateVolume(docker.CreateVolumeOptions{
+		Name: container.VolumeName(box, dsk.Id),
+		Labels: map[string]string{
+			constants.ASSEMBLY_ID: box.CartonId,
+			container.DISK_ID:     dsk.Id,
+			container.DISK_SIZE:   dsk.NumMemory(),
+		},
+	}, node)
+	if err != nil {
+		fmt.Fprintf(w, lb.W(lb.UPDATING, lb.ERROR, fmt.Sprintf("--- adding new storage to box (%s)--> %s", box.GetFullName(), err)))
+		return err
+	}
+	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf(" ---> Created volume %s for %s", vol.Name, container.MountPath(dsk.Id))))
+	if err = p.remountUnits(ctx, box, w); err != nil {
+		fmt.Fprintf(w, lb.W(lb.UPDATING, lb.ERROR, fmt.Sprintf("--- adding new storage to box (%s)--> %s", box.GetFullName(), err)))
+		return err
+	}
+	dsk.DiskId = vol.Name
+	dsk.Size = dsk.NumMemory() + " MB"
+	dsk.Status = "success"
+	if err = dsk.UpdateDisk(); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- adding new storage to box (%s)OK", box.GetFullName())))
+	return nil
+}
+
+// DetachDisk deploys the units of the box again without the volume of the
+// disk, and removes the volume.
+func (p *dockerProvisioner) DetachDisk(ctx context.Context, box *provision.Box, w io.Writer) error {
+	p = p.inRegion(box)
+	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- removing storage from box (%s)", box.GetFullName())))
+	dsk, err := carton.GetDisks(box.CartonsId, box.AccountId)
+	if err != nil {
+		return err
+	}
+	name := dsk.DiskId
+	if name == "" {
+		name = container.VolumeName(box, dsk.Id)
+	}
+	// a volume in use can't be removed, it goes once the old units are gone.
+	unmount := *box
+	unmount.Unmounting = []string{dsk.Id}
+	if err = p.remountUnits(ctx, &unmount, w); err == nil {
+		err = p.Cluster().RemoveVolume(name)
+	}
+	if err != nil {
+		fmt.Fprintf(w, lb.W(lb.UPDATING, lb.ERROR, fmt.Sprintf("--- removing storage from box (%s)--> %s", box.GetFullName(), err)))
+		return err
+	}
+	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- removing storage from box (%s, %s)OK", box.GetFullName(), name)))
+	return nil
+}
+
+// remountUnits replaces the units of the box by ones of the image they run,
+// so they mount the volumes of the box as they are now. The old units are
+// removed once the new ones are up.
+func (p *dockerProvisioner) remountUnits(ctx context.Context, box *provision.Box, w io.Writer) error {
+	units, err := p.listUnits(box)
+	if err != nil || len(units) == 0 {
+		return err
+	}
+	return p.replaceUnits(ctx, box, units[0].Image, units, w)
+}
+
+// volumeNode is the node a new volume of the box goes in, the one of its
+// volumes or else of its unit 0. It's blank for a box that has neither.
+func (p *dockerProvisioner) volumeNode(box *provision.Box) (string, error) {
+	vols, err := container.Volumes(p.Cluster(), box)
+	if err != nil {
+		return "", err
+	}
+	if node := container.VolumeNode(vols); node != "" {
+		return node, nil
+	}
+	units, err := p.listUnits(box)
+	if err != nil || len(units) == 0 {
+		return "", err
+	}
+	return p.Cluster().ContainerNode(units[0].Id)
+}
+
+// removeVolumes removes the volumes of the disks of a destroyed box.
+func (p *dockerProvisioner) removeVolumes(box *provision.Box, w io.Writer) error {
+	vols, err := container.Volumes(p.Cluster(), box)
+	if err != nil {
+		log.Errorf("error on listing the volumes of %s - %s", box.GetFullName(), err)
+		return err
+	}
+	for _, v := range vols {
+		if err = p.Cluster().RemoveVolume(v.Name); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, lb.W(lb.DESTORYING, lb.INFO, fmt.Sprintf(" ---> Removed volume %s", v.Name)))
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package docker
+
+import (
+	"github.com/fsouza/go-dockerclient"
+	"github.com/megamsys/vertice/provision"
+	"github.com/megamsys/vertice/provision/docker/cluster"
+	"github.com/megamsys/vertice/provision/docker/container"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestVolumeNames(c *check.C) {
+	box := &provision.Box{CartonName: "BigBang", DomainName: "megambox.com"}
+	c.Assert(container.VolumeName(box, "DSK0123"), check.Equals, "bigbang.megambox.com-dsk0123")
+	c.Assert(container.MountPath("DSK0123"), check.Equals, "/mnt/dsk0123")
+}
+
+func (s *S) TestPlanDiskOps(c *check.C) {
+	box := &provision.Box{CartonName: "bigbang"}
+	names, err := (&dockerProvisioner{}).PlanActions(box, provision.CYCLE_DISK_DETACH)
+	c.Assert(err, check.IsNil)
+	c.Assert(names[len(names)-2:], check.DeepEquals, []string{"remove-old-units", "Cluster.RemoveVolume"})
+}
+
+func (s *S) TestVolumeNode(c *check.C) {
+	vols := []docker.Volume{
+		{Name: "bigbang-dsk01", Labels: map[string]string{container.DISK_ID: "DSK01", cluster.VOLUME_NODE: "http://192.168.1.10:2375"}},
+		{Name: "bigbang-dsk02", Labels: map[string]string{container.DISK_ID: "DSK02"}},
+	}
+	c.Assert(container.VolumeNode(vols), check.Equals, "http://192.168.1.10:2375")
+	c.Assert(container.VolumeNode(vols[1:]), check.Equals, "")
+}
@@ -0,0 +1,132 @@
+/*
+** Copyright [2013-2017] [Megam Systems]
+**
+** Licensed under the Apache License, Version 2.0 (the "License");
+** you may not use this file except in compliance with the License.
+** You may obtain a copy of the License at
+**
+** http://www.apache.org/licenses/LICENSE-2.0
+**
+** Unless required by applicable law or agreed to in writing, software
+** distributed under the License is distributed on an "AS IS" BASIS,
+** WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+** See the License for the specific language governing permissions and
+** limitations under the License.
+ */
+package provision
+
+import (
+	"fmt"
+
+	"github.com/megamsys/libgo/utils"
+)
+
+// BoxOp is an operation that moves a box from one lifecycle state to another.
+type BoxOp string
+
+const (
+	CYCLE_DEPLOY        BoxOp = "deploy"
+	CYCLE_DESTROY       BoxOp = "destroy"
+	CYCLE_START         BoxOp = "start"
+	CYCLE_STOP          BoxOp = "stop"
+	CYCLE_RESTART       BoxOp = "restart"
+	CYCLE_SUSPEND       BoxOp = "suspend"
+	CYCLE_STATEUP       BoxOp = "stateup"
+	CYCLE_RUNNING       BoxOp = "running"
+	CYCLE_UPGRADE       BoxOp = "upgrade"
+	CYCLE_ROLLBACK      BoxOp = "rollback"
+	CYCLE_SNAP_CREATE   BoxOp = "snapshot create"
+	CYCLE_SNAP_RESTORE  BoxOp = "snapshot restore"
+	CYCLE_SNAP_DELETE   BoxOp = "snapshot delete"
+	CYCLE_BACKUP_CREATE BoxOp = "backup create"
+	CYCLE_BACKUP_DELETE BoxOp = "backup delete"
+	CYCLE_DISK_ATTACH   BoxOp = "disk attach"
+	CYCLE_DISK_DETACH   BoxOp = "disk detach"
+	CYCLE_NETWORK       BoxOp = "network update"
+	CYCLE_RESIZE        BoxOp = "resize"
+	CYCLE_SCALE         BoxOp = "scale"
+	CYCLE_MIGRATE       BoxOp = "migrate"
+	CYCLE_SNAP_CLONE    BoxOp = "snapshot clone"
+)
+
+// the ops that work on the disks of a box, and can run whether its up or down.
+var diskOps = []BoxOp{
+	CYCLE_SNAP_CREATE, CYCLE_SNAP_RESTORE, CYCLE_SNAP_DELETE,
+	CYCLE_BACKUP_CREATE, CYCLE_BACKUP_DELETE,
+	CYCLE_DISK_ATTACH, CYCLE_DISK_DETACH,
+}
+
+func ops(o ...[]BoxOp) []BoxOp {
+	all := []BoxOp{}
+	for _, l := range o {
+		all = append(all, l...)
+	}
+	return all
+}
+
+// lifecycle has the ops allowed on a box in each of its states. A box whose
+// state isn't here (a blank or a newer state) can't be worked on.
+var lifecycle = map[utils.State][]BoxOp{
+	utils.StateInitializing:  {CYCLE_DEPLOY, CYCLE_SNAP_CLONE, CYCLE_DESTROY, CYCLE_STATEUP, CYCLE_RUNNING, CYCLE_NETWORK},
+	utils.StateInitialized:   {CYCLE_DEPLOY, CYCLE_SNAP_CLONE, CYCLE_DESTROY, CYCLE_STATEUP, CYCLE_RUNNING, CYCLE_NETWORK},
+	utils.StatePreError:      {CYCLE_DEPLOY, CYCLE_SNAP_CLONE, CYCLE_DESTROY},
+	utils.StateBootstrapped:  {CYCLE_DESTROY, CYCLE_STATEUP, CYCLE_RUNNING, CYCLE_NETWORK, CYCLE_UPGRADE},
+	utils.StateMachineParked: {CYCLE_DESTROY, CYCLE_STOP, CYCLE_SUSPEND},
+	utils.StateRunning: ops([]BoxOp{CYCLE_DESTROY, CYCLE_STOP, CYCLE_RESTART, CYCLE_SUSPEND,
+		CYCLE_STATEUP, CYCLE_RUNNING, CYCLE_UPGRADE, CYCLE_ROLLBACK, CYCLE_NETWORK, CYCLE_RESIZE, CYCLE_SCALE, CYCLE_MIGRATE}, diskOps),
+	utils.StatePostError: ops([]BoxOp{CYCLE_DESTROY, CYCLE_STOP, CYCLE_RESTART, CYCLE_SUSPEND,
+		CYCLE_STATEUP, CYCLE_RUNNING, CYCLE_UPGRADE, CYCLE_ROLLBACK, CYCLE_NETWORK}, diskOps),
+	utils.StateStopped:    ops([]BoxOp{CYCLE_DESTROY, CYCLE_START, CYCLE_ROLLBACK, CYCLE_RESIZE, CYCLE_MIGRATE}, diskOps),
+	utils.StateDestroying: {CYCLE_DESTROY, CYCLE_SNAP_DELETE, CYCLE_BACKUP_DELETE},
+	utils.StateDestroyed:  {CYCLE_DESTROY, CYCLE_SNAP_DELETE, CYCLE_BACKUP_DELETE},
+}
+
+// statusLimits narrows the ops allowed by the state of a box while its status
+// is one of these. A status that isn't here, like the ones the agents on the
+// boxes report, puts no extra limit and leaves the state to decide.
+var statusLimits = map[utils.Status][]BoxOp{
+	utils.StatusDestroying:       {CYCLE_DESTROY, CYCLE_SNAP_DELETE, CYCLE_BACKUP_DELETE},
+	utils.StatusDestroyed:        {CYCLE_DESTROY, CYCLE_SNAP_DELETE, CYCLE_BACKUP_DELETE},
+	utils.StatusInsufficientFund: {CYCLE_DESTROY, CYCLE_STOP, CYCLE_SUSPEND},
+}
+
+// LifecycleError is returned when an op isn't allowed on a box in its current
+// state.
+type LifecycleError struct {
+	Box    string
+	Op     BoxOp
+	State  utils.State
+	Status utils.Status
+}
+
+func (e *LifecycleError) Error() string {
+	return fmt.Sprintf("%s not allowed on %s in state %s (%s)", e.Op, e.Box, e.State, e.Status)
+}
+
+// IsLifecycleError tells if err is a disallowed lifecycle op.
+func IsLifecycleError(err error) bool {
+	_, ok := err.(*LifecycleError)
+	return ok
+}
+
+func allows(list []BoxOp, op BoxOp) bool {
+	for _, o := range list {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+// Can returns a LifecycleError when op isn't allowed on the box, or when the
+// state of the box isn't known.
+func (b *Box) Can(op BoxOp) error {
+	allowed, known := lifecycle[b.State]
+	if !known || !allows(allowed, op) {
+		return &LifecycleError{Box: b.GetFullName(), Op: op, State: b.State, Status: b.Status}
+	}
+	if limit, ok := statusLimits[b.Status]; ok && !allows(limit, op) {
+		return &LifecycleError{Box: b.GetFullName(), Op: op, State: b.State, Status: b.Status}
+	}
+	return nil
+}
@@ -16,6 +16,7 @@
 package one
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -34,7 +35,14 @@ import (
 	"github.com/megamsys/vertice/provision/one/machine"
 )
 
+// libgo has no statuses for migrations.
+const (
+	statusMigrating = constants.Status("migrating")
+	statusMigrated  = constants.Status("migrated")
+)
+
 type runMachineActionsArgs struct {
+	ctx           context.Context
 	box           *provision.Box
 	writer        io.Writer
 	imageId       string
@@ -43,6 +51,9 @@ type runMachineActionsArgs struct {
 	machineState  utils.State
 	provisioner   *oneProvisioner
 	process       string
+	resizeTo      provision.BoxCompute
+	migrateTo     provision.MigrateTo
+	cloneFrom     provision.CloneSource
 }
 
 //If there is a previous machine created and it has a status, we use that.
@@ -190,7 +201,7 @@ var getVmHostIpPort = action.Action{
 		if writer == nil {
 			writer = ioutil.Discard
 		}
-		err := mach.VmHostIpPort(&machine.CreateArgs{Provisioner: args.provisioner})
+		err := mach.VmHostIpPort(args.ctx, &machine.CreateArgs{Provisioner: args.provisioner})
 		if err != nil {
 			return nil, err
 		}
@@ -260,6 +271,10 @@ var destroyOldMachine = action.Action{
 			return nil, err
 		}
 
+		// the vm is gone, a clone leaves the image it booted from behind.
+		if err = mach.RemoveCloneImage(args.provisioner); err != nil {
+			fmt.Fprintf(writer, lb.W(lb.DESTORYING, lb.ERROR, fmt.Sprintf("  removing clone image of machine %s : %s", mach.Name, err)))
+		}
 		fmt.Fprintf(writer, lb.W(lb.DESTORYING, lb.INFO, fmt.Sprintf("  destroyed old machine (%s, %s) OK", mach.Id, mach.Name)))
 		return ctx.Previous, nil
 	},
@@ -286,7 +301,7 @@ var startMachine = action.Action{
 			fmt.Fprintf(writer, lb.W(lb.STARTING, lb.ERROR, fmt.Sprintf("  error start machine ( %s)", args.box.GetFullName())))
 			return nil, err
 		}
-		err = mach.WaitUntillVMState(args.provisioner, vm.ACTIVE, vm.RUNNING)
+		err = mach.WaitUntillVMState(args.ctx, args.provisioner, vm.ACTIVE, vm.RUNNING)
 		if err != nil {
 			fmt.Fprintf(writer, lb.W(lb.STARTING, lb.ERROR, fmt.Sprintf("  error start machine ( %s)", args.box.GetFullName())))
 			return nil, err
@@ -321,7 +336,7 @@ var stopMachine = action.Action{
 			fmt.Fprintf(writer, lb.W(lb.STOPPING, lb.ERROR, fmt.Sprintf("  error stop machine ( %s)", args.box.GetFullName())))
 			return nil, err
 		}
-		err = mach.WaitUntillVMState(args.provisioner, vm.POWEROFF, vm.LCM_INIT)
+		err = mach.WaitUntillVMState(args.ctx, args.provisioner, vm.POWEROFF, vm.LCM_INIT)
 		if err != nil {
 			fmt.Fprintf(writer, lb.W(lb.STOPPING, lb.ERROR, fmt.Sprintf("  error stop machine ( %s)", args.box.GetFullName())))
 			return nil, err
@@ -382,7 +397,7 @@ var suspendMachine = action.Action{
 			fmt.Fprintf(writer, lb.W(lb.STOPPING, lb.ERROR, fmt.Sprintf("  error suspend machine ( %s)", args.box.GetFullName())))
 			return nil, err
 		}
-		err = mach.WaitUntillVMState(args.provisioner, vm.POWEROFF, vm.LCM_INIT)
+		err = mach.WaitUntillVMState(args.ctx, args.provisioner, vm.POWEROFF, vm.LCM_INIT)
 		if err != nil {
 			fmt.Fprintf(writer, lb.W(lb.STOPPING, lb.ERROR, fmt.Sprintf("  error suspend machine ( %s)", args.box.GetFullName())))
 			return nil, err
@@ -628,7 +643,7 @@ var restoreVirtualMachine = action.Action{
 		if err := mach.RestoreSnapshot(args.provisioner); err != nil {
 			return nil, err
 		}
-		err := mach.WaitUntillVMState(args.provisioner, vm.POWEROFF, vm.LCM_INIT)
+		err := mach.WaitUntillVMState(args.ctx, args.provisioner, vm.POWEROFF, vm.LCM_INIT)
 		if err != nil {
 			fmt.Fprintf(writer, lb.W(lb.STARTING, lb.ERROR, fmt.Sprintf("  error start machine ( %s)", args.box.GetFullName())))
 			return nil, err
@@ -1031,7 +1046,7 @@ var waitUntillSnapReady = action.Action{
 		args := ctx.Params[0].(runMachineActionsArgs)
 		writer := args.writer
 		fmt.Fprintf(writer, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf(" waiting to backups creating for machine (%s, %s)", args.box.GetFullName(), constants.SNAPSHOTTING)))
-		if err := mach.IsSnapReady(args.provisioner); err != nil {
+		if err := mach.IsSnapReady(args.ctx, args.provisioner); err != nil {
 			return nil, err
 		}
 		fmt.Fprintf(writer, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf(" waiting to backups creating  for machine (%s, %s)OK", args.box.GetFullName(), constants.SNAPSHOTTING)))
@@ -1253,3 +1268,171 @@ var removeNetworkIps = action.Action{
 	Backward: func(ctx action.BWContext) {
 	},
 }
+
+var resizeMachine = action.Action{
+	Name: "resize-machine",
+	Forward: func(ctx action.FWContext) (action.Result, error) {
+		mach := ctx.Previous.(machine.Machine)
+		args := ctx.Params[0].(runMachineActionsArgs)
+		writer := args.writer
+		if writer == nil {
+			writer = ioutil.Discard
+		}
+		fmt.Fprintf(writer, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("  resizing machine %s to %s", mach.Name, args.resizeTo.String())))
+		if err := mach.Resize(args.provisioner, args.box.Compute, args.resizeTo); err != nil {
+			fmt.Fprintf(writer, lb.W(lb.UPDATING, lb.ERROR, fmt.Sprintf("  error resize machine ( %s) %s", args.box.GetFullName(), err)))
+			return nil, err
+		}
+		fmt.Fprintf(writer, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("  resizing machine (%s, %s) OK", mach.Id, mach.Name)))
+		return mach, nil
+	},
+	Backward: func(ctx action.BWContext) {
+	},
+	OnError:   rollbackNotice,
+	MinParams: 1,
+}
+
+var recontextualizeMachine = action.Action{
+	Name: "recontextualize-machine",
+	Forward: func(ctx action.FWContext) (action.Result, error) {
+		mach := ctx.Previous.(machine.Machine)
+		args := ctx.Params[0].(runMachineActionsArgs)
+		writer := args.writer
+		if writer == nil {
+			writer = ioutil.Discard
+		}
+		fmt.Fprintf(writer, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("  recontextualizing machine %s to %s", mach.Name, args.box.Commit)))
+		if err := mach.Recontextualize(args.provisioner, args.box); err != nil {
+			fmt.Fprintf(writer, lb.W(lb.UPDATING, lb.ERROR, fmt.Sprintf("  error recontextualize machine ( %s) %s", args.box.GetFullName(), err)))
+			return nil, err
+		}
+		fmt.Fprintf(writer, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("  recontextualizing machine (%s, %s) OK", mach.Id, mach.Name)))
+		return mach, nil
+	},
+	Backward: func(ctx action.BWContext) {
+	},
+	OnError:   rollbackNotice,
+	MinParams: 1,
+}
+
+var migrateMachine = action.Action{
+	Name: "migrate-machine",
+	Forward: func(ctx action.FWContext) (action.Result, error) {
+		mach := ctx.Previous.(machine.Machine)
+		args := ctx.Params[0].(runMachineActionsArgs)
+		writer := args.writer
+		if writer == nil {
+			writer = ioutil.Discard
+		}
+		fmt.Fprintf(writer, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("  migrating machine %s to %s", mach.Name, args.migrateTo.String())))
+		if err := mach.Migrate(args.provisioner, args.migrateTo, args.box.Vnets); err != nil {
+			fmt.Fprintf(writer, lb.W(lb.UPDATING, lb.ERROR, fmt.Sprintf("  error migrate machine ( %s) %s", args.box.GetFullName(), err)))
+			return nil, err
+		}
+		// the vm is back in the state it was once one has moved it.
+		var err error
+		if args.machineState == constants.StateRunning {
+			err = mach.WaitUntillVMState(args.ctx, args.provisioner, vm.ACTIVE, vm.RUNNING)
+		} else {
+			err = mach.WaitUntillVMState(args.ctx, args.provisioner, vm.POWEROFF, vm.LCM_INIT)
+		}
+		if err != nil {
+			fmt.Fprintf(writer, lb.W(lb.UPDATING, lb.ERROR, fmt.Sprintf("  error migrate machine ( %s) %s", args.box.GetFullName(), err)))
+			return nil, err
+		}
+		mach.Status = statusMigrated
+		mach.State = args.machineState
+		fmt.Fprintf(writer, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("  migrating machine (%s, %s) OK", mach.Id, mach.Name)))
+		return mach, nil
+	},
+	Backward: func(ctx action.BWContext) {
+	},
+	OnError:   rollbackNotice,
+	MinParams: 1,
+}
+
+var cloneImage = action.Action{
+	Name: "clone-image",
+	Forward: func(ctx action.FWContext) (action.Result, error) {
+		mach := ctx.Previous.(machine.Machine)
+		args := ctx.Params[0].(runMachineActionsArgs)
+		writer := args.writer
+		if writer == nil {
+			writer = ioutil.Discard
+		}
+		fmt.Fprintf(writer, lb.W(lb.DEPLOY, lb.INFO, fmt.Sprintf("  creating image of %s for machine %s", args.cloneFrom.String(), mach.Name)))
+		if err := mach.CloneImage(args.provisioner, args.cloneFrom); err != nil {
+			fmt.Fprintf(writer, lb.W(lb.DEPLOY, lb.ERROR, fmt.Sprintf("  error creating image of %s (%s) %s", args.cloneFrom.String(), args.box.GetFullName(), err)))
+			return nil, err
+		}
+		fmt.Fprintf(writer, lb.W(lb.DEPLOY, lb.INFO, fmt.Sprintf("  creating image of %s for machine %s (%s) OK", args.cloneFrom.String(), mach.Name, mach.Image)))
+		return mach, nil
+	},
+	Backward: func(ctx action.BWContext) {
+		c := ctx.FWResult.(machine.Machine)
+		args := ctx.Params[0].(runMachineActionsArgs)
+		if c.CloneImageId == "" {
+			return
+		}
+		if err := c.RemoveCloneImage(args.provisioner); err != nil {
+			fmt.Fprintf(args.writer, lb.W(lb.DESTORYING, lb.ERROR, fmt.Sprintf("  removing err clone image %s", err.Error())))
+		}
+	},
+	OnError:   rollbackNotice,
+	MinParams: 1,
+}
+
+var createCloneMachine = action.Action{
+	Name: "create-clone-machine",
+	Forward: func(ctx action.FWContext) (action.Result, error) {
+		mach := ctx.Previous.(machine.Machine)
+		args := ctx.Params[0].(runMachineActionsArgs)
+		writer := args.writer
+		if writer == nil {
+			writer = ioutil.Discard
+		}
+		fmt.Fprintf(writer, lb.W(lb.DEPLOY, lb.INFO, fmt.Sprintf(" create machine for box (%s, image:%s)/%s", args.box.GetFullName(), mach.Image, args.box.Compute)))
+		err := mach.CreateClone(&machine.CreateArgs{
+			Box:         args.box,
+			Compute:     args.box.Compute,
+			Deploy:      true,
+			Provisioner: args.provisioner,
+		})
+		if err != nil {
+			return nil, err
+		}
+		mach.State = constants.StateInitialized
+		fmt.Fprintf(writer, lb.W(lb.DEPLOY, lb.INFO, fmt.Sprintf(" create machine for box (%s, image:%s)/%s OK", args.box.GetFullName(), mach.Image, args.box.Compute)))
+		return mach, nil
+	},
+	Backward: func(ctx action.BWContext) {
+		c := ctx.FWResult.(machine.Machine)
+		args := ctx.Params[0].(runMachineActionsArgs)
+		if err := c.Remove(args.provisioner); err != nil {
+			fmt.Fprintf(args.writer, lb.W(lb.DESTORYING, lb.ERROR, fmt.Sprintf("  removing err machine %s", err.Error())))
+		}
+	},
+	OnError:   rollbackNotice,
+	MinParams: 1,
+}
+
+// updateCloneIps sets the ips the clone got in its outputs, and its public ip
+// on the box to route it.
+var updateCloneIps = action.Action{
+	Name: "update-clone-ips",
+	Forward: func(ctx action.FWContext) (action.Result, error) {
+		mach := ctx.Previous.(machine.Machine)
+		args := ctx.Params[0].(runMachineActionsArgs)
+		if err := mach.UpdateVMIps(args.provisioner); err != nil {
+			return nil, err
+		}
+		ip, err := mach.PublicIp(args.provisioner)
+		if err != nil {
+			return nil, err
+		}
+		args.box.PublicIp = ip
+		return mach, nil
+	},
+	Backward: func(ctx action.BWContext) {
+	},
+}
@@ -95,14 +95,52 @@ func New(storage Storage, nodes ...Node) (*Cluster, error) {
 	if len(nodes) > 0 {
 		for _, n := range nodes {
 			err = c.Register(n)
+			if err == ErrDuplicatedNodeAddress {
+				err = c.reconfigure(n)
+			}
 			if err != nil {
 				return &c, err
 			}
 		}
+		err = c.prune(nodes)
 	}
 	return &c, err
 }
 
+// reconfigure stores the node of the region as configured now, keeping its
+// health.
+func (c *Cluster) reconfigure(node Node) error {
+	stored, err := c.storage().RetrieveNode(node.Region)
+	if err != nil {
+		return err
+	}
+	node.keepHealth(stored)
+	return c.storage().UpdateNode(node)
+}
+
+// prune drops the regions taken out of the config since the storage was
+// written.
+func (c *Cluster) prune(nodes []Node) error {
+	keep := make(map[string]bool, len(nodes))
+	for _, n := range nodes {
+		keep[n.Region] = true
+	}
+	stored, err := c.storage().RetrieveNodes()
+	if err != nil {
+		return err
+	}
+	var gone []string
+	for _, n := range stored {
+		if !keep[n.Region] {
+			gone = append(gone, n.Region)
+		}
+	}
+	if len(gone) == 0 {
+		return nil
+	}
+	return c.storage().RemoveNodes(gone)
+}
+
 // Register adds new nodes to the cluster.
 func (c *Cluster) Register(node Node) error {
 	if node.Region == "" {
@@ -0,0 +1,92 @@
+package cluster
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	VM_UPDATECONF = "one.vm.updateconf"
+
+	// the context of a vm deployed from git, its contextualization builds
+	// the repo at the commit.
+	CONTEXT_GIT_URL    = "GIT_URL"
+	CONTEXT_GIT_COMMIT = "GIT_COMMIT"
+)
+
+type contextVar struct {
+	XMLName xml.Name
+	Value   string `xml:",chardata"`
+}
+
+type vmContext struct {
+	Context struct {
+		Vars []contextVar `xml:",any"`
+	} `xml:"TEMPLATE>CONTEXT"`
+}
+
+// UpdateVMContext sets the vars in the context of the powered off vm, its
+// contextualization runs with them when it is started again.
+func (c *Cluster) UpdateVMContext(vmid, region string, vars map[string]string) error {
+	id, err := strconv.Atoi(vmid)
+	if err != nil {
+		return fmt.Errorf("invalid vm id %q", vmid)
+	}
+	node, err := c.getNodeRegion(region)
+	if err != nil {
+		return err
+	}
+	defer node.Client.Client.Close()
+
+	res, err := node.Client.Call(VM_INFO, []interface{}{node.Client.Key, id})
+	if err != nil {
+		return wrapErrorWithCmd(node, err, "UpdateVMContext")
+	}
+	body, ok := res[1].(string)
+	if !ok {
+		return fmt.Errorf("unexpected info of vm %s : %v", vmid, res[1])
+	}
+	ctx := &vmContext{}
+	if err = xml.Unmarshal([]byte(body), ctx); err != nil {
+		return err
+	}
+	_, err = node.Client.Call(VM_UPDATECONF, []interface{}{node.Client.Key, id, contextTemplate(ctx.Context.Vars, vars)})
+	if err != nil {
+		return wrapErrorWithCmd(node, err, "UpdateVMContext")
+	}
+	return nil
+}
+
+// contextTemplate is the CONTEXT section of a vm template with the vars it
+// has, the ones of set replacing them or added after them.
+func contextTemplate(has []contextVar, set map[string]string) string {
+	var lines []string
+	seen := make(map[string]bool, len(set))
+	for _, v := range has {
+		name, value := v.XMLName.Local, v.Value
+		if s, ok := set[name]; ok {
+			value = s
+			seen[name] = true
+		}
+		lines = append(lines, contextLine(name, value))
+	}
+	var added []string
+	for name := range set {
+		if !seen[name] {
+			added = append(added, name)
+		}
+	}
+	sort.Strings(added)
+	for _, name := range added {
+		lines = append(lines, contextLine(name, set[name]))
+	}
+	return "CONTEXT=[\n" + strings.Join(lines, ",\n") + " ]"
+}
+
+func contextLine(name, value string) string {
+	value = strings.Replace(value, `\`, `\\`, -1)
+	return fmt.Sprintf(`  %s="%s"`, name, strings.Replace(value, `"`, `\"`, -1))
+}
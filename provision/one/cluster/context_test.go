@@ -0,0 +1,26 @@
+package cluster
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func TestContextTemplate(t *testing.T) {
+	body := `<VM><ID>42</ID><TEMPLATE><CONTEXT><NETWORK><![CDATA[YES]]></NETWORK><GIT_COMMIT><![CDATA[3a1f]]></GIT_COMMIT><SSH_PUBLIC_KEY><![CDATA[ssh-rsa AAAA "tom"]]></SSH_PUBLIC_KEY></CONTEXT></TEMPLATE></VM>`
+	ctx := &vmContext{}
+	if err := xml.Unmarshal([]byte(body), ctx); err != nil {
+		t.Fatal(err)
+	}
+	got := contextTemplate(ctx.Context.Vars, map[string]string{
+		CONTEXT_GIT_COMMIT: "9c2e",
+		CONTEXT_GIT_URL:    "https://github.com/megamsys/java-spring-petclinic.git",
+	})
+	want := "CONTEXT=[\n" +
+		`  NETWORK="YES",` + "\n" +
+		`  GIT_COMMIT="9c2e",` + "\n" +
+		`  SSH_PUBLIC_KEY="ssh-rsa AAAA \"tom\"",` + "\n" +
+		`  GIT_URL="https://github.com/megamsys/java-spring-petclinic.git" ]`
+	if got != want {
+		t.Errorf("contextTemplate:\n%s\nwant\n%s", got, want)
+	}
+}
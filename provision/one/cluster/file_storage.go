@@ -0,0 +1,101 @@
+package cluster
+
+import (
+	"encoding/gob"
+	"sync"
+	"time"
+
+	"github.com/megamsys/vertice/provision"
+)
+
+// FileStorage is a MapStorage kept in a file, so that vertice remembers the
+// health of the nodes after a restart. Every change is written to the file.
+type FileStorage struct {
+	MapStorage
+	path string
+	fMut sync.Mutex
+}
+
+type fileState struct {
+	Nodes []Node
+}
+
+// NewFileStorage loads the storage kept in path, an empty one when there is
+// no such file yet.
+func NewFileStorage(path string) (*FileStorage, error) {
+	s := &FileStorage{path: path}
+	var st fileState
+	ok, err := provision.LoadGob(path, &st)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return s, nil
+	}
+	s.nodes = st.Nodes
+	s.updateNodeMap()
+	return s, nil
+}
+
+// save writes the whole storage to its file.
+func (s *FileStorage) save() error {
+	s.fMut.Lock()
+	defer s.fMut.Unlock()
+	return provision.SaveGob(s.path, s.encode)
+}
+
+func (s *FileStorage) encode(enc *gob.Encoder) error {
+	s.nMut.Lock()
+	defer s.nMut.Unlock()
+	return enc.Encode(fileState{Nodes: s.nodes})
+}
+
+func (s *FileStorage) StoreNode(node Node) error {
+	if err := s.MapStorage.StoreNode(node); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+func (s *FileStorage) UpdateNode(node Node) error {
+	if err := s.MapStorage.UpdateNode(node); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+func (s *FileStorage) RemoveNode(region string) error {
+	if err := s.MapStorage.RemoveNode(region); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+func (s *FileStorage) RemoveNodes(regions []string) error {
+	if err := s.MapStorage.RemoveNodes(regions); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+func (s *FileStorage) LockNodeForHealing(region string, isFailure bool, timeout time.Duration) (bool, error) {
+	locked, err := s.MapStorage.LockNodeForHealing(region, isFailure, timeout)
+	if err != nil || !locked {
+		return locked, err
+	}
+	return true, s.save()
+}
+
+func (s *FileStorage) ExtendNodeLock(region string, timeout time.Duration) error {
+	if err := s.MapStorage.ExtendNodeLock(region, timeout); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+func (s *FileStorage) UnlockNode(region string) error {
+	if err := s.MapStorage.UnlockNode(region); err != nil {
+		return err
+	}
+	return s.save()
+}
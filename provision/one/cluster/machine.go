@@ -20,6 +20,7 @@ import (
 	"github.com/megamsys/vertice/provision"
 	"net"
 	"net/url"
+	"path"
 	"strconv"
 	"strings"
 	"time"
@@ -27,6 +28,16 @@ import (
 
 var ErrConnRefused = errors.New("connection refused")
 
+const (
+	// the xml-rpc calls of one the client has no method for.
+	VM_RESIZE      = "one.vm.resize"
+	VM_DISK_RESIZE = "one.vm.diskresize"
+
+	// IMAGE_STORE is the url the images of the datastore of a region are
+	// served from, to the regions that create images of their own from them.
+	IMAGE_STORE = "image_store"
+)
+
 func (c *Cluster) newVM(opts compute.VirtualMachine, throttle, storage string) (compute.VirtualMachine, string, error) {
 	var addr string
 	nodlist, err := c.Nodes()
@@ -142,6 +153,42 @@ func (c *Cluster) GetVM(opts virtualmachine.Vnc, region string) (*virtualmachine
 	return res, err
 }
 
+type vmGraphics struct {
+	Passwd string `xml:"TEMPLATE>GRAPHICS>PASSWD"`
+}
+
+// GetVMVNC returns the vm like GetVM does, with the password of its vnc
+// server read from the same info, blank when it has none.
+func (c *Cluster) GetVMVNC(opts virtualmachine.Vnc, region string) (*virtualmachine.VM, string, error) {
+	id, err := strconv.Atoi(opts.VmId)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid vm id %q", opts.VmId)
+	}
+	node, err := c.getNodeRegion(region)
+	if err != nil {
+		return nil, "", err
+	}
+	defer node.Client.Client.Close()
+
+	res, err := node.Client.Call(VM_INFO, []interface{}{node.Client.Key, id})
+	if err != nil {
+		return nil, "", wrapErrorWithCmd(node, err, "GetVMVNC")
+	}
+	body, ok := res[1].(string)
+	if !ok {
+		return nil, "", fmt.Errorf("unexpected info of vm %s : %v", opts.VmId, res[1])
+	}
+	vm := &virtualmachine.VM{}
+	if err = xml.Unmarshal([]byte(body), vm); err != nil {
+		return nil, "", err
+	}
+	g := &vmGraphics{}
+	if err = xml.Unmarshal([]byte(body), g); err != nil {
+		return nil, "", err
+	}
+	return vm, g.Passwd, nil
+}
+
 // DestroyVM kills a vm, returning an error in case of failure.
 func (c *Cluster) DestroyVM(opts compute.VirtualMachine) error {
 
@@ -299,6 +346,50 @@ func (c *Cluster) ForceStopVM(opts compute.VirtualMachine) error {
 	return nil
 }
 
+// ResizeVM changes the cpu and memory of a powered off vm, throttling the cpu
+// like the vm was created.
+func (c *Cluster) ResizeVM(opts compute.VirtualMachine, throttle string) error {
+	nodlist, err := c.Nodes()
+	if err != nil {
+		return err
+	}
+	for _, v := range nodlist {
+		if v.Metadata[api.ONEZONE] == opts.Region && v.Metadata[api.VCPU_PERCENTAGE] != "" {
+			throttle = v.Metadata[api.VCPU_PERCENTAGE]
+		}
+	}
+	opts.Cpu = cpuThrottle(throttle, opts.Cpu)
+
+	node, err := c.getNodeRegion(opts.Region)
+	if err != nil {
+		return err
+	}
+	defer node.Client.Client.Close()
+
+	tmpl := fmt.Sprintf("CPU=%s\nVCPU=%s\nMEMORY=%s", opts.Cpu, opts.VCpu, opts.Memory)
+	_, err = node.Client.Call(VM_RESIZE, []interface{}{node.Client.Key, opts.VMId, tmpl, true})
+	if err != nil {
+		return wrapErrorWithCmd(node, err, "ResizeVM")
+	}
+	return nil
+}
+
+// ResizeVMDisk grows a disk of the vm to opts.HDD megabytes, one can't shrink
+// disks.
+func (c *Cluster) ResizeVMDisk(opts compute.VirtualMachine, diskId int) error {
+	node, err := c.getNodeRegion(opts.Region)
+	if err != nil {
+		return err
+	}
+	defer node.Client.Client.Close()
+
+	_, err = node.Client.Call(VM_DISK_RESIZE, []interface{}{node.Client.Key, opts.VMId, diskId, opts.HDD})
+	if err != nil {
+		return wrapErrorWithCmd(node, err, "ResizeVMDisk")
+	}
+	return nil
+}
+
 func (c *Cluster) getNodeRegion(region string) (node, error) {
 	return c.getNode(func(s Storage) (Node, error) {
 		return s.RetrieveNode(region)
@@ -606,6 +697,23 @@ func (c *Cluster) ImageTypeChange(opts images.Image, region string) error {
 	return err
 }
 
+// ImageUrl returns where another region downloads the image of the region
+// from, the file of its source in the image store of the region.
+func (c *Cluster) ImageUrl(img *images.Image, region string) (string, error) {
+	nodeo, err := c.regionNode(region)
+	if err != nil {
+		return "", err
+	}
+	store := strings.TrimRight(nodeo.Metadata[IMAGE_STORE], "/")
+	if store == "" {
+		return "", fmt.Errorf("region %s has no image_store, its images can't be used in other regions", region)
+	}
+	if img.Source == "" {
+		return "", fmt.Errorf("image %d of region %s has no source", img.Id, region)
+	}
+	return store + "/" + path.Base(img.Source), nil
+}
+
 func (c *Cluster) GetImage(opts images.Image, region string) (*images.Image, error) {
 	node, err := c.getNodeRegion(region)
 	if err != nil {
@@ -646,6 +754,18 @@ func (c *Cluster) AttachNics(policy *provision.PolicyOps, vmid, region, storage
 	return c.attachNics(vnets, vmid, region)
 }
 
+// Nics returns new nics in region, one on a network of each type vnets turns
+// on, for a vm that doesn't take the networks of its template.
+func (c *Cluster) Nics(vnets map[string]string, region, storage string) ([]*template.NIC, error) {
+	rules := make(map[string]string)
+	for netType, v := range vnets {
+		if v == constants.TRUE {
+			rules[netType] = "1"
+		}
+	}
+	return c.getNics(&provision.PolicyOps{Rules: rules}, region, storage)
+}
+
 func (c *Cluster) attachNics(vnets []*template.NIC, vmid, region string) error {
 	var failures []error
 	node, err := c.getNodeRegion(region)
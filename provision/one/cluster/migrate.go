@@ -0,0 +1,190 @@
+package cluster
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+
+	constants "github.com/megamsys/libgo/utils"
+	"github.com/megamsys/opennebula-go/api"
+)
+
+const (
+	VM_MIGRATE    = "one.vm.migrate"
+	VM_INFO       = "one.vm.info"
+	HOSTPOOL_INFO = "one.hostpool.info"
+
+	// HOST_MONITORED is the state of a host one can place vms on.
+	HOST_MONITORED = 2
+)
+
+// MigrateOpts is where a vm moves to, the host by its name or id, or any host
+// of the cluster. Live migrations move the vm while it runs.
+type MigrateOpts struct {
+	VMId      string
+	Region    string
+	Host      string
+	ClusterId string
+	Storage   string
+	Vnets     map[string]string
+	Live      bool
+}
+
+type Host struct {
+	Id         int    `xml:"ID"`
+	Name       string `xml:"NAME"`
+	State      int    `xml:"STATE"`
+	ClusterId  int    `xml:"CLUSTER_ID"`
+	RunningVMs int    `xml:"HOST_SHARE>RUNNING_VMS"`
+}
+
+type hostPool struct {
+	Hosts []Host `xml:"HOST"`
+}
+
+type vmHistory struct {
+	Hosts []int `xml:"HISTORY_RECORDS>HISTORY>HID"`
+}
+
+// MigrateVM moves the vm to the host or cluster of opts. The cluster the vm
+// lands in has to have the storage type and networks of the vm, as when it
+// was created.
+func (c *Cluster) MigrateVM(opts MigrateOpts) error {
+	if opts.Host == "" && opts.ClusterId == "" {
+		return fmt.Errorf("vm %s needs a host or a cluster to migrate to", opts.VMId)
+	}
+	nodeo, err := c.regionNode(opts.Region)
+	if err != nil {
+		return err
+	}
+	node, err := c.getNodeRegion(opts.Region)
+	if err != nil {
+		return err
+	}
+	defer node.Client.Client.Close()
+
+	vmid, err := strconv.Atoi(opts.VMId)
+	if err != nil {
+		return fmt.Errorf("invalid vm id %q", opts.VMId)
+	}
+	hosts, err := listHosts(node)
+	if err != nil {
+		return wrapErrorWithCmd(node, err, "MigrateVM")
+	}
+	current, err := vmHost(node, vmid)
+	if err != nil {
+		return wrapErrorWithCmd(node, err, "MigrateVM")
+	}
+	host, err := pickHost(hosts, opts.Host, opts.ClusterId, current)
+	if err != nil {
+		return err
+	}
+	if err = canHost(nodeo, strconv.Itoa(host.ClusterId), opts.Storage, opts.Vnets); err != nil {
+		return err
+	}
+	_, err = node.Client.Call(VM_MIGRATE, []interface{}{node.Client.Key, vmid, host.Id, opts.Live, false, -1})
+	if err != nil {
+		return wrapErrorWithCmd(node, err, "MigrateVM")
+	}
+	return nil
+}
+
+func (c *Cluster) regionNode(region string) (Node, error) {
+	nodlist, err := c.Nodes()
+	if err != nil {
+		return Node{}, err
+	}
+	for _, v := range nodlist {
+		if v.Metadata[api.ONEZONE] == region {
+			return v, nil
+		}
+	}
+	return Node{}, fmt.Errorf("Unavailable region ( %s ) nodes", region)
+}
+
+func listHosts(n node) ([]Host, error) {
+	res, err := n.Client.Call(HOSTPOOL_INFO, []interface{}{n.Client.Key})
+	if err != nil {
+		return nil, err
+	}
+	pool := &hostPool{}
+	if err = xml.Unmarshal([]byte(res[1].(string)), pool); err != nil {
+		return nil, err
+	}
+	return pool.Hosts, nil
+}
+
+// vmHost returns the id of the host the vm is on, or -1.
+func vmHost(n node, vmid int) (int, error) {
+	res, err := n.Client.Call(VM_INFO, []interface{}{n.Client.Key, vmid})
+	if err != nil {
+		return -1, err
+	}
+	h := &vmHistory{}
+	if err = xml.Unmarshal([]byte(res[1].(string)), h); err != nil {
+		return -1, err
+	}
+	if len(h.Hosts) == 0 {
+		return -1, nil
+	}
+	return h.Hosts[len(h.Hosts)-1], nil
+}
+
+// pickHost returns the host named (or numbered) host, in the cluster when one
+// is given. With only the cluster, the monitored host of it running the least
+// vms is picked, other than the current one.
+func pickHost(hosts []Host, host, clusterId string, current int) (Host, error) {
+	var picked *Host
+	for i := range hosts {
+		h := &hosts[i]
+		if clusterId != "" && strconv.Itoa(h.ClusterId) != clusterId {
+			continue
+		}
+		if host != "" {
+			if h.Name != host && strconv.Itoa(h.Id) != host {
+				continue
+			}
+			if h.Id == current {
+				return Host{}, fmt.Errorf("vm is on host %s already", host)
+			}
+			if h.State != HOST_MONITORED {
+				return Host{}, fmt.Errorf("host %s isn't monitored, state %d", host, h.State)
+			}
+			return *h, nil
+		}
+		if h.Id == current || h.State != HOST_MONITORED {
+			continue
+		}
+		if picked == nil || h.RunningVMs < picked.RunningVMs {
+			picked = h
+		}
+	}
+	if picked == nil {
+		if host != "" {
+			return Host{}, fmt.Errorf("host %s not found in cluster (%s)", host, clusterId)
+		}
+		return Host{}, fmt.Errorf("no host to migrate to in cluster %s", clusterId)
+	}
+	return *picked, nil
+}
+
+// canHost tells if the cluster of the region node has the storage type and
+// every network the vm uses, as getVnets picks them for a new vm.
+func canHost(nodeo Node, clusterId, st string, vnets map[string]string) error {
+	cl, ok := nodeo.Clusters[clusterId]
+	if !ok {
+		return fmt.Errorf("cluster %s isn't in region (%s)", clusterId, nodeo.Metadata[api.ONEZONE])
+	}
+	if s := cl[constants.STORAGE_TYPE]; len(s) == 0 || s[0] != st {
+		return fmt.Errorf("Storage (%s) unavailable in cluster (%s)", st, clusterId)
+	}
+	if len(cl[constants.VONE_CLOUD]) > 0 && cl[constants.VONE_CLOUD][0] == constants.TRUE {
+		return fmt.Errorf("cluster %s is a vone cloud", clusterId)
+	}
+	for netType, v := range vnets {
+		if v == constants.TRUE && len(cl[netType]) == 0 {
+			return fmt.Errorf("No (%s) network in cluster (%s)", netType, clusterId)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package cluster
+
+import (
+	"testing"
+
+	constants "github.com/megamsys/libgo/utils"
+)
+
+func TestPickHost(t *testing.T) {
+	hosts := []Host{
+		{Id: 0, Name: "alpha", State: HOST_MONITORED, ClusterId: 100, RunningVMs: 2},
+		{Id: 1, Name: "beta", State: HOST_MONITORED, ClusterId: 100, RunningVMs: 7},
+		{Id: 2, Name: "gamma", State: 8, ClusterId: 101},
+		{Id: 3, Name: "delta", State: HOST_MONITORED, ClusterId: 101, RunningVMs: 4},
+	}
+	h, err := pickHost(hosts, "beta", "", 0)
+	if err != nil || h.Id != 1 {
+		t.Errorf("pickHost by name: got %#v, %v", h, err)
+	}
+	h, err = pickHost(hosts, "3", "101", 0)
+	if err != nil || h.Id != 3 {
+		t.Errorf("pickHost by id in cluster: got %#v, %v", h, err)
+	}
+	if _, err = pickHost(hosts, "beta", "101", 0); err == nil {
+		t.Error("pickHost: expected an error for a host out of the cluster")
+	}
+	if _, err = pickHost(hosts, "alpha", "", 0); err == nil {
+		t.Error("pickHost: expected an error for the current host")
+	}
+	if _, err = pickHost(hosts, "gamma", "", 0); err == nil {
+		t.Error("pickHost: expected an error for a host not monitored")
+	}
+	h, err = pickHost(hosts, "", "100", 0)
+	if err != nil || h.Id != 1 {
+		t.Errorf("pickHost in cluster: got %#v, %v", h, err)
+	}
+	h, err = pickHost(hosts, "", "101", 1)
+	if err != nil || h.Id != 3 {
+		t.Errorf("pickHost in cluster: got %#v, %v", h, err)
+	}
+}
+
+func TestCanHost(t *testing.T) {
+	nodeo := Node{
+		Metadata: map[string]string{},
+		Clusters: map[string]map[string][]string{
+			"100": {constants.STORAGE_TYPE: {"hdd"}, "ipv4_pub": {"public"}},
+			"101": {constants.STORAGE_TYPE: {"ssd"}},
+		},
+	}
+	vnets := map[string]string{"ipv4_pub": constants.TRUE, "ipv6_pub": "false"}
+	if err := canHost(nodeo, "100", "hdd", vnets); err != nil {
+		t.Errorf("canHost: %s", err)
+	}
+	if err := canHost(nodeo, "100", "ssd", vnets); err == nil {
+		t.Error("canHost: expected an error for the storage type")
+	}
+	if err := canHost(nodeo, "101", "ssd", vnets); err == nil {
+		t.Error("canHost: expected an error for the missing network")
+	}
+	if err := canHost(nodeo, "102", "hdd", nil); err == nil {
+		t.Error("canHost: expected an error for an unknown cluster")
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"strconv"
 	"time"
+
+	"github.com/megamsys/vertice/provision"
 )
 
 // Node represents a farm with endpoint of One. Each node has an Address
@@ -139,6 +141,12 @@ func (n *Node) FailureCount() int {
 	return failures
 }
 
+// keepHealth carries the health the region had in from over to n.
+func (n *Node) keepHealth(from Node) {
+	n.Metadata = provision.KeepHealth(n.Metadata, from.Metadata)
+	n.Healing = from.Healing
+}
+
 func (n *Node) ResetFailures() {
 	if n.Metadata == nil {
 		n.Metadata = make(map[string]string)
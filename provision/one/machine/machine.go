@@ -1,6 +1,7 @@
 package machine
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -48,7 +49,9 @@ type Machine struct {
 	VMId         string
 	VNCHost      string
 	VNCPort      string
+	VNCPass      string
 	ImageId      string
+	CloneImageId string
 	StorageType  string
 	Routable     bool
 	PublicUrl    string
@@ -194,7 +197,7 @@ func (m *Machine) CheckQuotaState(b *provision.Box, w io.Writer) error {
 	return nil
 }
 
-func (m *Machine) VmHostIpPort(args *CreateArgs) error {
+func (m *Machine) VmHostIpPort(ctx context.Context, args *CreateArgs) error {
 	asm, err := carton.NewAssembly(m.CartonId, m.AccountId, "")
 	if err != nil {
 		return err
@@ -204,11 +207,12 @@ func (m *Machine) VmHostIpPort(args *CreateArgs) error {
 	}
 
 	res := &virtualmachine.VM{}
+	pass := ""
 	_ = asm.SetStatus(utils.Status(constants.StatusLcmStateChecking))
 
-	err = safe.WaitCondition(30*time.Minute, 20*time.Second, func() (bool, error) {
+	err = provision.WaitCondition(ctx, 30*time.Minute, 20*time.Second, func() (bool, error) {
 		_ = asm.Trigger_event(utils.Status(constants.StatusWaitUntill))
-		res, err = args.Provisioner.Cluster().GetVM(opts, m.Region)
+		res, pass, err = args.Provisioner.Cluster().GetVMVNC(opts, m.Region)
 		if err != nil {
 			return false, err
 		}
@@ -229,13 +233,14 @@ func (m *Machine) VmHostIpPort(args *CreateArgs) error {
 
 	m.VNCHost = res.GetHostIp()
 	m.VNCPort = res.GetPort()
+	m.setVNCPass(pass)
 	return nil
 }
 
-func (m *Machine) WaitUntillVMState(p OneProvisioner, vm virtualmachine.VmState, lcm virtualmachine.LcmState) error {
+func (m *Machine) WaitUntillVMState(ctx context.Context, p OneProvisioner, vm virtualmachine.VmState, lcm virtualmachine.LcmState) error {
 	opts := virtualmachine.Vnc{VmId: m.VMId}
 
-	err := safe.WaitCondition(20*time.Minute, 15*time.Second, func() (bool, error) {
+	err := provision.WaitCondition(ctx, 20*time.Minute, 15*time.Second, func() (bool, error) {
 		res, err := p.Cluster().GetVM(opts, m.Region)
 		if err != nil {
 			return false, err
@@ -259,6 +264,7 @@ func (m *Machine) UpdateVncHostPost() error {
 	port = []string{m.VNCPort}
 	vnc[carton.VNCHOST] = host
 	vnc[carton.VNCPORT] = port
+	vnc[carton.VNCPASS] = []string{m.VNCPass}
 	if asm, err := carton.NewAssembly(m.CartonId, m.AccountId, ""); err != nil {
 		return err
 	} else if err = asm.NukeAndSetOutputs(vnc); err != nil {
@@ -360,6 +366,201 @@ func (m *Machine) LifecycleOps(p OneProvisioner, action string) error {
 	return nil
 }
 
+// Resize changes the powered off vm from the compute it has to the new one.
+// The system disk is grown when the new one is bigger.
+func (m *Machine) Resize(p OneProvisioner, from, to provision.BoxCompute) error {
+	log.Debugf("  resize machine in one (%s) to %s", m.Name, to.String())
+	id, _ := strconv.Atoi(m.VMId)
+	was, box := &provision.Box{Compute: from}, &provision.Box{Compute: to}
+	opts := compute.VirtualMachine{
+		Name:   m.Name,
+		Region: m.Region,
+		VMId:   id,
+		Cpu:    strconv.FormatInt(int64(box.GetCpushare()), 10),
+		Memory: strconv.FormatInt(int64(box.GetMemory()), 10),
+		HDD:    strconv.FormatInt(int64(box.GetHDD()), 10),
+	}
+	opts.VCpu = opts.Cpu
+	if box.GetHDD() > was.GetHDD() {
+		if err := p.Cluster().ResizeVMDisk(opts, 0); err != nil {
+			return err
+		}
+	}
+	return p.Cluster().ResizeVM(opts, m.VCPUThrottle)
+}
+
+// Recontextualize sets the repo and commit of the box in the context of the
+// powered off vm, which builds them when it is started again.
+func (m *Machine) Recontextualize(p OneProvisioner, box *provision.Box) error {
+	log.Debugf("  recontextualize machine in one (%s) to %s", m.Name, box.Commit)
+	return p.Cluster().UpdateVMContext(m.VMId, m.Region, map[string]string{
+		cluster.CONTEXT_GIT_URL:    box.Repo.Gitr(),
+		cluster.CONTEXT_GIT_COMMIT: box.Commit,
+	})
+}
+
+// Migrate moves the vm to the host or cluster of to, the vm keeps its storage
+// type and networks.
+func (m *Machine) Migrate(p OneProvisioner, to provision.MigrateTo, vnets map[string]string) error {
+	log.Debugf("  migrate machine in one (%s) to host (%s) cluster (%s)", m.Name, to.Host, to.ClusterId)
+	return p.Cluster().MigrateVM(cluster.MigrateOpts{
+		VMId:      m.VMId,
+		Region:    m.Region,
+		Host:      to.Host,
+		ClusterId: to.ClusterId,
+		Storage:   m.StorageType,
+		Vnets:     vnets,
+		Live:      to.Live,
+	})
+}
+
+// CloneImage makes the image a clone boots from. The snapshot of a disk is
+// saved as an image of its own, a backup is an image already. An image of
+// another region is downloaded into the region of the clone from the image
+// store of its region, the snapshot image saved for it goes once its copied.
+// The image made for the clone is its CloneImageId.
+func (m *Machine) CloneImage(p OneProvisioner, from provision.CloneSource) error {
+	log.Debugf("  creating clone image of machine in one (%s) from %s", m.Name, from.String())
+	name := m.Name + "-" + from.Id
+	m.ImageId = from.ImageId
+	saved := ""
+	if from.Kind == provision.CLONE_SNAPSHOT {
+		vmid, _ := strconv.Atoi(from.InstanceId)
+		diskId, _ := strconv.Atoi(from.DiskId)
+		sid, _ := strconv.Atoi(from.SnapId)
+		id, err := p.Cluster().SaveDiskImage(compute.Image{
+			Name:   name,
+			Region: from.Region,
+			VMId:   vmid,
+			DiskId: diskId,
+			SnapId: sid,
+		})
+		if err != nil {
+			return err
+		}
+		m.ImageId, saved = id, id
+	}
+	id, _ := strconv.Atoi(m.ImageId)
+	if from.Region != m.Region {
+		res, err := m.copyImage(p, id, name, from.Region)
+		if saved != "" {
+			m.removeImage(p, saved, from.Region)
+		}
+		if err != nil {
+			return err
+		}
+		m.ImageId, saved = res, res
+		id, _ = strconv.Atoi(m.ImageId)
+	}
+	m.CloneImageId = saved
+	img, err := p.Cluster().GetImage(images.Image{Id: id}, m.Region)
+	if err != nil {
+		return err
+	}
+	m.Image = img.Name
+	return nil
+}
+
+// copyImage creates the image id of region again in the region of the
+// machine, and waits till its downloaded.
+func (m *Machine) copyImage(p OneProvisioner, id int, name, region string) (string, error) {
+	if err := p.Cluster().IsImageReady(&images.Image{Id: id}, region); err != nil {
+		return "", err
+	}
+	src, err := p.Cluster().GetImage(images.Image{Id: id}, region)
+	if err != nil {
+		return "", err
+	}
+	location, err := p.Cluster().ImageUrl(src, region)
+	if err != nil {
+		return "", err
+	}
+	res, err := p.Cluster().ImageCreate(images.Image{
+		Name: name,
+		Path: location,
+		Type: images.OPERATING_SYSTEM,
+	}, m.Region)
+	if err != nil {
+		return "", err
+	}
+	copied := res.(string)
+	cid, _ := strconv.Atoi(copied)
+	if err = p.Cluster().IsImageReady(&images.Image{Id: cid}, m.Region); err != nil {
+		m.removeImage(p, copied, m.Region)
+		return "", err
+	}
+	return copied, nil
+}
+
+// RemoveCloneImage removes the image made for the clone, once the vm that
+// booted from it is gone.
+func (m *Machine) RemoveCloneImage(p OneProvisioner) error {
+	if m.CloneImageId == "" {
+		asm, err := carton.NewAssembly(m.CartonId, m.AccountId, "")
+		if err != nil {
+			return err
+		}
+		m.CloneImageId = asm.CloneImageId()
+	}
+	if m.CloneImageId == "" {
+		return nil
+	}
+	id, _ := strconv.Atoi(m.CloneImageId)
+	// one keeps the image in use till the vm is done.
+	if err := p.Cluster().IsImageReady(&images.Image{Id: id}, m.Region); err != nil {
+		return err
+	}
+	return m.removeImage(p, m.CloneImageId, m.Region)
+}
+
+func (m *Machine) removeImage(p OneProvisioner, imageId, region string) error {
+	id, _ := strconv.Atoi(imageId)
+	err := p.Cluster().RemoveImage(compute.Image{Region: region, ImageId: id})
+	if err != nil {
+		log.Errorf("  removing image %s of machine (%s) in region %s : %s", imageId, m.Name, region, err)
+	}
+	return err
+}
+
+// CreateClone creates the vm of a clone from its image, on new nics.
+func (m *Machine) CreateClone(args *CreateArgs) error {
+	opts, asm, err := m.create(args)
+	if err != nil {
+		return err
+	}
+	nics, err := args.Provisioner.Cluster().Nics(args.Box.Vnets, m.Region, m.StorageType)
+	if err != nil {
+		return err
+	}
+	opts.ForceNetwork = true
+	_, _, vmid, err := args.Provisioner.Cluster().CreateVM(opts, m.VCPUThrottle, m.StorageType, nics)
+	if err != nil {
+		return err
+	}
+	m.VMId = vmid
+	var id = make(map[string][]string)
+	id[carton.INSTANCE_ID] = []string{m.VMId}
+	if m.CloneImageId != "" {
+		id[carton.CLONE_IMAGE_ID] = []string{m.CloneImageId}
+	}
+	return asm.NukeAndSetOutputs(id)
+}
+
+// PublicIp returns the first public ipv4 of the vm, or its first private one.
+func (m *Machine) PublicIp(p OneProvisioner) (string, error) {
+	res, err := p.Cluster().GetVM(virtualmachine.Vnc{VmId: m.VMId}, m.Region)
+	if err != nil {
+		return "", err
+	}
+	ips := m.IPs(res.Nics())
+	for _, t := range []string{constants.PUBLICIPV4, constants.PRIVATEIPV4} {
+		if len(ips[t]) > 0 {
+			return ips[t][0], nil
+		}
+	}
+	return "", fmt.Errorf("machine %s has no ip", m.Name)
+}
+
 //it possible to have a Notifier interface that does this, duck typed b y Assembly, Components.
 func (m *Machine) SetStatus(status utils.Status) error {
 	log.Debugf("  set status[%s] of machine (%s, %s)", m.Id, m.Name, status.String())
@@ -562,9 +763,9 @@ func (m *Machine) IsImageReady(p OneProvisioner) error {
 	return p.Cluster().IsImageReady(opts, m.Region)
 }
 
-func (m *Machine) IsSnapReady(p OneProvisioner) error {
+func (m *Machine) IsSnapReady(ctx context.Context, p OneProvisioner) error {
 	opts := virtualmachine.Vnc{VmId: m.VMId}
-	err := safe.WaitCondition(10*time.Minute, 15*time.Second, func() (bool, error) {
+	err := provision.WaitCondition(ctx, 10*time.Minute, 15*time.Second, func() (bool, error) {
 		res, err := p.Cluster().GetVM(opts, m.Region)
 		if err != nil {
 			return false, err
@@ -1013,20 +1214,31 @@ func (m *Machine) GetMarketplaceVNC(p OneProvisioner) error {
 	opts := virtualmachine.Vnc{
 		VmId: m.VMId,
 	}
-	res, err := p.Cluster().GetVM(opts, m.Region)
+	res, pass, err := p.Cluster().GetVMVNC(opts, m.Region)
 	if err != nil {
 		return err
 	}
 	//	ips := m.mergeSameIPtype(m.IPs(res.Nics()))
 	m.VNCHost = res.GetHostIp()
 	m.VNCPort = res.GetPort()
+	m.setVNCPass(pass)
 	return nil
 }
 
+// setVNCPass keeps the password of the vnc server, a vm without one still
+// deploys but its console can't be captured.
+func (m *Machine) setVNCPass(pass string) {
+	if pass == "" {
+		log.Warnf("  no vnc password in the graphics of machine (%s, %s)", m.VMId, m.Name)
+	}
+	m.VNCPass = pass
+}
+
 func (m *Machine) UpdateMarketplaceVNC() error {
 	var vnc = make(map[string][]string)
 	vnc[carton.VNCHOST] = []string{m.VNCHost}
 	vnc[carton.VNCPORT] = []string{m.VNCPort}
+	vnc[carton.VNCPASS] = []string{m.VNCPass}
 
 	if mark, err := m.getMarketPlace(m.CartonId); err != nil {
 		return err
@@ -1053,7 +1265,7 @@ func (m *Machine) ImageTypeChange(p OneProvisioner) error {
 	return p.Cluster().ImageTypeChange(opts, m.Region)
 }
 
-func (m *Machine) CheckSaveImage(p OneProvisioner) error {
+func (m *Machine) CheckSaveImage(ctx context.Context, p OneProvisioner) error {
 	mark, err := m.getMarketPlace(m.CartonId)
 	if err != nil {
 		return err
@@ -1070,7 +1282,7 @@ func (m *Machine) CheckSaveImage(p OneProvisioner) error {
 	if res.Persistent == "no" {
 		return fmt.Errorf("Image in Non-persistent state")
 	}
-	return m.WaitUntillVMState(p, virtualmachine.POWEROFF, virtualmachine.LCM_INIT)
+	return m.WaitUntillVMState(ctx, p, virtualmachine.POWEROFF, virtualmachine.LCM_INIT)
 }
 
 func (m *Machine) StopMarkplaceInstance(p OneProvisioner) error {
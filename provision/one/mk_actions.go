@@ -306,7 +306,7 @@ var waitForsaveImage = action.Action{
 		if writer == nil {
 			writer = ioutil.Discard
 		}
-		err := mach.CheckSaveImage(args.provisioner)
+		err := mach.CheckSaveImage(args.ctx, args.provisioner)
 		if err != nil {
 			fmt.Fprintf(writer, lb.W(lb.DEPLOY, lb.ERROR, fmt.Sprintf("  error start machine ( %s)", args.box.GetFullName())))
 			return nil, err
@@ -17,6 +17,7 @@
 package one
 
 import (
+	"context"
 	"fmt"
 	"io"
 
@@ -37,8 +38,9 @@ func (p *oneProvisioner) ISODeploy(m *provision.Box, w io.Writer) error {
 		&waitUntillImageReady,
 		&updateImageStatus,
 	}
-	pipeline := action.NewPipeline(actions...)
+	pipeline := action.NewPipeline(provision.TrackActions(m, actions)...)
 	args := runMachineActionsArgs{
+		ctx:           context.Background(),
 		box:           m,
 		writer:        w,
 		machineStatus: constants.StatusCreating,
@@ -76,8 +78,9 @@ func (p *oneProvisioner) CustomizeImage(m *provision.Box, w io.Writer) error {
 		&setFinalStatus,
 		&updateMarketplaceStatus,
 	}
-	pipeline := action.NewPipeline(actions...)
+	pipeline := action.NewPipeline(provision.TrackActions(m, actions)...)
 	args := runMachineActionsArgs{
+		ctx:           context.Background(),
 		box:           m,
 		writer:        w,
 		machineStatus: constants.StatusLaunching,
@@ -110,8 +113,9 @@ func (p *oneProvisioner) SaveMarketplaceImage(m *provision.Box, w io.Writer) err
 
 	actions = append(actions, &updateMarketplaceStatus)
 
-	pipeline := action.NewPipeline(actions...)
+	pipeline := action.NewPipeline(provision.TrackActions(m, actions)...)
 	args := runMachineActionsArgs{
+		ctx:           context.Background(),
 		box:           m,
 		writer:        w,
 		machineStatus: constants.StatusImageSaving,
@@ -0,0 +1,324 @@
+/*
+** Copyright [2013-2017] [Megam Systems]
+**
+** Licensed under the Apache License, Version 2.0 (the "License");
+** you may not use this file except in compliance with the License.
+** You may obtain a copy of the License at
+**
+** http://www.apache.org/licenses/LICENSE-2.0
+**
+** Unless required by applicable law or agreed to in writing, software
+** distributed under the License is distributed on an "AS IS" BASIS,
+** WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+** See the License for the specific language governing permissions and
+** limitations under the License.
+ */
+
+package one
+
+import (
+	"strings"
+
+	"github.com/megamsys/libgo/action"
+	"github.com/megamsys/libgo/events"
+	constants "github.com/megamsys/libgo/utils"
+	"github.com/megamsys/vertice/carton"
+	"github.com/megamsys/vertice/provision"
+)
+
+// The pipelines run on the machines. The operations and PlanActions both
+// build them here, so a plan lists exactly what the operation runs.
+
+func deployActions(box *provision.Box, backup bool) []*action.Action {
+	actions := []*action.Action{&machCreating}
+	if events.IsEnabled(constants.BILLMGR) && !strings.Contains(box.Authority, "admin") {
+		if !(len(box.QuotaId) > 0) {
+			actions = append(actions, &checkBalances)
+		} else {
+			actions = append(actions, &checkQuotaState)
+		}
+	}
+
+	actions = append(actions, &updateStatusInScylla, &mileStoneUpdate)
+	if backup {
+		actions = append(actions, &createBackupMachine)
+	} else {
+		actions = append(actions, &createMachine)
+	}
+	return append(actions, &getVmHostIpPort, &mileStoneUpdate, &updateStatusInScylla, &updateVnchostPostInScylla, &updateStatusInScylla, &setFinalStatus, &updateStatusInScylla, &followLogs)
+}
+
+func destroyActions(box *provision.Box) []*action.Action {
+	actions := []*action.Action{
+		&machCreating,
+		&updateStatusInScylla,
+		&mileStoneUpdate,
+		&destroyOldMachine,
+	}
+
+	if len(box.QuotaId) > 0 && !box.Replacing {
+		actions = append(actions, &updateVMQuota)
+	}
+	return append(actions, &destroyOldRoute, &mileStoneUpdate, &updateStatusInScylla)
+}
+
+func runningActions() []*action.Action {
+	return []*action.Action{
+		&machCreating,
+		&updateNetworkIps,
+		&updateStatusInScylla,
+		&mileStoneUpdate,
+	}
+}
+
+func stateActions(box *provision.Box) []*action.Action {
+	actions := make([]*action.Action, 0, 4)
+	actions = append(actions, &machCreating, &changeStateofMachine)
+	if box.PublicIp != "" {
+		actions = append(actions, &updateStatusInScylla, &addNewRoute, &updateStatusInScylla)
+	} else {
+		actions = append(actions, &updateStatusInScylla)
+	}
+	return append(actions, &setFinalState, &updateStatusInScylla)
+}
+
+func saveImageActions() []*action.Action {
+	return []*action.Action{
+		&machCreating,
+		&updateStatusInScylla,
+		&createBackupImage,
+		&waitUntillImageReady,
+		&updateSourcePath,
+		&updateBackupStatus,
+		&updateSourceVMIdIps,
+		&updateStatusInScylla,
+	}
+}
+
+func createImageActions() []*action.Action {
+	return []*action.Action{
+		&machCreating,
+		&updateBackupStatus,
+		&uploadBackupImage,
+		&waitUntillImageReady,
+		&updateBackupStatus,
+		&updateSourcePath,
+	}
+}
+
+func deleteImageActions(box *provision.Box) []*action.Action {
+	actions := []*action.Action{&machCreating}
+	if box.Tosca == constants.BACKUP_NEW {
+		return append(actions, &updateBackupStatus, &removeBackup)
+	}
+	return append(actions, &updateBackupStatus, &updateStatusInScylla, &removeBackup, &updateStatusInScylla)
+}
+
+func createSnapshotActions(box *provision.Box) []*action.Action {
+	actions := []*action.Action{
+		&machCreating,
+		&updateSnapStatus,
+		&createSnapshot,
+		&waitUntillSnapReady,
+		&updateIdInSnapTable,
+		&makeActiveSnap,
+	}
+
+	if len(box.QuotaId) > 0 {
+		actions = append(actions, &updateSnapQuotaCount)
+	}
+	return append(actions, &updateStatusInScylla)
+}
+
+func restoreSnapshotActions(box *provision.Box) []*action.Action {
+	actions := []*action.Action{&machCreating, &updateStatusInScylla}
+	if box.CanCycleStop() {
+		actions = append(actions, &stopMachine, &mileStoneUpdate, &updateStatusInScylla)
+	}
+	actions = append(actions, &restoreVirtualMachine, &updateSnapStatus, &makeActiveSnap, &updateStatusInScylla)
+	if box.CanCycleStop() {
+		actions = append(actions, &startMachine, &mileStoneUpdate, &updateStatusInScylla)
+	}
+	return actions
+}
+
+func deleteSnapshotActions(quota bool) []*action.Action {
+	actions := []*action.Action{
+		&machCreating,
+		&updateStatusInScylla,
+		&removeSnapShot,
+	}
+
+	if quota {
+		actions = append(actions, &updateSnapQuotaCount)
+	}
+	return append(actions, &updateSnapStatus, &updateStatusInScylla)
+}
+
+func attachDiskActions() []*action.Action {
+	return []*action.Action{
+		&machCreating,
+		&updateStatusInScylla,
+		&addNewStorage,
+		&updateIdInDiskTable,
+		&updateStatusInScylla,
+	}
+}
+
+func detachDiskActions() []*action.Action {
+	return []*action.Action{
+		&machCreating,
+		&updateStatusInScylla,
+		&removeDiskStorage,
+		&updateStatusInScylla,
+	}
+}
+
+// resizeActions run on the powered off machine.
+func resizeActions() []*action.Action {
+	return []*action.Action{
+		&machCreating,
+		&resizeMachine,
+		&updateStatusInScylla,
+	}
+}
+
+// upgradeActions run on the powered off machine.
+func upgradeActions() []*action.Action {
+	return []*action.Action{
+		&machCreating,
+		&recontextualizeMachine,
+		&updateStatusInScylla,
+	}
+}
+
+// migrateActions move the machine, a running one gets the vnc of its new host.
+func migrateActions(box *provision.Box) []*action.Action {
+	actions := []*action.Action{
+		&machCreating,
+		&updateStatusInScylla,
+		&migrateMachine,
+		&updateStatusInScylla,
+	}
+	if box.State == constants.StateRunning {
+		actions = append(actions, &getVmHostIpPort, &updateVnchostPostInScylla, &updateStatusInScylla)
+	}
+	return actions
+}
+
+// cloneActions launch the box as a new vm, from the image of a snapshot or a
+// backup, and route it.
+func cloneActions() []*action.Action {
+	return []*action.Action{
+		&machCreating,
+		&updateStatusInScylla,
+		&cloneImage,
+		&waitUntillImageReady,
+		&createCloneMachine,
+		&getVmHostIpPort,
+		&mileStoneUpdate,
+		&updateStatusInScylla,
+		&updateVnchostPostInScylla,
+		&updateCloneIps,
+		&addNewRoute,
+		&setFinalStatus,
+		&updateStatusInScylla,
+	}
+}
+
+// cycleActions are the actions of start, stop, restart and suspend, which
+// differ only in how the machine is cycled.
+func cycleActions(cycle *action.Action) []*action.Action {
+	return []*action.Action{
+		&machCreating,
+		&updateStatusInScylla,
+		cycle,
+		&mileStoneUpdate,
+		&updateStatusInScylla,
+	}
+}
+
+func networkActions(networks *action.Action) []*action.Action {
+	return []*action.Action{
+		&machCreating,
+		&updateStatusInScylla,
+		&updataPoliciesStatus,
+		networks,
+		&updateNetworkIps,
+		&updataPoliciesStatus,
+	}
+}
+
+// PlanActions returns the names of the actions op would run on the box.
+func (p *oneProvisioner) PlanActions(box *provision.Box, op provision.BoxOp) ([]string, error) {
+	var actions []*action.Action
+	switch op {
+	case provision.CYCLE_DEPLOY:
+		actions = deployActions(box, box.Backup)
+	case provision.CYCLE_DESTROY:
+		actions = destroyActions(box)
+	case provision.CYCLE_ROLLBACK:
+		actions = append(destroyActions(box), deployActions(box, false)...)
+	case provision.CYCLE_RUNNING:
+		actions = runningActions()
+	case provision.CYCLE_STATEUP:
+		actions = stateActions(box)
+	case provision.CYCLE_START:
+		actions = cycleActions(&startMachine)
+	case provision.CYCLE_STOP:
+		actions = cycleActions(&stopMachine)
+	case provision.CYCLE_RESTART:
+		actions = cycleActions(&restartMachine)
+	case provision.CYCLE_SUSPEND:
+		actions = cycleActions(&suspendMachine)
+	case provision.CYCLE_BACKUP_CREATE:
+		if box.Tosca == constants.BACKUP_NEW {
+			actions = createImageActions()
+		} else {
+			actions = saveImageActions()
+		}
+	case provision.CYCLE_BACKUP_DELETE:
+		actions = deleteImageActions(box)
+	case provision.CYCLE_SNAP_CREATE:
+		actions = createSnapshotActions(box)
+	case provision.CYCLE_SNAP_RESTORE:
+		actions = restoreSnapshotActions(box)
+	case provision.CYCLE_SNAP_DELETE:
+		snp, err := carton.GetSnap(box.CartonsId, box.AccountId)
+		if err != nil {
+			return nil, err
+		}
+		actions = deleteSnapshotActions(snp.IsQuota())
+	case provision.CYCLE_DISK_ATTACH:
+		actions = attachDiskActions()
+	case provision.CYCLE_DISK_DETACH:
+		actions = detachDiskActions()
+	case provision.CYCLE_RESIZE:
+		actions = resizeActions()
+		if box.State == constants.StateRunning {
+			actions = append(append(cycleActions(&stopMachine), actions...), cycleActions(&startMachine)...)
+		}
+	case provision.CYCLE_UPGRADE:
+		actions = upgradeActions()
+		if box.State == constants.StateRunning {
+			actions = append(append(cycleActions(&stopMachine), actions...), cycleActions(&startMachine)...)
+		}
+	case provision.CYCLE_MIGRATE:
+		actions = migrateActions(box)
+	case provision.CYCLE_SNAP_CLONE:
+		actions = cloneActions()
+	case provision.CYCLE_NETWORK:
+		if box.PolicyOps == nil {
+			return []string{}, nil
+		}
+		switch box.PolicyOps.Operation {
+		case carton.NETWORK_ATTACH:
+			actions = networkActions(&attachNetworks)
+		case carton.NETWORK_DETACH:
+			actions = networkActions(&detachNetworks)
+		}
+	default:
+		return nil, provision.ErrNotImplemented
+	}
+	return provision.ActionNames(actions), nil
+}
@@ -18,6 +18,7 @@ package one
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"strings"
@@ -26,7 +27,6 @@ import (
 	log "github.com/Sirupsen/logrus"
 	"github.com/megamsys/libgo/action"
 	"github.com/megamsys/libgo/cmd"
-	"github.com/megamsys/libgo/events"
 	"github.com/megamsys/libgo/events/alerts"
 	"github.com/megamsys/libgo/utils"
 	constants "github.com/megamsys/libgo/utils"
@@ -50,6 +50,7 @@ func init() {
 type oneProvisioner struct {
 	defaultImage string
 	vcpuThrottle string
+	sshKey       string
 	cluster      *cluster.Cluster
 	storage      cluster.Storage
 }
@@ -60,6 +61,10 @@ type One struct {
 	Image          string   `json:"image" toml:"image"`
 	VCPUPercentage string   `json:"vcpu_percentage" toml:"vcpu_percentage"`
 	OneTemplate    string   `json:"one_template" toml:"one_template"`
+	SSHKey         string   `json:"ssh_key" toml:"ssh_key"`
+	// Storage keeps the regions of the cluster in memory, or in a file when
+	// it is file, so that their health survives a restart.
+	Storage string `json:"storage" toml:"storage"`
 }
 
 type Region struct {
@@ -73,6 +78,7 @@ type Region struct {
 	VCPUPercentage string    `json:"vcpu_percentage" toml:"vcpu_percentage"`
 	Datastore      string    `json:"one_datastore_id" toml:"one_datastore_id"`
 	Certificate    string    `json:"certificate" toml:"certificate"`
+	ImageStore     string    `json:"image_store" toml:"image_store"`
 	Clusters       []Cluster `json:"cluster" toml:"cluster"`
 }
 
@@ -101,23 +107,32 @@ func (p *oneProvisioner) String() string {
 	return "ready"
 }
 
+// Capabilities of one, the vms can be snapshotted, backed up, suspended,
+// resized, get disks and networks added, and logged into over ssh.
+func (p *oneProvisioner) Capabilities() []provision.Capability {
+	return []provision.Capability{provision.CAP_SNAPSHOTS, provision.CAP_BACKUPS, provision.CAP_DISKS, provision.CAP_SUSPEND,
+		provision.CAP_NETWORK_POLICY, provision.CAP_RESIZE, provision.CAP_SHELL, provision.CAP_EXEC}
+}
+
 func (p *oneProvisioner) Initialize(m interface{}) error {
 	return p.initOneCluster(m)
 }
 
 func (p *oneProvisioner) initOneCluster(i interface{}) error {
 	var err error
+	w, ok := i.(One)
 	if p.storage == nil {
-		p.storage, err = buildClusterStorage()
+		p.storage, err = buildClusterStorage(w.Storage)
 		if err != nil {
 			return err
 		}
 	}
 
-	if w, ok := i.(One); ok {
+	if ok {
 		var nodes []cluster.Node
 		p.defaultImage = w.Image
 		p.vcpuThrottle = w.VCPUPercentage
+		p.sshKey = w.SSHKey
 		for i := 0; i < len(w.Regions); i++ {
 			m := w.Regions[i].ToMap()
 			c := w.Regions[i].ToClusterMap()
@@ -150,6 +165,7 @@ func (c Region) ToMap() map[string]string {
 	m[api.IMAGE] = c.Image
 	m[api.VCPU_PERCENTAGE] = c.VCPUPercentage
 	m[constants.DATASTORE] = c.Datastore
+	m[cluster.IMAGE_STORE] = c.ImageStore
 	return m
 }
 
@@ -176,8 +192,14 @@ func (c Region) ToClusterMap() map[string]map[string][]string {
 	return clData
 }
 
-func buildClusterStorage() (cluster.Storage, error) {
-	return &cluster.MapStorage{}, nil
+func buildClusterStorage(kind string) (cluster.Storage, error) {
+	switch kind {
+	case "", provision.STORAGE_MEMORY:
+		return &cluster.MapStorage{}, nil
+	case provision.STORAGE_FILE:
+		return cluster.NewFileStorage(provision.ClusterStoragePath("one"))
+	}
+	return nil, fmt.Errorf("unknown one cluster storage %q, it is %s or %s", kind, provision.STORAGE_MEMORY, provision.STORAGE_FILE)
 }
 
 func getRouterForBox(box *provision.Box) (router.Router, error) {
@@ -199,13 +221,13 @@ func (p *oneProvisioner) StartupMessage() (string, error) {
 	return strings.TrimSpace(b.String()), nil
 }
 
-func (p *oneProvisioner) GitDeploy(box *provision.Box, w io.Writer) (string, error) {
+func (p *oneProvisioner) GitDeploy(ctx context.Context, box *provision.Box, w io.Writer) (string, error) {
 	imageId, err := p.gitDeploy(box.Repo, box.ImageVersion, w)
 	if err != nil {
 		return "", err
 	}
 
-	return p.deployPipeline(box, imageId, false, w)
+	return p.deployPipeline(ctx, box, imageId, false, w)
 }
 
 func (p *oneProvisioner) gitDeploy(re *repository.Repo, version string, w io.Writer) (string, error) {
@@ -213,7 +235,7 @@ func (p *oneProvisioner) gitDeploy(re *repository.Repo, version string, w io.Wri
 	return p.getBuildImage(re, version), nil
 }
 
-func (p *oneProvisioner) ImageDeploy(box *provision.Box, imageId string, w io.Writer) (string, error) {
+func (p *oneProvisioner) ImageDeploy(ctx context.Context, box *provision.Box, imageId string, w io.Writer) (string, error) {
 	fmt.Fprintf(w, lb.W(lb.DEPLOY, lb.INFO, fmt.Sprintf("--- deploy box (%s, image:%s)", box.GetFullName(), imageId)))
 
 	isValid, err := isValidBoxImage(box.GetFullName(), imageId)
@@ -224,10 +246,10 @@ func (p *oneProvisioner) ImageDeploy(box *provision.Box, imageId string, w io.Wr
 	if !isValid {
 		imageId = p.getBuildImage(box.Repo, box.ImageVersion)
 	}
-	return p.deployPipeline(box, imageId, false, w)
+	return p.deployPipeline(ctx, box, imageId, false, w)
 }
 
-func (p *oneProvisioner) BackupDeploy(box *provision.Box, imageId string, w io.Writer) (string, error) {
+func (p *oneProvisioner) BackupDeploy(ctx context.Context, box *provision.Box, imageId string, w io.Writer) (string, error) {
 	fmt.Fprintf(w, lb.W(lb.DEPLOY, lb.INFO, fmt.Sprintf("--- deploy box (%s, image:%s)", box.GetFullName(), imageId)))
 
 	isValid, err := isValidBoxImage(box.GetFullName(), imageId)
@@ -238,7 +260,7 @@ func (p *oneProvisioner) BackupDeploy(box *provision.Box, imageId string, w io.W
 	if !isValid {
 		imageId = p.getBuildImage(box.Repo, box.ImageVersion)
 	}
-	return p.deployPipeline(box, imageId, true, w)
+	return p.deployPipeline(ctx, box, imageId, true, w)
 }
 
 //start by validating the image.
@@ -246,30 +268,14 @@ func (p *oneProvisioner) BackupDeploy(box *provision.Box, imageId string, w io.W
 //2. &create an inmemory machine type from a Box.
 //3. &updateStatus in Scylla - Creating..
 //4. &followLogs by posting it in the queue.
-func (p *oneProvisioner) deployPipeline(box *provision.Box, imageId string, backup bool, w io.Writer) (string, error) {
+func (p *oneProvisioner) deployPipeline(ctx context.Context, box *provision.Box, imageId string, backup bool, w io.Writer) (string, error) {
 
 	fmt.Fprintf(w, lb.W(lb.DEPLOY, lb.INFO, fmt.Sprintf("--- deploy box (%s, image:%s)", box.GetFullName(), imageId)))
 
-	actions := []*action.Action{&machCreating}
-	if events.IsEnabled(constants.BILLMGR) && !strings.Contains(box.Authority, "admin") {
-		if !(len(box.QuotaId) > 0) {
-			actions = append(actions, &checkBalances)
-		} else {
-			actions = append(actions, &checkQuotaState)
-		}
-	}
-
-	actions = append(actions, &updateStatusInScylla, &mileStoneUpdate)
-	if backup {
-		actions = append(actions, &createBackupMachine)
-	} else {
-		actions = append(actions, &createMachine)
-	}
-	actions = append(actions, &getVmHostIpPort, &mileStoneUpdate, &updateStatusInScylla, &updateVnchostPostInScylla, &updateStatusInScylla, &setFinalStatus, &updateStatusInScylla, &followLogs)
-
-	pipeline := action.NewPipeline(actions...)
+	pipeline := action.NewPipeline(provision.TrackActions(box, deployActions(box, backup))...)
 
 	args := runMachineActionsArgs{
+		ctx:           ctx,
 		box:           box,
 		imageId:       imageId,
 		writer:        w,
@@ -289,10 +295,11 @@ func (p *oneProvisioner) deployPipeline(box *provision.Box, imageId string, back
 	return imageId, nil
 }
 
-func (p *oneProvisioner) Destroy(box *provision.Box, w io.Writer) error {
+func (p *oneProvisioner) Destroy(ctx context.Context, box *provision.Box, w io.Writer) error {
 
 	fmt.Fprintf(w, lb.W(lb.DESTORYING, lb.INFO, fmt.Sprintf("--- destroying box (%s)", box.GetFullName())))
 	args := runMachineActionsArgs{
+		ctx:           ctx,
 		box:           box,
 		writer:        w,
 		machineStatus: constants.StatusDestroying,
@@ -300,20 +307,7 @@ func (p *oneProvisioner) Destroy(box *provision.Box, w io.Writer) error {
 		provisioner:   p,
 	}
 
-	actions := []*action.Action{
-		&machCreating,
-		&updateStatusInScylla,
-		&mileStoneUpdate,
-		&destroyOldMachine,
-	}
-
-	if len(box.QuotaId) > 0 {
-		actions = append(actions, &updateVMQuota)
-	}
-
-	actions = append(actions, &destroyOldRoute, &mileStoneUpdate, &updateStatusInScylla)
-
-	pipeline := action.NewPipeline(actions...)
+	pipeline := action.NewPipeline(provision.TrackActions(box, destroyActions(box))...)
 
 	err := pipeline.Execute(args)
 	if err != nil {
@@ -327,17 +321,11 @@ func (p *oneProvisioner) Destroy(box *provision.Box, w io.Writer) error {
 	return nil
 }
 
-func (p *oneProvisioner) SetRunning(box *provision.Box, w io.Writer) error {
+func (p *oneProvisioner) SetRunning(ctx context.Context, box *provision.Box, w io.Writer) error {
 	fmt.Fprintf(w, lb.W(lb.DEPLOY, lb.INFO, fmt.Sprintf("--- set state running box (%s)", box.GetFullName())))
-	actions := []*action.Action{
-		&machCreating,
-		&updateNetworkIps,
-		&updateStatusInScylla,
-		&mileStoneUpdate,
-	}
-
-	pipeline := action.NewPipeline(actions...)
+	pipeline := action.NewPipeline(provision.TrackActions(box, runningActions())...)
 	args := runMachineActionsArgs{
+		ctx:           ctx,
 		box:           box,
 		writer:        w,
 		machineStatus: constants.StatusRunning,
@@ -354,34 +342,24 @@ func (p *oneProvisioner) SetRunning(box *provision.Box, w io.Writer) error {
 	return carton.DoneNotify(box, w, alerts.RUNNING, "")
 }
 
-func (p *oneProvisioner) SaveImage(box *provision.Box, w io.Writer) error {
+func (p *oneProvisioner) SaveImage(ctx context.Context, box *provision.Box, w io.Writer) error {
 	if box.Tosca == constants.BACKUP_NEW {
-		return p.createImage(box, w)
+		return p.createImage(ctx, box, w)
 	}
-	return p.saveImage(box, w)
+	return p.saveImage(ctx, box, w)
 }
 
-func (p *oneProvisioner) saveImage(box *provision.Box, w io.Writer) error {
+func (p *oneProvisioner) saveImage(ctx context.Context, box *provision.Box, w io.Writer) error {
 	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- creating backup box (%s)", box.GetFullName())))
 	args := runMachineActionsArgs{
+		ctx:           ctx,
 		box:           box,
 		writer:        w,
 		machineStatus: constants.StatusBackupCreating,
 		provisioner:   p,
 	}
 
-	actions := []*action.Action{
-		&machCreating,
-		&updateStatusInScylla,
-		&createBackupImage,
-		&waitUntillImageReady,
-		&updateSourcePath,
-		&updateBackupStatus,
-		&updateSourceVMIdIps,
-		&updateStatusInScylla,
-	}
-
-	pipeline := action.NewPipeline(actions...)
+	pipeline := action.NewPipeline(provision.TrackActions(box, saveImageActions())...)
 	err := pipeline.Execute(args)
 	if err != nil {
 		fmt.Fprintf(w, lb.W(lb.UPDATING, lb.ERROR, fmt.Sprintf("--- creating backup box (%s)--> %s", box.GetFullName(), err)))
@@ -392,25 +370,17 @@ func (p *oneProvisioner) saveImage(box *provision.Box, w io.Writer) error {
 	return nil
 }
 
-func (p *oneProvisioner) createImage(box *provision.Box, w io.Writer) error {
+func (p *oneProvisioner) createImage(ctx context.Context, box *provision.Box, w io.Writer) error {
 	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- creating new backup box (%s)", box.GetFullName())))
 	args := runMachineActionsArgs{
+		ctx:           ctx,
 		box:           box,
 		writer:        w,
 		machineStatus: constants.StatusBackupCreating,
 		provisioner:   p,
 	}
 
-	actions := []*action.Action{
-		&machCreating,
-		&updateBackupStatus,
-		&uploadBackupImage,
-		&waitUntillImageReady,
-		&updateBackupStatus,
-		&updateSourcePath,
-	}
-
-	pipeline := action.NewPipeline(actions...)
+	pipeline := action.NewPipeline(provision.TrackActions(box, createImageActions())...)
 	err := pipeline.Execute(args)
 	if err != nil {
 		fmt.Fprintf(w, lb.W(lb.UPDATING, lb.ERROR, fmt.Sprintf("--- creating new backup box (%s)--> %s", box.GetFullName(), err)))
@@ -421,22 +391,16 @@ func (p *oneProvisioner) createImage(box *provision.Box, w io.Writer) error {
 	return nil
 }
 
-func (p *oneProvisioner) DeleteImage(box *provision.Box, w io.Writer) error {
+func (p *oneProvisioner) DeleteImage(ctx context.Context, box *provision.Box, w io.Writer) error {
 	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- removing backup box (%s)", box.GetFullName())))
 	args := runMachineActionsArgs{
+		ctx:           ctx,
 		box:           box,
 		writer:        w,
 		machineStatus: constants.StatusBackupDeleting,
 		provisioner:   p,
 	}
-	actions := []*action.Action{&machCreating}
-	if box.Tosca == constants.BACKUP_NEW {
-		actions = append(actions, &updateBackupStatus, &removeBackup)
-	} else {
-		actions = append(actions, &updateBackupStatus, &updateStatusInScylla, &removeBackup, &updateStatusInScylla)
-	}
-
-	pipeline := action.NewPipeline(actions...)
+	pipeline := action.NewPipeline(provision.TrackActions(box, deleteImageActions(box))...)
 	err := pipeline.Execute(args)
 	if err != nil {
 		fmt.Fprintf(w, lb.W(lb.UPDATING, lb.ERROR, fmt.Sprintf("--- removing backup box (%s)--> %s", box.GetFullName(), err)))
@@ -447,31 +411,17 @@ func (p *oneProvisioner) DeleteImage(box *provision.Box, w io.Writer) error {
 	return nil
 }
 
-func (p *oneProvisioner) CreateSnapshot(box *provision.Box, w io.Writer) error {
+func (p *oneProvisioner) CreateSnapshot(ctx context.Context, box *provision.Box, w io.Writer) error {
 	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- creating snapshot box (%s)", box.GetFullName())))
-
-	actions := []*action.Action{
-		&machCreating,
-		&updateSnapStatus,
-		&createSnapshot,
-		&waitUntillSnapReady,
-		&updateIdInSnapTable,
-		&makeActiveSnap,
-	}
-
-	if len(box.QuotaId) > 0 {
-		actions = append(actions, &updateSnapQuotaCount)
-	}
-	actions = append(actions, &updateStatusInScylla)
-
 	args := runMachineActionsArgs{
+		ctx:           ctx,
 		box:           box,
 		writer:        w,
 		machineStatus: constants.StatusSnapCreating,
 		provisioner:   p,
 	}
 
-	pipeline := action.NewPipeline(actions...)
+	pipeline := action.NewPipeline(provision.TrackActions(box, createSnapshotActions(box))...)
 	err := pipeline.Execute(args)
 	if err != nil {
 		fmt.Fprintf(w, lb.W(lb.UPDATING, lb.ERROR, fmt.Sprintf("--- creating snapshot box (%s)--> %s", box.GetFullName(), err)))
@@ -482,25 +432,17 @@ func (p *oneProvisioner) CreateSnapshot(box *provision.Box, w io.Writer) error {
 	return nil
 }
 
-func (p *oneProvisioner) RestoreSnapshot(box *provision.Box, w io.Writer) error {
+func (p *oneProvisioner) RestoreSnapshot(ctx context.Context, box *provision.Box, w io.Writer) error {
 	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- restore snapshot box (%s)", box.GetFullName())))
 	args := runMachineActionsArgs{
+		ctx:           ctx,
 		box:           box,
 		writer:        w,
 		machineStatus: constants.StatusSnapRestoring,
 		provisioner:   p,
 	}
 
-	actions := []*action.Action{&machCreating, &updateStatusInScylla}
-	if box.CanCycleStop() {
-		actions = append(actions, &stopMachine, &mileStoneUpdate, &updateStatusInScylla)
-	}
-	actions = append(actions, &restoreVirtualMachine, &updateSnapStatus, &makeActiveSnap, &updateStatusInScylla)
-	if box.CanCycleStop() {
-		actions = append(actions, &startMachine, &mileStoneUpdate, &updateStatusInScylla)
-	}
-
-	pipeline := action.NewPipeline(actions...)
+	pipeline := action.NewPipeline(provision.TrackActions(box, restoreSnapshotActions(box))...)
 	err := pipeline.Execute(args)
 	if err != nil {
 		fmt.Fprintf(w, lb.W(lb.UPDATING, lb.ERROR, fmt.Sprintf("--- restore snapshot box (%s)--> %s", box.GetFullName(), err)))
@@ -511,9 +453,10 @@ func (p *oneProvisioner) RestoreSnapshot(box *provision.Box, w io.Writer) error
 	return nil
 }
 
-func (p *oneProvisioner) DeleteSnapshot(box *provision.Box, w io.Writer) error {
+func (p *oneProvisioner) DeleteSnapshot(ctx context.Context, box *provision.Box, w io.Writer) error {
 	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- removing snapshot box (%s)", box.GetFullName())))
 	args := runMachineActionsArgs{
+		ctx:           ctx,
 		box:           box,
 		writer:        w,
 		machineStatus: constants.StatusSnapDeleting,
@@ -525,19 +468,7 @@ func (p *oneProvisioner) DeleteSnapshot(box *provision.Box, w io.Writer) error {
 		return err
 	}
 
-	actions := []*action.Action{
-		&machCreating,
-		&updateStatusInScylla,
-		&removeSnapShot,
-	}
-
-	if snp.IsQuota() {
-		actions = append(actions, &updateSnapQuotaCount)
-	}
-
-	actions = append(actions, &updateSnapStatus, &updateStatusInScylla)
-
-	pipeline := action.NewPipeline(actions...)
+	pipeline := action.NewPipeline(provision.TrackActions(box, deleteSnapshotActions(snp.IsQuota()))...)
 	err = pipeline.Execute(args)
 	if err != nil {
 		fmt.Fprintf(w, lb.W(lb.UPDATING, lb.ERROR, fmt.Sprintf("--- removing snapshot box (%s)--> %s", box.GetFullName(), err)))
@@ -548,24 +479,17 @@ func (p *oneProvisioner) DeleteSnapshot(box *provision.Box, w io.Writer) error {
 	return nil
 }
 
-func (p *oneProvisioner) AttachDisk(box *provision.Box, w io.Writer) error {
+func (p *oneProvisioner) AttachDisk(ctx context.Context, box *provision.Box, w io.Writer) error {
 	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- adding new storage to box (%s)", box.GetFullName())))
 	args := runMachineActionsArgs{
+		ctx:           ctx,
 		box:           box,
 		writer:        w,
 		machineStatus: constants.StatusDiskAttaching,
 		provisioner:   p,
 	}
 
-	actions := []*action.Action{
-		&machCreating,
-		&updateStatusInScylla,
-		&addNewStorage,
-		&updateIdInDiskTable,
-		&updateStatusInScylla,
-	}
-
-	pipeline := action.NewPipeline(actions...)
+	pipeline := action.NewPipeline(provision.TrackActions(box, attachDiskActions())...)
 	err := pipeline.Execute(args)
 	if err != nil {
 		fmt.Fprintf(w, lb.W(lb.UPDATING, lb.ERROR, fmt.Sprintf("--- adding new storage to box (%s)--> %s", box.GetFullName(), err)))
@@ -576,23 +500,17 @@ func (p *oneProvisioner) AttachDisk(box *provision.Box, w io.Writer) error {
 	return nil
 }
 
-func (p *oneProvisioner) DetachDisk(box *provision.Box, w io.Writer) error {
+func (p *oneProvisioner) DetachDisk(ctx context.Context, box *provision.Box, w io.Writer) error {
 	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- removing existing storage from box (%s)", box.GetFullName())))
 	args := runMachineActionsArgs{
+		ctx:           ctx,
 		box:           box,
 		writer:        w,
 		machineStatus: constants.StatusDiskDetaching,
 		provisioner:   p,
 	}
 
-	actions := []*action.Action{
-		&machCreating,
-		&updateStatusInScylla,
-		&removeDiskStorage,
-		&updateStatusInScylla,
-	}
-
-	pipeline := action.NewPipeline(actions...)
+	pipeline := action.NewPipeline(provision.TrackActions(box, detachDiskActions())...)
 	err := pipeline.Execute(args)
 	if err != nil {
 		fmt.Fprintf(w, lb.W(lb.UPDATING, lb.ERROR, fmt.Sprintf("--- removing existing storage from box (%s)--> %s", box.GetFullName(), err)))
@@ -603,28 +521,18 @@ func (p *oneProvisioner) DetachDisk(box *provision.Box, w io.Writer) error {
 	return nil
 }
 
-func (p *oneProvisioner) SetState(box *provision.Box, w io.Writer, changeto utils.Status) error {
+func (p *oneProvisioner) SetState(ctx context.Context, box *provision.Box, w io.Writer, changeto utils.Status) error {
 
 	fmt.Fprintf(w, lb.W(lb.DEPLOY, lb.INFO, fmt.Sprintf("--- stateto %s", box.GetFullName())))
 	args := runMachineActionsArgs{
+		ctx:           ctx,
 		box:           box,
 		writer:        w,
 		machineStatus: changeto,
 		provisioner:   p,
 	}
 
-	stateAction := make([]*action.Action, 0, 4)
-	stateAction = append(stateAction, &machCreating, &changeStateofMachine)
-	if args.box.PublicIp != "" {
-		stateAction = append(stateAction, &updateStatusInScylla, &addNewRoute, &updateStatusInScylla)
-	} else {
-		stateAction = append(stateAction, &updateStatusInScylla)
-	}
-
-	stateAction = append(stateAction, &setFinalState, &updateStatusInScylla)
-
-	actions := stateAction
-	pipeline := action.NewPipeline(actions...)
+	pipeline := action.NewPipeline(provision.TrackActions(box, stateActions(box))...)
 
 	err := pipeline.Execute(args)
 	if err != nil {
@@ -636,10 +544,11 @@ func (p *oneProvisioner) SetState(box *provision.Box, w io.Writer, changeto util
 	return err
 }
 
-func (p *oneProvisioner) Restart(box *provision.Box, process string, w io.Writer) error {
+func (p *oneProvisioner) Restart(ctx context.Context, box *provision.Box, process string, w io.Writer) error {
 
 	fmt.Fprintf(w, lb.W(lb.RESTARTING, lb.INFO, fmt.Sprintf("--- restarting box (%s)", box.GetFullName())))
 	args := runMachineActionsArgs{
+		ctx:           ctx,
 		box:           box,
 		writer:        w,
 		machineStatus: constants.StatusBootstrapped,
@@ -648,15 +557,7 @@ func (p *oneProvisioner) Restart(box *provision.Box, process string, w io.Writer
 		process:       process,
 	}
 
-	actions := []*action.Action{
-		&machCreating,
-		&updateStatusInScylla,
-		&restartMachine,
-		&mileStoneUpdate,
-		&updateStatusInScylla,
-	}
-
-	pipeline := action.NewPipeline(actions...)
+	pipeline := action.NewPipeline(provision.TrackActions(box, cycleActions(&restartMachine))...)
 
 	err := pipeline.Execute(args)
 	if err != nil {
@@ -668,10 +569,56 @@ func (p *oneProvisioner) Restart(box *provision.Box, process string, w io.Writer
 	return nil
 }
 
-func (p *oneProvisioner) Start(box *provision.Box, process string, w io.Writer) error {
+// Upgrade sets the repo of the box at box.Commit in the context of its vm, and
+// starts the vm again for its contextualization to build it. A running vm is
+// powered off for that only when restart is set, one doesn't change the
+// context of a running vm.
+func (p *oneProvisioner) Upgrade(ctx context.Context, box *provision.Box, restart bool, w io.Writer) (string, error) {
+	if box.Repo == nil || box.Repo.Gitr() == "" {
+		return "", fmt.Errorf("box %s has no repo to upgrade from", box.GetFullName())
+	}
+	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- upgrading box (%s) to %s", box.GetFullName(), box.Commit)))
+	running := box.State == constants.StateRunning
+	if running && !restart {
+		err := fmt.Errorf("box %s runs, its context changes only when its restarted", box.GetFullName())
+		fmt.Fprintf(w, lb.W(lb.UPDATING, lb.ERROR, fmt.Sprintf("--- upgrading box (%s)-->%s", box.GetFullName(), err)))
+		return "", err
+	}
+	if running {
+		if err := p.Stop(ctx, box, constants.STOP, w); err != nil {
+			return "", err
+		}
+	}
+	args := runMachineActionsArgs{
+		ctx:           ctx,
+		box:           box,
+		writer:        w,
+		machineStatus: constants.StatusStopped,
+		machineState:  constants.StateStopped,
+		provisioner:   p,
+	}
+	pipeline := action.NewPipeline(provision.TrackActions(box, upgradeActions())...)
+	err := pipeline.Execute(args)
+	if err != nil {
+		fmt.Fprintf(w, lb.W(lb.UPDATING, lb.ERROR, fmt.Sprintf("--- upgrading box (%s)-->%s", box.GetFullName(), err)))
+	}
+	if running {
+		if serr := p.Start(ctx, box, constants.START, w); serr != nil && err == nil {
+			err = serr
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- upgrading box (%s) to %s OK", box.GetFullName(), box.Commit)))
+	return "", nil
+}
+
+func (p *oneProvisioner) Start(ctx context.Context, box *provision.Box, process string, w io.Writer) error {
 
 	fmt.Fprintf(w, lb.W(lb.STARTING, lb.INFO, fmt.Sprintf("--- starting box (%s)", box.GetFullName())))
 	args := runMachineActionsArgs{
+		ctx:           ctx,
 		box:           box,
 		writer:        w,
 		machineStatus: constants.StatusStarting,
@@ -680,15 +627,7 @@ func (p *oneProvisioner) Start(box *provision.Box, process string, w io.Writer)
 		process:       process,
 	}
 
-	actions := []*action.Action{
-		&machCreating,
-		&updateStatusInScylla,
-		&startMachine,
-		&mileStoneUpdate,
-		&updateStatusInScylla,
-	}
-
-	pipeline := action.NewPipeline(actions...)
+	pipeline := action.NewPipeline(provision.TrackActions(box, cycleActions(&startMachine))...)
 
 	err := pipeline.Execute(args)
 	if err != nil {
@@ -701,10 +640,11 @@ func (p *oneProvisioner) Start(box *provision.Box, process string, w io.Writer)
 	return nil
 }
 
-func (p *oneProvisioner) Stop(box *provision.Box, process string, w io.Writer) error {
+func (p *oneProvisioner) Stop(ctx context.Context, box *provision.Box, process string, w io.Writer) error {
 
 	fmt.Fprintf(w, lb.W(lb.STOPPING, lb.INFO, fmt.Sprintf("--- stopping box (%s)", box.GetFullName())))
 	args := runMachineActionsArgs{
+		ctx:           ctx,
 		box:           box,
 		writer:        w,
 		machineStatus: constants.StatusStopping,
@@ -712,15 +652,7 @@ func (p *oneProvisioner) Stop(box *provision.Box, process string, w io.Writer) e
 		provisioner:   p,
 		process:       process,
 	}
-	actions := []*action.Action{
-		&machCreating,
-		&updateStatusInScylla,
-		&stopMachine,
-		&mileStoneUpdate,
-		&updateStatusInScylla,
-	}
-
-	pipeline := action.NewPipeline(actions...)
+	pipeline := action.NewPipeline(provision.TrackActions(box, cycleActions(&stopMachine))...)
 
 	err := pipeline.Execute(args)
 	if err != nil {
@@ -732,10 +664,94 @@ func (p *oneProvisioner) Stop(box *provision.Box, process string, w io.Writer) e
 	return nil
 }
 
-func (p *oneProvisioner) Suspend(box *provision.Box, process string, w io.Writer) error {
+// Resize powers off a running machine to resize it, and starts it again even
+// when the resize fails.
+func (p *oneProvisioner) Resize(ctx context.Context, box *provision.Box, to provision.BoxCompute, w io.Writer) error {
+	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- resizing box (%s)", box.GetFullName())))
+	running := box.State == constants.StateRunning
+	if running {
+		if err := p.Stop(ctx, box, constants.STOP, w); err != nil {
+			return err
+		}
+	}
+	args := runMachineActionsArgs{
+		ctx:           ctx,
+		box:           box,
+		writer:        w,
+		machineStatus: constants.StatusStopped,
+		machineState:  constants.StateStopped,
+		provisioner:   p,
+		resizeTo:      to,
+	}
+	pipeline := action.NewPipeline(provision.TrackActions(box, resizeActions())...)
+	err := pipeline.Execute(args)
+	if err != nil {
+		fmt.Fprintf(w, lb.W(lb.UPDATING, lb.ERROR, fmt.Sprintf("--- resizing box (%s)-->%s", box.GetFullName(), err)))
+	}
+	if running {
+		if serr := p.Start(ctx, box, constants.START, w); serr != nil && err == nil {
+			err = serr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- resizing box (%s) OK", box.GetFullName())))
+	return nil
+}
+
+// Migrate moves the machine of the box to another host, live or cold. The
+// machine is left running or powered off as it was.
+func (p *oneProvisioner) Migrate(ctx context.Context, box *provision.Box, to provision.MigrateTo, w io.Writer) error {
+	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- migrating box (%s) to %s", box.GetFullName(), to.String())))
+	state := constants.StateStopped
+	if box.State == constants.StateRunning {
+		state = constants.StateRunning
+	}
+	args := runMachineActionsArgs{
+		ctx:           ctx,
+		box:           box,
+		writer:        w,
+		machineStatus: statusMigrating,
+		machineState:  state,
+		provisioner:   p,
+		migrateTo:     to,
+	}
+	pipeline := action.NewPipeline(provision.TrackActions(box, migrateActions(box))...)
+	if err := pipeline.Execute(args); err != nil {
+		fmt.Fprintf(w, lb.W(lb.UPDATING, lb.ERROR, fmt.Sprintf("--- migrating box (%s)-->%s", box.GetFullName(), err)))
+		return err
+	}
+	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- migrating box (%s) OK", box.GetFullName())))
+	return nil
+}
+
+func (p *oneProvisioner) Clone(ctx context.Context, box *provision.Box, from provision.CloneSource, w io.Writer) error {
+	fmt.Fprintf(w, lb.W(lb.DEPLOY, lb.INFO, fmt.Sprintf("--- cloning box (%s) from %s", box.GetFullName(), from.String())))
+	args := runMachineActionsArgs{
+		ctx:           ctx,
+		box:           box,
+		writer:        w,
+		isDeploy:      true,
+		machineStatus: constants.StatusLaunching,
+		machineState:  constants.StateInitializing,
+		provisioner:   p,
+		cloneFrom:     from,
+	}
+	pipeline := action.NewPipeline(provision.TrackActions(box, cloneActions())...)
+	if err := pipeline.Execute(args); err != nil {
+		fmt.Fprintf(w, lb.W(lb.DEPLOY, lb.ERROR, fmt.Sprintf("--- cloning box (%s)-->%s", box.GetFullName(), err)))
+		return err
+	}
+	fmt.Fprintf(w, lb.W(lb.DEPLOY, lb.INFO, fmt.Sprintf("--- cloning box (%s) OK", box.GetFullName())))
+	return nil
+}
+
+func (p *oneProvisioner) Suspend(ctx context.Context, box *provision.Box, process string, w io.Writer) error {
 
 	fmt.Fprintf(w, lb.W(lb.STOPPING, lb.INFO, fmt.Sprintf("--- suspending box (%s)", box.GetFullName())))
 	args := runMachineActionsArgs{
+		ctx:           ctx,
 		box:           box,
 		writer:        w,
 		machineStatus: constants.StatusSuspending,
@@ -743,15 +759,7 @@ func (p *oneProvisioner) Suspend(box *provision.Box, process string, w io.Writer
 		provisioner:   p,
 		process:       process,
 	}
-	actions := []*action.Action{
-		&machCreating,
-		&updateStatusInScylla,
-		&suspendMachine,
-		&mileStoneUpdate,
-		&updateStatusInScylla,
-	}
-
-	pipeline := action.NewPipeline(actions...)
+	pipeline := action.NewPipeline(provision.TrackActions(box, cycleActions(&suspendMachine))...)
 
 	err := pipeline.Execute(args)
 	if err != nil {
@@ -763,10 +771,6 @@ func (p *oneProvisioner) Suspend(box *provision.Box, process string, w io.Writer
 	return nil
 }
 
-func (p *oneProvisioner) Shell(provision.ShellOptions) error {
-	return provision.ErrNotImplemented
-}
-
 func (*oneProvisioner) Addr(box *provision.Box) (string, error) {
 	r, err := getRouterForBox(box)
 	if err != nil {
@@ -806,9 +810,10 @@ func (p *oneProvisioner) SetBoxStatus(box *provision.Box, w io.Writer, status ut
 		&machCreating,
 		&updateStatusInScylla,
 	}
-	pipeline := action.NewPipeline(actions...)
+	pipeline := action.NewPipeline(provision.TrackActions(box, actions)...)
 
 	args := runMachineActionsArgs{
+		ctx:           context.Background(),
 		box:           box,
 		writer:        w,
 		machineStatus: status,
@@ -866,47 +871,23 @@ func (p *oneProvisioner) usePlatformImage(re *repository.Repo) bool {
 	return !re.OneClick
 }
 
-func (p *oneProvisioner) ExecuteCommandOnce(stdout, stderr io.Writer, box *provision.Box, cmd string, args ...string) error {
-	/*if boxs, err := p.listRunnableMachinesByBox(box.GetName()); err ! =nil {
-					return err
-	    }
-
-		if err := nil; err != nil {
-			return err
-		}
-		if len(boxs) == 0 {
-			return provision.ErrBoxNotFound
-		}
-		box := boxs[0]
-		return box.Exec(p, stdout, stderr, cmd, args...)
-	*/
-	return nil
-}
-
-func (p *oneProvisioner) NetworkUpdate(box *provision.Box, w io.Writer) error {
+func (p *oneProvisioner) NetworkUpdate(ctx context.Context, box *provision.Box, w io.Writer) error {
 	switch box.PolicyOps.Operation {
 	case carton.NETWORK_ATTACH:
-		return p.networkAttach(box, w)
+		return p.networkAttach(ctx, box, w)
 	case carton.NETWORK_DETACH:
-		return p.networkDetach(box, w)
+		return p.networkDetach(ctx, box, w)
 	}
 	return nil
 }
 
-func (p *oneProvisioner) networkAttach(box *provision.Box, w io.Writer) error {
+func (p *oneProvisioner) networkAttach(ctx context.Context, box *provision.Box, w io.Writer) error {
 
 	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- network attach for box %s", box.GetFullName())))
-	actions := []*action.Action{
-		&machCreating,
-		&updateStatusInScylla,
-		&updataPoliciesStatus,
-		&attachNetworks,
-		&updateNetworkIps,
-		&updataPoliciesStatus,
-	}
-	pipeline := action.NewPipeline(actions...)
+	pipeline := action.NewPipeline(provision.TrackActions(box, networkActions(&attachNetworks))...)
 
 	args := runMachineActionsArgs{
+		ctx:           ctx,
 		box:           box,
 		writer:        w,
 		machineStatus: constants.StatusInitialized,
@@ -923,19 +904,12 @@ func (p *oneProvisioner) networkAttach(box *provision.Box, w io.Writer) error {
 	return nil
 }
 
-func (p *oneProvisioner) networkDetach(box *provision.Box, w io.Writer) error {
+func (p *oneProvisioner) networkDetach(ctx context.Context, box *provision.Box, w io.Writer) error {
 	fmt.Fprintf(w, lb.W(lb.UPDATING, lb.INFO, fmt.Sprintf("--- network detach for box %s", box.GetFullName())))
-	actions := []*action.Action{
-		&machCreating,
-		&updateStatusInScylla,
-		&updataPoliciesStatus,
-		&detachNetworks,
-		&updateNetworkIps,
-		&updataPoliciesStatus,
-	}
-	pipeline := action.NewPipeline(actions...)
+	pipeline := action.NewPipeline(provision.TrackActions(box, networkActions(&detachNetworks))...)
 
 	args := runMachineActionsArgs{
+		ctx:           ctx,
 		box:           box,
 		writer:        w,
 		machineStatus: constants.StatusInitialized,
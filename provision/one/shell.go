@@ -0,0 +1,249 @@
+/*
+** Copyright [2013-2017] [Megam Systems]
+**
+** Licensed under the Apache License, Version 2.0 (the "License");
+** you may not use this file except in compliance with the License.
+** You may obtain a copy of the License at
+**
+** http://www.apache.org/licenses/LICENSE-2.0
+**
+** Unless required by applicable law or agreed to in writing, software
+** distributed under the License is distributed on an "AS IS" BASIS,
+** WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+** See the License for the specific language governing permissions and
+** limitations under the License.
+ */
+
+package one
+
+import (
+	"bytes"
+	b64 "encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	constants "github.com/megamsys/libgo/utils"
+	"github.com/megamsys/vertice/carton"
+	"github.com/megamsys/vertice/meta"
+	"github.com/megamsys/vertice/provision"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	sshPort        = "22"
+	sshDialTimeout = 30 * time.Second
+	defaultSSHUser = "root"
+	defaultTerm    = "xterm"
+)
+
+// Shell logs into the vm of the box over ssh, and runs a login shell in a pty
+// of the size of the terminal of the user.
+func (p *oneProvisioner) Shell(opts provision.ShellOptions) error {
+	client, err := p.sshClient(opts.Box)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	session, err := client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+	term := opts.Term
+	if term == "" {
+		term = defaultTerm
+	}
+	modes := ssh.TerminalModes{
+		ssh.ECHO:          1,
+		ssh.TTY_OP_ISPEED: 14400,
+		ssh.TTY_OP_OSPEED: 14400,
+	}
+	if err = session.RequestPty(term, opts.Height, opts.Width, modes); err != nil {
+		return err
+	}
+	session.Stdin = opts.Conn
+	session.Stdout = opts.Conn
+	session.Stderr = opts.Conn
+	if err = session.Shell(); err != nil {
+		return err
+	}
+	if opts.Resize != nil {
+		go func() {
+			for size := range opts.Resize {
+				if err := session.WindowChange(size.Height, size.Width); err != nil {
+					log.Debugf("  resize shell of %s : %s", opts.Box.GetFullName(), err)
+				}
+			}
+		}()
+	}
+	return session.Wait()
+}
+
+// ExecuteCommandOnce runs the command in the vm of the box over ssh.
+func (p *oneProvisioner) ExecuteCommandOnce(stdout, stderr io.Writer, box *provision.Box, cmd string, args ...string) error {
+	client, err := p.sshClient(box)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	session, err := client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+	session.Stdout = stdout
+	session.Stderr = stderr
+	return session.Run(shellCommand(cmd, args...))
+}
+
+// sshClient logs into the vm of the box, on its private ip or, when that
+// can't be reached or fails the handshake, its public ip. A host key that
+// changed for the same vm fails it right away.
+func (p *oneProvisioner) sshClient(box *provision.Box) (*ssh.Client, error) {
+	auths, err := p.sshAuths(box)
+	if err != nil {
+		return nil, err
+	}
+	user := box.SSH.User
+	if user == "" {
+		user = defaultSSHUser
+	}
+	asm, err := carton.NewAssembly(box.CartonId, box.AccountId, "")
+	if err != nil {
+		return nil, err
+	}
+	hosts, err := sshHosts(box, asm)
+	if err != nil {
+		return nil, err
+	}
+	known := &knownHost{asm: asm}
+	config := &ssh.ClientConfig{
+		User:            user,
+		Auth:            auths,
+		HostKeyCallback: known.check,
+	}
+	var last error
+	for _, host := range hosts {
+		addr := net.JoinHostPort(host, sshPort)
+		conn, err := net.DialTimeout("tcp", addr, sshDialTimeout)
+		if err != nil {
+			log.Debugf("  ssh to %s on %s : %s", box.GetFullName(), addr, err)
+			last = err
+			continue
+		}
+		c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+		if known.mismatch != nil {
+			conn.Close()
+			return nil, known.mismatch
+		}
+		if err != nil {
+			log.Debugf("  ssh handshake with %s on %s : %s", box.GetFullName(), addr, err)
+			conn.Close()
+			last = err
+			continue
+		}
+		known.trust()
+		return ssh.NewClient(c, chans, reqs), nil
+	}
+	return nil, fmt.Errorf("can't log into %s over ssh on %s : %s", box.GetFullName(), strings.Join(hosts, ", "), last)
+}
+
+// knownHost checks the host key of the vm against the one trusted the first
+// time vertice logged into it. The key is kept in the outputs of the assembly
+// with the instance id of the vm, a vm launched again is trusted again.
+type knownHost struct {
+	asm      *carton.Assembly
+	key      ssh.PublicKey
+	mismatch error
+}
+
+func (k *knownHost) check(host string, remote net.Addr, key ssh.PublicKey) error {
+	vmid, trusted := k.asm.SSHHostKey()
+	if trusted == "" || vmid != k.asm.InstanceId() {
+		k.key = key
+		return nil
+	}
+	want, _, _, _, err := ssh.ParseAuthorizedKey([]byte(trusted))
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(want.Marshal(), key.Marshal()) {
+		k.mismatch = fmt.Errorf("host key of %s (%s) doesn't match the one trusted for %s", host, key.Type(), k.asm.GetFullName())
+		return k.mismatch
+	}
+	return nil
+}
+
+// trust keeps the key of a vm seen for the first time.
+func (k *knownHost) trust() {
+	if k.key == nil {
+		return
+	}
+	line := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(k.key)))
+	if err := k.asm.NukeAndSetOutputs(map[string][]string{carton.SSH_HOSTKEY: {k.asm.InstanceId(), line}}); err != nil {
+		log.Errorf("  unable to keep the ssh host key of %s : %s", k.asm.GetFullName(), err)
+	}
+}
+
+// sshAuths are the key vertice holds, and the root password of the box when
+// it was launched with one.
+func (p *oneProvisioner) sshAuths(box *provision.Box) ([]ssh.AuthMethod, error) {
+	var auths []ssh.AuthMethod
+	if key, err := ioutil.ReadFile(p.sshKeyFile()); err == nil {
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		auths = append(auths, ssh.PublicKeys(signer))
+	}
+	if box.SSH.Password != "" {
+		if pwd, err := b64.StdEncoding.DecodeString(box.SSH.Password); err == nil {
+			auths = append(auths, ssh.Password(string(pwd)))
+		}
+	}
+	if len(auths) == 0 {
+		return nil, fmt.Errorf("no ssh key or password to log into %s", box.GetFullName())
+	}
+	return auths, nil
+}
+
+func (p *oneProvisioner) sshKeyFile() string {
+	if p.sshKey != "" {
+		return p.sshKey
+	}
+	return filepath.Join(meta.MC.Dir, ".ssh", "id_rsa")
+}
+
+// sshHosts are the ips of the vm of the box, private first.
+func sshHosts(box *provision.Box, asm *carton.Assembly) ([]string, error) {
+	var hosts []string
+	for _, nic := range []string{constants.PRIVATEIPV4, constants.PUBLICIPV4} {
+		for _, ip := range strings.Split(asm.Outputs.Match(nic), ",") {
+			if ip = strings.TrimSpace(ip); ip != "" {
+				hosts = append(hosts, ip)
+			}
+		}
+	}
+	if len(hosts) == 0 && box.PublicIp != "" {
+		hosts = append(hosts, box.PublicIp)
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("box %s has no ip to ssh to", box.GetFullName())
+	}
+	return hosts, nil
+}
+
+// shellCommand is cmd with its args quoted for the login shell of the vm.
+func shellCommand(cmd string, args ...string) string {
+	parts := []string{cmd}
+	for _, a := range args {
+		parts = append(parts, "'"+strings.Replace(a, "'", `'\''`, -1)+"'")
+	}
+	return strings.Join(parts, " ")
+}
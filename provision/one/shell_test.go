@@ -0,0 +1,51 @@
+package one
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+
+	"github.com/megamsys/libgo/pairs"
+	"github.com/megamsys/vertice/carton"
+	"golang.org/x/crypto/ssh"
+)
+
+func hostKey(t *testing.T) ssh.PublicKey {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestKnownHostCheck(t *testing.T) {
+	trusted, changed := hostKey(t), hostKey(t)
+	line := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(trusted)))
+	asm := &carton.Assembly{Name: "tom", Outputs: pairs.JsonPairs{}}
+	asm.Outputs.NukeAndSet(map[string][]string{
+		carton.INSTANCE_ID: {"42"},
+		carton.SSH_HOSTKEY: {"42", line},
+	})
+
+	known := &knownHost{asm: asm}
+	if err := known.check("10.0.0.4:22", nil, trusted); err != nil {
+		t.Errorf("trusted key: %s", err)
+	}
+	if err := known.check("10.0.0.4:22", nil, changed); err == nil {
+		t.Error("a changed key for the same vm was accepted")
+	}
+	if known.mismatch == nil {
+		t.Error("the mismatch of the changed key wasn't kept")
+	}
+
+	asm.Outputs.NukeAndSet(map[string][]string{carton.INSTANCE_ID: {"43"}})
+	known = &knownHost{asm: asm}
+	if err := known.check("10.0.0.4:22", nil, changed); err != nil || known.key != changed {
+		t.Errorf("the key of a vm launched again wasn't trusted: %v", err)
+	}
+}
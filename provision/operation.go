@@ -0,0 +1,210 @@
+/*
+** Copyright [2013-2017] [Megam Systems]
+**
+** Licensed under the Apache License, Version 2.0 (the "License");
+** you may not use this file except in compliance with the License.
+** You may obtain a copy of the License at
+**
+** http://www.apache.org/licenses/LICENSE-2.0
+**
+** Unless required by applicable law or agreed to in writing, software
+** distributed under the License is distributed on an "AS IS" BASIS,
+** WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+** See the License for the specific language governing permissions and
+** limitations under the License.
+ */
+package provision
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/megamsys/libgo/action"
+	"github.com/satori/go.uuid"
+)
+
+const (
+	OP_RUNNING = "running"
+	OP_DONE    = "done"
+	OP_FAILED  = "failed"
+
+	OP_PREFIX = "OPS"
+
+	// maxFinishedOperations is the number of finished operations remembered.
+	maxFinishedOperations = 500
+)
+
+// OperationStep is the progress of a pipeline action run by an operation.
+type OperationStep struct {
+	Name      string        `json:"name"`
+	BoxName   string        `json:"box_name"`
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// Operation is the record of a request accepted by vertice.
+type Operation struct {
+	Id         string          `json:"id"`
+	RequestId  string          `json:"request_id"`
+	AssemblyId string          `json:"assembly_id"`
+	AccountId  string          `json:"account_id"`
+	Category   string          `json:"category"`
+	Action     string          `json:"action"`
+	Status     string          `json:"status"`
+	Current    string          `json:"current"`
+	Steps      []OperationStep `json:"steps"`
+	Error      string          `json:"error,omitempty"`
+	Rollback   string          `json:"rollback,omitempty"`
+	Plan       []BoxPlan       `json:"plan,omitempty"`
+	StartedAt  time.Time       `json:"started_at"`
+	EndedAt    time.Time       `json:"ended_at,omitempty"`
+}
+
+type operationStore struct {
+	sync.RWMutex
+	ops      map[string]*Operation
+	finished []string
+}
+
+var operations = &operationStore{ops: make(map[string]*Operation)}
+
+// NewOperation starts recording an operation for the request.
+func NewOperation(requestId, assemblyId, accountId, category, act string) *Operation {
+	op := &Operation{
+		Id:         OP_PREFIX + strings.Replace(uuid.NewV1().String(), "-", "", -1),
+		RequestId:  requestId,
+		AssemblyId: assemblyId,
+		AccountId:  accountId,
+		Category:   category,
+		Action:     act,
+		Status:     OP_RUNNING,
+		StartedAt:  time.Now(),
+	}
+	operations.Lock()
+	operations.ops[op.Id] = op
+	operations.Unlock()
+	return op
+}
+
+// GetOperation returns a copy of the operation with id.
+func GetOperation(id string) (*Operation, bool) {
+	operations.RLock()
+	defer operations.RUnlock()
+	op, ok := operations.ops[id]
+	if !ok {
+		return nil, false
+	}
+	return op.copy(), true
+}
+
+// ListOperations returns a copy of all the known operations, the latest first.
+func ListOperations() []*Operation {
+	operations.RLock()
+	list := make([]*Operation, 0, len(operations.ops))
+	for _, op := range operations.ops {
+		list = append(list, op.copy())
+	}
+	operations.RUnlock()
+	sort.Sort(byStartedAt(list))
+	return list
+}
+
+// Begin records the start of the step name run for box, and returns a func to
+// be called with the outcome of the step.
+func (op *Operation) Begin(name, boxName string) func(error) {
+	operations.Lock()
+	i := len(op.Steps)
+	op.Current = name
+	op.Steps = append(op.Steps, OperationStep{Name: name, BoxName: boxName, StartedAt: time.Now()})
+	operations.Unlock()
+	return func(err error) {
+		operations.Lock()
+		defer operations.Unlock()
+		op.Steps[i].Duration = time.Since(op.Steps[i].StartedAt)
+		if err != nil {
+			op.Steps[i].Error = err.Error()
+		}
+	}
+}
+
+// Done records the final outcome of the operation.
+func (op *Operation) Done(err error) {
+	operations.Lock()
+	defer operations.Unlock()
+	op.EndedAt = time.Now()
+	op.Current = ""
+	op.Status = OP_DONE
+	if err != nil {
+		op.Status = OP_FAILED
+		op.Error = err.Error()
+	}
+	operations.finished = append(operations.finished, op.Id)
+	for len(operations.finished) > maxFinishedOperations {
+		delete(operations.ops, operations.finished[0])
+		operations.finished = operations.finished[1:]
+	}
+}
+
+// RecordRollback records why the operation id rolled back the work it did.
+func RecordRollback(id, reason string) {
+	operations.Lock()
+	defer operations.Unlock()
+	if op, ok := operations.ops[id]; ok {
+		op.Rollback = reason
+	}
+}
+
+// RecordPlan records what a dry run of the operation would do.
+func (op *Operation) RecordPlan(plan []BoxPlan) {
+	operations.Lock()
+	defer operations.Unlock()
+	op.Plan = plan
+}
+
+func (op *Operation) copy() *Operation {
+	c := *op
+	c.Steps = make([]OperationStep, len(op.Steps))
+	copy(c.Steps, op.Steps)
+	return &c
+}
+
+type byStartedAt []*Operation
+
+func (b byStartedAt) Len() int           { return len(b) }
+func (b byStartedAt) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byStartedAt) Less(i, j int) bool { return b[i].StartedAt.After(b[j].StartedAt) }
+
+// TrackActions wraps the pipeline actions, so the operation running on the box
+// records the progress and duration of every action.
+func TrackActions(box *Box, actions []*action.Action) []*action.Action {
+	if box == nil || box.OperationId == "" {
+		return actions
+	}
+	operations.RLock()
+	op, ok := operations.ops[box.OperationId]
+	operations.RUnlock()
+	if !ok {
+		return actions
+	}
+	tracked := make([]*action.Action, len(actions))
+	for i, a := range actions {
+		forward := a.Forward
+		name := a.Name
+		tracked[i] = &action.Action{
+			Name: a.Name,
+			Forward: func(ctx action.FWContext) (action.Result, error) {
+				end := op.Begin(name, box.GetFullName())
+				res, err := forward(ctx)
+				end(err)
+				return res, err
+			},
+			Backward:  a.Backward,
+			OnError:   a.OnError,
+			MinParams: a.MinParams,
+		}
+	}
+	return tracked
+}
@@ -0,0 +1,81 @@
+/*
+** Copyright [2013-2017] [Megam Systems]
+**
+** Licensed under the Apache License, Version 2.0 (the "License");
+** you may not use this file except in compliance with the License.
+** You may obtain a copy of the License at
+**
+** http://www.apache.org/licenses/LICENSE-2.0
+**
+** Unless required by applicable law or agreed to in writing, software
+** distributed under the License is distributed on an "AS IS" BASIS,
+** WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+** See the License for the specific language governing permissions and
+** limitations under the License.
+ */
+package provision
+
+import (
+	"github.com/megamsys/libgo/action"
+)
+
+// Planner is a provisioner that can tell the actions it would run for an op on
+// a box, without running them. Those are the names of the pipeline actions,
+// or for an op that isn't a pipeline the Type.Method of the calls it makes.
+type Planner interface {
+	PlanActions(b *Box, op BoxOp) ([]string, error)
+}
+
+// BoxPlan is what an op would do to a box.
+type BoxPlan struct {
+	Id       string   `json:"id"`
+	Name     string   `json:"name"`
+	Provider string   `json:"provider"`
+	Region   string   `json:"region"`
+	TargetId string   `json:"target_id"`
+	Op       BoxOp    `json:"op"`
+	Actions  []string `json:"actions"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// ActionNames returns the names of the pipeline actions, in the order they run.
+func ActionNames(actions []*action.Action) []string {
+	names := make([]string, 0, len(actions))
+	for _, a := range actions {
+		names = append(names, a.Name)
+	}
+	return names
+}
+
+// PlanBox returns the plan for op on the box, as provisioned by p.
+func PlanBox(p Provisioner, b *Box, op BoxOp) BoxPlan {
+	plan := BoxPlan{
+		Id:       b.Id,
+		Name:     b.GetFullName(),
+		Provider: b.Provider,
+		Region:   b.Region,
+		TargetId: b.InstanceId,
+		Op:       op,
+		Actions:  []string{},
+	}
+	if err := b.Can(op); err != nil {
+		plan.Error = err.Error()
+		return plan
+	}
+	if err := NeedFor(b.Provider, p, op); err != nil {
+		plan.Error = err.Error()
+		return plan
+	}
+	planner, ok := p.(Planner)
+	if !ok {
+		plan.Error = "provisioner " + b.Provider + " can't plan " + string(op)
+		return plan
+	}
+	actions, err := planner.PlanActions(b, op)
+	if err != nil {
+		plan.Error = err.Error()
+		return plan
+	}
+	plan.Actions = actions
+	return plan
+}
@@ -16,6 +16,7 @@
 package provision
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/megamsys/libgo/utils"
@@ -77,26 +78,42 @@ type ShellOptions struct {
 	Height int
 	Unit   string
 	Term   string
+	// Resize gets the new size of the terminal when it is resized during the
+	// session, it is closed once the session is over.
+	Resize <-chan TermSize
+}
+
+// TermSize is the size of a terminal in columns and rows.
+type TermSize struct {
+	Width  int
+	Height int
 }
 
 // GitDeployer is a provisioner that can deploy the box from a Git
 // repository.
 type GitDeployer interface {
-	GitDeploy(b *Box, w io.Writer) (string, error)
+	GitDeploy(ctx context.Context, b *Box, w io.Writer) (string, error)
 }
 
 // ImageDeployer is a provisioner that can deploy the box from a
 // previously generated image.
 type ImageDeployer interface {
-	ImageDeploy(b *Box, image string, w io.Writer) (string, error)
-	BackupDeploy(b *Box, image string, w io.Writer) (string, error)
+	ImageDeploy(ctx context.Context, b *Box, image string, w io.Writer) (string, error)
+	BackupDeploy(ctx context.Context, b *Box, image string, w io.Writer) (string, error)
+}
+
+// Upgrader is a provisioner that can rebuild a box deployed from a Git
+// repository at b.Commit. The running unit is replaced only when restart is
+// set. It returns the image the box was rebuilt to, if any.
+type Upgrader interface {
+	Upgrade(ctx context.Context, b *Box, restart bool, w io.Writer) (string, error)
 }
 
 // StateChanger changes the state of a deployed box
 // A deployed box is termed as a machine or a container
 type StateChanger interface {
-	SetRunning(*Box, io.Writer) error
-	SetState(*Box, io.Writer, utils.Status) error
+	SetRunning(context.Context, *Box, io.Writer) error
+	SetState(context.Context, *Box, io.Writer, utils.Status) error
 }
 
 type RawImageAccess interface {
@@ -108,17 +125,84 @@ type MarketPlaceAccess interface {
 }
 
 type Network interface {
-	NetworkUpdate(b *Box, w io.Writer) error
+	NetworkUpdate(ctx context.Context, b *Box, w io.Writer) error
+}
+
+// Resizer is a provisioner that can change the cpu, ram and disk of a deployed
+// box from b.Compute to another compute.
+type Resizer interface {
+	Resize(ctx context.Context, b *Box, to BoxCompute, w io.Writer) error
+}
+
+// Scaler is a provisioner that can run a deployed box in a number of units,
+// adding or removing them as needed.
+type Scaler interface {
+	Scale(ctx context.Context, b *Box, units int, w io.Writer) error
+}
+
+// MigrateTo is where a box is migrated to, a host or any host of a cluster.
+// Live migrations move a running box without stopping it.
+type MigrateTo struct {
+	Host      string
+	ClusterId string
+	Live      bool
+}
+
+func (m MigrateTo) String() string {
+	kind := "cold"
+	if m.Live {
+		kind = "live"
+	}
+	if m.Host != "" {
+		return fmt.Sprintf("host %s (%s)", m.Host, kind)
+	}
+	return fmt.Sprintf("cluster %s (%s)", m.ClusterId, kind)
+}
+
+// Migrator is a provisioner that can move a deployed box between hosts.
+type Migrator interface {
+	Migrate(ctx context.Context, b *Box, to MigrateTo, w io.Writer) error
+}
+
+const (
+	CLONE_SNAPSHOT = "snapshot"
+	CLONE_BACKUP   = "backup"
+)
+
+// CloneSource is the snapshot or backup a new box is cloned from. A snapshot
+// is the snapshot SnapId of the disk DiskId of the vm InstanceId, a backup is
+// the image ImageId, both in the Region of the assembly AssemblyId.
+type CloneSource struct {
+	Kind       string
+	Id         string
+	Name       string
+	AssemblyId string
+	Region     string
+	InstanceId string
+	DiskId     string
+	SnapId     string
+	ImageId    string
+}
+
+func (c CloneSource) String() string {
+	return fmt.Sprintf("%s %s (%s)", c.Kind, c.Id, c.Region)
+}
+
+// Cloner is a provisioner that can launch a box as a new vm, from the
+// snapshot or backup of another one.
+type Cloner interface {
+	Clone(ctx context.Context, b *Box, from CloneSource, w io.Writer) error
 }
 
 // Provisioner is the basic interface of this package.
 //
 // Any vertice provisioner must implement this interface in order to provision
-// vertice cartons.
+// vertice cartons. The operations are handed the context of the request, and
+// should give up when its cancelled.
 type Provisioner interface {
 
 	// Destroy is called when vertice is destroying the box.
-	Destroy(*Box, io.Writer) error
+	Destroy(context.Context, *Box, io.Writer) error
 
 	// SetBoxStatus changes the status of a box.
 	SetBoxStatus(*Box, io.Writer, utils.Status) error
@@ -128,39 +212,39 @@ type Provisioner interface {
 
 	// Restart restarts the boxes of the carton, with an optional
 	// string parameter represeting the name of the process to start.
-	Restart(*Box, string, io.Writer) error
+	Restart(context.Context, *Box, string, io.Writer) error
 	// Start starts the boxes of the application, with an optional string
 	// parameter represeting the name of the process to start.
-	Start(*Box, string, io.Writer) error
+	Start(context.Context, *Box, string, io.Writer) error
 
 	// Stop stops the boxes of the application, with an optional string
 	// parameter represeting the name of the process to stop.
-	Stop(*Box, string, io.Writer) error
+	Stop(context.Context, *Box, string, io.Writer) error
 
 	// Suspend suspends the boxes of the application, with an optional string
 	// parameter represeting the name of the process to suspend.
-	Suspend(*Box, string, io.Writer) error
+	Suspend(context.Context, *Box, string, io.Writer) error
 
 	// DiskSave creates the image for current state of the running VM
-	SaveImage(*Box, io.Writer) error
+	SaveImage(context.Context, *Box, io.Writer) error
 
 	// DeleteImage removes the image from storage created from running VM
-	DeleteImage(*Box, io.Writer) error
+	DeleteImage(context.Context, *Box, io.Writer) error
 
 	// DiskSnapCreate(SnapShot) saves current state of the running VM
-	CreateSnapshot(*Box, io.Writer) error
+	CreateSnapshot(context.Context, *Box, io.Writer) error
 
 	// DeleteImage removes the image from storage created from running VM
-	DeleteSnapshot(*Box, io.Writer) error
+	DeleteSnapshot(context.Context, *Box, io.Writer) error
 
 	// Restore current VM state to Saved Snapshot state
-	RestoreSnapshot(*Box, io.Writer) error
+	RestoreSnapshot(context.Context, *Box, io.Writer) error
 
 	// AttachDisk add additional disk to current state of the running VM
-	AttachDisk(*Box, io.Writer) error
+	AttachDisk(context.Context, *Box, io.Writer) error
 
 	// DetachDisk remove additional disk from current state of the running VM
-	DetachDisk(*Box, io.Writer) error
+	DetachDisk(context.Context, *Box, io.Writer) error
 
 	// Open a remote shel in one of the boxs in the carton.
 	Shell(ShellOptions) error
@@ -1,6 +1,7 @@
 package rancher
 
 import (
+	"context"
 	//"errors"
 	"fmt"
 	"io"
@@ -19,6 +20,7 @@ import (
 )
 
 type runContainerActionsArgs struct {
+	ctx              context.Context
 	box              *provision.Box
 	imageId          string
 	containerStatus  utils.Status
@@ -36,6 +38,7 @@ type containersToAdd struct {
 }
 
 type changeUnitsPipelineArgs struct {
+	ctx         context.Context
 	box         *provision.Box
 	writer      io.Writer
 	toAdd       map[string]*containersToAdd
@@ -201,7 +204,7 @@ var waitToContainerUp = action.Action{
 		c := ctx.Previous.(container.Container)
 		log.Debugf("  state checking container (%s)", c.Id)
 		args := ctx.Params[0].(runContainerActionsArgs)
-		err := c.StateCheck(args.provisioner)
+		err := c.StateCheck(args.ctx, args.provisioner)
 		if err != nil {
 			return c, err
 		}
@@ -93,24 +93,95 @@ func (c *Cluster) getContainerNode(hostId string) (*client.Host, error) {
 
 func (c *Cluster) getNodeClient(region string) (node, error) {
 	var n node
-	var addr, aid, access, secret string
+	v, err := c.regionNode(region)
+	if err != nil {
+		return n, err
+	}
+	return c.nodeClient(v)
+}
+
+// nodeClient is the client of the rancher server v, with the admin keys of
+// its config.
+func (c *Cluster) nodeClient(v Node) (node, error) {
+	cliaddr := client.ClientOpts{Url: v.Address, AccountId: v.Metadata[ADMIN_ID], AccessKey: v.Metadata[ACCESSKEY], SecretKey: v.Metadata[SECRETKEY]}
+	return c.getNodeByAddr(cliaddr)
+}
+
+// RebuildContainers stores the rancher server and the name of the containers
+// of every server in the cluster, for a storage that starts empty while the
+// servers already have containers. A server that can't list them is skipped,
+// the error is returned after the others are stored.
+func (c *Cluster) RebuildContainers() (int, error) {
+	nodes, err := c.Nodes()
+	if err != nil {
+		return 0, err
+	}
+	var (
+		stored  int
+		lastErr error
+	)
+	for _, v := range nodes {
+		n, err := c.nodeClient(v)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		opts := client.NewListOpts()
+		for {
+			page, err := n.RancherClient.Container.List(opts)
+			if err != nil {
+				lastErr = wrapErrorWithCmd(n, err, "listContainers")
+				break
+			}
+			for _, ct := range page.Data {
+				if err = c.storage().StoreContainer(ct.Id, v.Address); err != nil {
+					return stored, err
+				}
+				if ct.Name != "" {
+					if err = c.storage().StoreContainerByName(ct.Id, ct.Name); err != nil {
+						return stored, err
+					}
+				}
+				stored++
+			}
+			marker := nextMarker(page.Pagination)
+			if marker == "" {
+				break
+			}
+			opts.Filters["marker"] = marker
+		}
+	}
+	return stored, lastErr
+}
+
+// nextMarker is the marker of the page after p, blank on the last one.
+func nextMarker(p *client.Pagination) string {
+	if p == nil || !p.Partial || p.Next == "" {
+		return ""
+	}
+	next, err := url.Parse(p.Next)
+	if err != nil {
+		return ""
+	}
+	return next.Query().Get("marker")
+}
+
+// regionNode is the rancher server of region.
+func (c *Cluster) regionNode(region string) (Node, error) {
+	var n Node
 	nodes, err := c.Nodes()
 	if err != nil {
 		return n, err
 	}
 	for _, v := range nodes {
 		if v.Metadata[RANCHER_ZONE] == region {
-			addr = v.Address
-			aid = v.Metadata[ADMIN_ID]
-			access = v.Metadata[ACCESSKEY]
-			secret = v.Metadata[SECRETKEY]
+			n = v
 		}
 	}
-	if addr == "" {
-		return n, errors.New("selected region unavailable [" + c.Region + "]")
+	if n.Address == "" {
+		return n, errors.New("selected region unavailable [" + region + "]")
 	}
-	cliaddr := client.ClientOpts{Url: addr, AccountId: aid, AccessKey: access, SecretKey: secret}
-	return c.getNodeByAddr(cliaddr)
+	return n, nil
 }
 
 func (c *Cluster) SetNetworkinNode(hostId, IpAddress, cartonId, email string) error {
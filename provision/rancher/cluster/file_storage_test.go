@@ -0,0 +1,88 @@
+package cluster
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func tempStoragePath(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "cluster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "clusters", "rancher.gob"), func() { os.RemoveAll(dir) }
+}
+
+func TestNewPrunesNodesNotInTheConfig(t *testing.T) {
+	path, cleanup := tempStoragePath(t)
+	defer cleanup()
+	s, err := NewFileStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chennai := Node{Address: "http://chennai.rancher.io:8080", Metadata: map[string]string{RANCHER_ZONE: "chennai"}}
+	tokyo := Node{Address: "http://tokyo.rancher.io:8080", Metadata: map[string]string{RANCHER_ZONE: "tokyo"}}
+	if _, err = New(s, chennai, tokyo); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = NewFileStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := New(s, tokyo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes, err := c.UnfilteredNodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1 || nodes[0].Address != tokyo.Address {
+		t.Errorf("expected only %s to be kept, got %#v", tokyo.Address, nodes)
+	}
+}
+
+func TestRebuildContainers(t *testing.T) {
+	server := newRancherServer()
+	defer server.Close()
+	created := server.add("tom")
+	path, cleanup := tempStoragePath(t)
+	defer cleanup()
+	s, err := NewFileStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Migrated() {
+		t.Error("a storage without a file should not be migrated")
+	}
+	c, err := New(s, Node{Address: server.URL, Metadata: map[string]string{RANCHER_ZONE: "chennai"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := c.RebuildContainers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("RebuildContainers stored %d containers, want 1", n)
+	}
+	if err = s.SetMigrated(); err != nil {
+		t.Fatal(err)
+	}
+	s, err = NewFileStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Migrated() {
+		t.Error("the migration mark should be kept")
+	}
+	if host, err := s.RetrieveContainer(created.Id); err != nil || host != server.URL {
+		t.Errorf("container not stored, got %q, %v", host, err)
+	}
+	if id, err := s.RetrieveContainerByName("tom"); err != nil || id != created.Id {
+		t.Errorf("container name not stored, got %q, %v", id, err)
+	}
+}
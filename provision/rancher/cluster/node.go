@@ -5,6 +5,8 @@ import (
 	//"github.com/fsouza/go-dockerclient"
 	"strconv"
 	"time"
+
+	"github.com/megamsys/vertice/provision"
 )
 
 // Node represents a host running Docker. Each node has an Address
@@ -86,6 +88,12 @@ func (n *Node) FailureCount() int {
 	return failures
 }
 
+// keepHealth carries the health of the stored node from over to n.
+func (n *Node) keepHealth(from Node) {
+	n.Metadata = provision.KeepHealth(n.Metadata, from.Metadata)
+	n.Healing = from.Healing
+}
+
 func (n *Node) ResetFailures() {
 	if n.Metadata == nil {
 		n.Metadata = make(map[string]string)
@@ -0,0 +1,163 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/megamsys/go-rancher/v2"
+)
+
+// rancherServer is a rancher api that only creates and lists containers.
+type rancherServer struct {
+	*httptest.Server
+	mu         sync.Mutex
+	containers []client.Container
+}
+
+func newRancherServer() *rancherServer {
+	rs := &rancherServer{}
+	rs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/containers") {
+			switch r.Method {
+			case "POST":
+				var c client.Container
+				if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				w.WriteHeader(http.StatusCreated)
+				json.NewEncoder(w).Encode(rs.add(c.Name))
+			case "GET":
+				json.NewEncoder(w).Encode(map[string]interface{}{"type": "collection", "data": rs.list()})
+			}
+			return
+		}
+		w.Header().Set("X-API-Schemas", rs.URL+"/schemas")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"type": "collection",
+			"data": []map[string]interface{}{{
+				"id":                "container",
+				"type":              "schema",
+				"pluralName":        "containers",
+				"collectionMethods": []string{"GET", "POST"},
+				"links":             map[string]string{"collection": rs.URL + "/containers"},
+			}},
+		})
+	}))
+	return rs
+}
+
+func (rs *rancherServer) add(name string) client.Container {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	c := client.Container{Name: name}
+	c.Id = fmt.Sprintf("1i%d", len(rs.containers)+1)
+	c.Type = "container"
+	rs.containers = append(rs.containers, c)
+	return c
+}
+
+func (rs *rancherServer) list() []client.Container {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return append([]client.Container(nil), rs.containers...)
+}
+
+func TestCreateContainerInRegionsInParallel(t *testing.T) {
+	c, err := New(&MapStorage{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	servers := map[string]*rancherServer{}
+	for _, zone := range []string{"chennai", "tokyo"} {
+		server := newRancherServer()
+		defer server.Close()
+		servers[zone] = server
+		if err = c.Register(Node{Address: server.URL, Metadata: map[string]string{RANCHER_ZONE: zone}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	const deploys = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, deploys*len(servers))
+	for i := 0; i < deploys; i++ {
+		for zone := range servers {
+			wg.Add(1)
+			go func(zone string, i int) {
+				defer wg.Done()
+				opts := client.Container{Name: fmt.Sprintf("%s-%d", zone, i), ImageUuid: "docker:megam/busybox"}
+				addr, container, err := c.InRegion(zone).CreateContainerSchedulerOpts(opts)
+				if err != nil {
+					errs <- err
+				} else if addr != servers[zone].URL {
+					errs <- fmt.Errorf("container %s created in %s, want %s", opts.Name, addr, servers[zone].URL)
+				} else if container.Name != opts.Name {
+					errs <- fmt.Errorf("created container %q, want %q", container.Name, opts.Name)
+				}
+			}(zone, i)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if c.Region != "" {
+		t.Errorf("the region of the cluster changed to %q", c.Region)
+	}
+	for zone, server := range servers {
+		if n := len(server.list()); n != deploys {
+			t.Errorf("%s created %d containers, want %d", zone, n, deploys)
+		}
+	}
+}
+
+func TestRegionNodesInParallel(t *testing.T) {
+	c, err := New(&MapStorage{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	zones := map[string]string{
+		"chennai": "http://chennai.rancher.io:8080",
+		"tokyo":   "http://tokyo.rancher.io:8080",
+	}
+	for zone, addr := range zones {
+		if err = c.Register(Node{Address: addr, Metadata: map[string]string{RANCHER_ZONE: zone}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, 20*len(zones))
+	for i := 0; i < 20; i++ {
+		for zone, addr := range zones {
+			wg.Add(1)
+			go func(zone, addr string) {
+				defer wg.Done()
+				v := c.InRegion(zone)
+				n, err := v.regionNode(v.Region)
+				if err != nil {
+					errs <- err
+				} else if n.Address != addr {
+					errs <- fmt.Errorf("%s picked %s, want %s", zone, n.Address, addr)
+				}
+			}(zone, addr)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if c.Region != "" {
+		t.Errorf("the region of the cluster changed to %q", c.Region)
+	}
+	if _, err = c.InRegion("delhi").regionNode("delhi"); err == nil || err.Error() != "selected region unavailable [delhi]" {
+		t.Errorf("expected the region to be unavailable, got %v", err)
+	}
+}
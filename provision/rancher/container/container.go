@@ -3,6 +3,7 @@ package container
 import (
 	//"fmt"
 	//"io"
+	"context"
 	"net"
 	"net/url"
 	"time"
@@ -15,7 +16,6 @@ import (
 	constants "github.com/megamsys/libgo/utils"
 	//"github.com/megamsys/libgo/events"
 	//lw	"github.com/megamsys/libgo/writer"
-	"github.com/megamsys/libgo/safe"
 	"github.com/megamsys/vertice/carton"
 	"github.com/megamsys/vertice/provision"
 	"github.com/megamsys/vertice/provision/rancher/cluster"
@@ -96,8 +96,7 @@ func (c *Container) Create(args *CreateArgs) error {
 		StartOnCreate: true,
 	}
 
-	cl := args.Provisioner.Cluster()
-	cl.Region = args.Box.Region
+	cl := args.Provisioner.Cluster().InRegion(args.Box.Region)
 
 	addr, cont, err := cl.CreateContainerSchedulerOpts(config)
 	if err != nil {
@@ -173,9 +172,9 @@ func urlToHost(urlStr string) string {
 	return host
 }
 
-func (c *Container) StateCheck(args RancherProvisioner) (err error) {
+func (c *Container) StateCheck(ctx context.Context, args RancherProvisioner) (err error) {
 	var res *client.Container
-	err = safe.WaitCondition(10*time.Minute, 10*time.Second, func() (bool, error) {
+	err = provision.WaitCondition(ctx, 10*time.Minute, 10*time.Second, func() (bool, error) {
 		res, err = args.Cluster().GetContainerById(c.Id)
 		if err != nil {
 			return false, err
@@ -197,7 +196,7 @@ type NetworkInfo struct {
 }
 
 func (c *Container) NetworkInfo(r RancherProvisioner) error {
-	err := r.Cluster().SetNetworkinNode(c.HostId, c.PublicIp, c.CartonId, c.AccountId)
+	err := r.Cluster().InRegion(c.Region).SetNetworkinNode(c.HostId, c.PublicIp, c.CartonId, c.AccountId)
 	if err != nil {
 		return err
 	}
@@ -208,8 +207,7 @@ func (c *Container) Remove(r RancherProvisioner) error {
 	log.Debugf("Removing container %s from docker", c.BoxName)
 
 	//this will be removed. containerID will be stored upon create in riak
-	p := r.Cluster()
-	p.Region = c.Region
+	p := r.Cluster().InRegion(c.Region)
 	cont, err := p.GetContainerById(c.Id)
 	if err != nil {
 		log.Errorf("error on get container unit %s - %s", c.Id, err)
@@ -230,8 +228,7 @@ type StartArgs struct {
 }
 
 func (c *Container) Start(args *StartArgs) error {
-	st := args.Provisioner.Cluster()
-	st.Region = c.Region
+	st := args.Provisioner.Cluster().InRegion(c.Region)
 	err := st.StartContainer(c.Id)
 	if err != nil {
 		return err
@@ -245,8 +242,7 @@ func (c *Container) Start(args *StartArgs) error {
 }
 
 func (c *Container) Stop(p RancherProvisioner) error {
-	st := p.Cluster()
-	st.Region = c.Region
+	st := p.Cluster().InRegion(c.Region)
 	err := st.StopContainer(c.Id)
 	if err != nil {
 		log.Errorf("error on stop container %s: %s", c.Id, err)
@@ -0,0 +1,49 @@
+package rancher
+
+import (
+	"github.com/megamsys/libgo/action"
+	"github.com/megamsys/vertice/provision"
+)
+
+func deployActions() []*action.Action {
+	return []*action.Action{
+		&updateStatusInScylla,
+		&createContainer,
+		&updateContainerIdInScylla,
+		&MileStoneUpdate,
+		&updateStatusInScylla,
+		&waitToContainerUp,
+		&MileStoneUpdate,
+		&updateStatusInScylla,
+		&setNetworkInfo,
+		&updateStatusInScylla,
+	}
+}
+
+func destroyActions() []*action.Action {
+	return []*action.Action{
+		&destroyOldContainers,
+	}
+}
+
+// PlanActions returns the names of the actions op would run on the box.
+// Start and stop aren't pipelines, they're named by the calls they make on
+// every container of the box.
+func (p *rancherProvisioner) PlanActions(box *provision.Box, op provision.BoxOp) ([]string, error) {
+	switch op {
+	case provision.CYCLE_DEPLOY:
+		return provision.ActionNames(deployActions()), nil
+	case provision.CYCLE_DESTROY:
+		return provision.ActionNames(destroyActions()), nil
+	case provision.CYCLE_ROLLBACK:
+		return provision.ActionNames(append(destroyActions(), deployActions()...)), nil
+	case provision.CYCLE_START:
+		return []string{"Container.Start"}, nil
+	case provision.CYCLE_STOP:
+		return []string{"Container.Stop"}, nil
+	case provision.CYCLE_RESTART:
+		// nothing is done to containers for it yet.
+		return []string{}, nil
+	}
+	return nil, provision.ErrNotImplemented
+}
@@ -2,6 +2,7 @@ package rancher
 
 import (
 	"bytes"
+	"context"
 	//	"errors"
 	"fmt"
 	"io"
@@ -41,6 +42,9 @@ type rancherProvisioner struct {
 type Rancher struct {
 	Enabled bool     `json:"enabled" toml:"enabled"`
 	Regions []Region `json:"region" toml:"region"`
+	// Storage keeps the nodes of the cluster in memory, or in a file when it
+	// is file, so that their health survives a restart.
+	Storage string `json:"storage" toml:"storage"`
 }
 
 type Region struct {
@@ -68,19 +72,25 @@ func (p *rancherProvisioner) String() string {
 	return "ready"
 }
 
+// Capabilities of rancher are none yet, its shell and exec are stubs.
+func (p *rancherProvisioner) Capabilities() []provision.Capability {
+	return []provision.Capability{}
+}
+
 func (p *rancherProvisioner) Initialize(m interface{}) error {
 	return p.initRancherCluster(m)
 }
 
 func (p *rancherProvisioner) initRancherCluster(i interface{}) error {
 	var err error
+	w, ok := i.(Rancher)
 	if p.storage == nil {
-		p.storage, err = buildClusterStorage()
+		p.storage, err = buildClusterStorage(w.Storage)
 		if err != nil {
 			return err
 		}
 	}
-	if w, ok := i.(Rancher); ok {
+	if ok {
 		var nodes []cluster.Node
 		for i := 0; i < len(w.Regions); i++ {
 			m := w.Regions[i].toMap()
@@ -96,10 +106,28 @@ func (p *rancherProvisioner) initRancherCluster(i interface{}) error {
 		if err != nil {
 			return err
 		}
+		if fs, ok := p.storage.(*cluster.FileStorage); ok && !fs.Migrated() {
+			p.rebuildContainers(fs)
+		}
 	}
 	return nil
 }
 
+// rebuildContainers fills a new FileStorage with the containers the rancher
+// servers already have, those created while the cluster was kept in memory.
+// It is tried again on the next start till all the servers could be listed.
+func (p *rancherProvisioner) rebuildContainers(fs *cluster.FileStorage) {
+	n, err := p.cluster.RebuildContainers()
+	log.Debugf("  %d containers of the rancher cluster stored", n)
+	if err != nil {
+		log.Errorf("  rebuilding the containers of the rancher cluster: %s", err)
+		return
+	}
+	if err = fs.SetMigrated(); err != nil {
+		log.Errorf("  marking the containers of the rancher cluster as stored: %s", err)
+	}
+}
+
 //convert the config to just a map.
 
 func (c Region) toMap() map[string]string {
@@ -115,10 +143,14 @@ func (c Region) toMap() map[string]string {
 	return m
 }
 
-func buildClusterStorage() (cluster.Storage, error) {
-
-	return &cluster.MapStorage{}, nil
-
+func buildClusterStorage(kind string) (cluster.Storage, error) {
+	switch kind {
+	case "", provision.STORAGE_MEMORY:
+		return &cluster.MapStorage{}, nil
+	case provision.STORAGE_FILE:
+		return cluster.NewFileStorage(provision.ClusterStoragePath("rancher"))
+	}
+	return nil, fmt.Errorf("unknown rancher cluster storage %q, it is %s or %s", kind, provision.STORAGE_MEMORY, provision.STORAGE_FILE)
 }
 
 func getRouterForBox(box *provision.Box) (router.Router, error) {
@@ -140,12 +172,12 @@ func (p *rancherProvisioner) StartupMessage() (string, error) {
 	return strings.TrimSpace(b.String()), nil
 }
 
-func (p *rancherProvisioner) GitDeploy(box *provision.Box, w io.Writer) (string, error) {
+func (p *rancherProvisioner) GitDeploy(ctx context.Context, box *provision.Box, w io.Writer) (string, error) {
 	imageId, err := p.gitDeploy(box.Repo, box.ImageVersion, w)
 	if err != nil {
 		return "", err
 	}
-	return p.deployPipeline(box, imageId, w)
+	return p.deployPipeline(ctx, box, imageId, w)
 }
 
 func (p *rancherProvisioner) gitDeploy(re *repository.Repo, version string, w io.Writer) (string, error) {
@@ -153,7 +185,7 @@ func (p *rancherProvisioner) gitDeploy(re *repository.Repo, version string, w io
 	//return "",nil
 }
 
-func (p *rancherProvisioner) ImageDeploy(box *provision.Box, imageId string, w io.Writer) (string, error) {
+func (p *rancherProvisioner) ImageDeploy(ctx context.Context, box *provision.Box, imageId string, w io.Writer) (string, error) {
 	isValid, err := isValidBoxImage(box.GetFullName(), imageId)
 	if err != nil {
 		return "", err
@@ -161,34 +193,20 @@ func (p *rancherProvisioner) ImageDeploy(box *provision.Box, imageId string, w i
 	if !isValid {
 		return "", fmt.Errorf("invalid image for box %s: %s", box.GetFullName(), imageId)
 	}
-	return p.deployPipeline(box, imageId, w)
+	return p.deployPipeline(ctx, box, imageId, w)
 }
 
-func (p *rancherProvisioner) BackupDeploy(box *provision.Box, imageId string, w io.Writer) (string, error) {
+func (p *rancherProvisioner) BackupDeploy(ctx context.Context, box *provision.Box, imageId string, w io.Writer) (string, error) {
 	return "", nil
 }
 
-func (p *rancherProvisioner) deployPipeline(box *provision.Box, imageId string, w io.Writer) (string, error) {
+func (p *rancherProvisioner) deployPipeline(ctx context.Context, box *provision.Box, imageId string, w io.Writer) (string, error) {
 	fmt.Fprintf(w, lb.W(lb.DEPLOY, lb.INFO, fmt.Sprintf("--- deploy box (%s, image:%s)", box.GetFullName(), imageId)))
-	actions := []*action.Action{
-		&updateStatusInScylla,
-		&createContainer,
-		&updateContainerIdInScylla,
-		&MileStoneUpdate,
-		&updateStatusInScylla,
-		&waitToContainerUp,
-		&MileStoneUpdate,
-		&updateStatusInScylla,
-		&setNetworkInfo,
-		&updateStatusInScylla,
-		//	&followLogsAndCommit,
-		//	&MileStoneUpdate,
-		//	&updateStatusInScylla,
-	}
 
-	pipeline := action.NewPipeline(actions...)
+	pipeline := action.NewPipeline(provision.TrackActions(box, deployActions())...)
 
 	args := runContainerActionsArgs{
+		ctx:             ctx,
 		box:             box,
 		imageId:         imageId,
 		writer:          w,
@@ -207,7 +225,7 @@ func (p *rancherProvisioner) deployPipeline(box *provision.Box, imageId string,
 	return imageId, nil
 }
 
-func (p *rancherProvisioner) Destroy(box *provision.Box, w io.Writer) error {
+func (p *rancherProvisioner) Destroy(ctx context.Context, box *provision.Box, w io.Writer) error {
 
 	fmt.Fprintf(w, lb.W(lb.DESTORYING, lb.INFO, fmt.Sprintf("\n--- destroying box (%s) ----", box.GetFullName())))
 	containers, err := p.listContainersByBox(box)
@@ -217,16 +235,14 @@ func (p *rancherProvisioner) Destroy(box *provision.Box, w io.Writer) error {
 		return err
 	}
 	args := changeUnitsPipelineArgs{
+		ctx:         ctx,
 		box:         box,
 		toRemove:    containers,
 		writer:      ioutil.Discard,
 		provisioner: p,
 		boxDestroy:  true,
 	}
-	pipeline := action.NewPipeline(
-		&destroyOldContainers,
-	//&removeOldRoutes,
-	)
+	pipeline := action.NewPipeline(provision.TrackActions(box, destroyActions())...)
 	err = pipeline.Execute(args)
 	if err != nil {
 		return err
@@ -234,7 +250,7 @@ func (p *rancherProvisioner) Destroy(box *provision.Box, w io.Writer) error {
 	return nil
 }
 
-func (p *rancherProvisioner) Start(box *provision.Box, process string, w io.Writer) error {
+func (p *rancherProvisioner) Start(ctx context.Context, box *provision.Box, process string, w io.Writer) error {
 	containers, err := p.listContainersByBox(box)
 	if err != nil {
 		fmt.Fprintf(w, lb.W(lb.STARTING, lb.ERROR, fmt.Sprintf("Failed to list box containers (%s) --> %s", box.GetFullName(), err)))
@@ -252,7 +268,7 @@ func (p *rancherProvisioner) Start(box *provision.Box, process string, w io.Writ
 	}, nil, true)
 }
 
-func (p *rancherProvisioner) Stop(box *provision.Box, process string, w io.Writer) error {
+func (p *rancherProvisioner) Stop(ctx context.Context, box *provision.Box, process string, w io.Writer) error {
 	containers, err := p.listContainersByBox(box)
 	if err != nil {
 		fmt.Fprintf(w, lb.W(lb.STOPPING, lb.ERROR, fmt.Sprintf("Failed to list box containers (%s) --> %s", box.GetFullName(), err)))
@@ -266,7 +282,7 @@ func (p *rancherProvisioner) Stop(box *provision.Box, process string, w io.Write
 	}, nil, true)
 }
 
-func (p *rancherProvisioner) Restart(box *provision.Box, process string, w io.Writer) error {
+func (p *rancherProvisioner) Restart(ctx context.Context, box *provision.Box, process string, w io.Writer) error {
 	return nil
 }
 
@@ -292,9 +308,10 @@ func (p *rancherProvisioner) SetBoxStatus(box *provision.Box, w io.Writer, statu
 	actions := []*action.Action{
 	//&updateStatusInScylla,
 	}
-	pipeline := action.NewPipeline(actions...)
+	pipeline := action.NewPipeline(provision.TrackActions(box, actions)...)
 
 	args := runContainerActionsArgs{
+		ctx:             context.Background(),
 		box:             box,
 		writer:          w,
 		containerStatus: status,
@@ -422,36 +439,36 @@ func (p *rancherProvisioner) MetricEnvs(start, end int64, point string, w io.Wri
 	return b, nil
 }
 
-func (p *rancherProvisioner) SaveImage(box *provision.Box, w io.Writer) error {
-	return nil
+func (p *rancherProvisioner) SaveImage(ctx context.Context, box *provision.Box, w io.Writer) error {
+	return provision.ErrNotImplemented
 }
 
-func (p *rancherProvisioner) DeleteImage(box *provision.Box, w io.Writer) error {
-	return nil
+func (p *rancherProvisioner) DeleteImage(ctx context.Context, box *provision.Box, w io.Writer) error {
+	return provision.ErrNotImplemented
 }
 
-func (p *rancherProvisioner) CreateSnapshot(box *provision.Box, w io.Writer) error {
-	return nil
+func (p *rancherProvisioner) CreateSnapshot(ctx context.Context, box *provision.Box, w io.Writer) error {
+	return provision.ErrNotImplemented
 }
 
-func (p *rancherProvisioner) DeleteSnapshot(box *provision.Box, w io.Writer) error {
-	return nil
+func (p *rancherProvisioner) DeleteSnapshot(ctx context.Context, box *provision.Box, w io.Writer) error {
+	return provision.ErrNotImplemented
 }
 
-func (p *rancherProvisioner) RestoreSnapshot(box *provision.Box, w io.Writer) error {
-	return nil
+func (p *rancherProvisioner) RestoreSnapshot(ctx context.Context, box *provision.Box, w io.Writer) error {
+	return provision.ErrNotImplemented
 }
 
-func (p *rancherProvisioner) AttachDisk(box *provision.Box, w io.Writer) error {
-	return nil
+func (p *rancherProvisioner) AttachDisk(ctx context.Context, box *provision.Box, w io.Writer) error {
+	return provision.ErrNotImplemented
 }
 
-func (p *rancherProvisioner) DetachDisk(box *provision.Box, w io.Writer) error {
-	return nil
+func (p *rancherProvisioner) DetachDisk(ctx context.Context, box *provision.Box, w io.Writer) error {
+	return provision.ErrNotImplemented
 }
 
-func (p *rancherProvisioner) Suspend(box *provision.Box, process string, w io.Writer) error {
-	return nil
+func (p *rancherProvisioner) Suspend(ctx context.Context, box *provision.Box, process string, w io.Writer) error {
+	return provision.ErrNotImplemented
 }
 
 func (p *rancherProvisioner) TriggerBills(account_id, cat_id, name string) error {
@@ -0,0 +1,96 @@
+/*
+** Copyright [2013-2017] [Megam Systems]
+**
+** Licensed under the Apache License, Version 2.0 (the "License");
+** you may not use this file except in compliance with the License.
+** You may obtain a copy of the License at
+**
+** http://www.apache.org/licenses/LICENSE-2.0
+**
+** Unless required by applicable law or agreed to in writing, software
+** distributed under the License is distributed on an "AS IS" BASIS,
+** WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+** See the License for the specific language governing permissions and
+** limitations under the License.
+ */
+package provision
+
+import (
+	"encoding/gob"
+	"os"
+	"path/filepath"
+
+	"github.com/megamsys/vertice/meta"
+)
+
+// the storages a provisioner can keep the nodes of its cluster in.
+const (
+	// STORAGE_MEMORY forgets the cluster on a restart, the default.
+	STORAGE_MEMORY = "memory"
+	// STORAGE_FILE keeps the cluster in a file under the dir of vertice.
+	STORAGE_FILE = "file"
+)
+
+// ClusterStoragePath is the file the cluster of the provisioner name is kept
+// in when its storage is STORAGE_FILE.
+func ClusterStoragePath(name string) string {
+	return filepath.Join(meta.MC.Dir, "clusters", name+".gob")
+}
+
+// healthMetadata are the keys of the node metadata healing keeps track of.
+var healthMetadata = []string{"Failures", "DisabledUntil", "LastError", "LastSuccess"}
+
+// KeepHealth copies the health metadata of a node in from to to, that gets
+// made when nil.
+func KeepHealth(to, from map[string]string) map[string]string {
+	if to == nil {
+		to = make(map[string]string)
+	}
+	for _, k := range healthMetadata {
+		if v, ok := from[k]; ok {
+			to[k] = v
+		}
+	}
+	return to
+}
+
+// LoadGob decodes the gob in path into v. It returns false when there is no
+// such file yet.
+func LoadGob(path string, v interface{}) (bool, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	if err = gob.NewDecoder(f).Decode(v); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SaveGob writes what encode encodes to a temporary file first, the file in
+// path is replaced only by a complete one.
+func SaveGob(path string, encode func(*gob.Encoder) error) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err = encode(gob.NewEncoder(f)); err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/megamsys/libgo/cmd"
 	constants "github.com/megamsys/libgo/utils"
 	"github.com/megamsys/opennebula-go/api"
+	"github.com/megamsys/vertice/carton"
 	"github.com/megamsys/vertice/provision/one"
 	"strconv"
 	"strings"
@@ -50,8 +51,10 @@ const (
 )
 
 type Config struct {
-	Provider string  `json:"provider" toml:"provider"`
-	One      one.One `json:"one" toml:"one"`
+	Provider    string       `json:"provider" toml:"provider"`
+	Concurrency int          `json:"concurrency" toml:"concurrency"`
+	Retry       carton.Retry `json:"retry" toml:"retry"`
+	One         one.One      `json:"one" toml:"one"`
 }
 
 /*
@@ -95,8 +98,10 @@ func NewConfig() *Config {
 	}
 
 	return &Config{
-		Provider: DefaultProvider,
-		One:      o,
+		Provider:    DefaultProvider,
+		Concurrency: carton.DefaultConcurrency,
+		Retry:       carton.NewRetry(),
+		One:         o,
 	}
 }
 
@@ -107,7 +112,10 @@ func (c Config) String() string {
 	b.Write([]byte(cmd.Colorfy("\nConfig:", "white", "", "bold") + "\t" +
 		cmd.Colorfy("Deployd", "cyan", "", "") + "\n"))
 	b.Write([]byte(constants.PROVIDER + "\t" + c.Provider + "\n"))
+	b.Write([]byte("concurrency  " + "\t" + strconv.Itoa(c.Concurrency) + "\n"))
+	b.Write([]byte("retry        " + "\t" + strconv.Itoa(c.Retry.Attempts) + " attempts, backoff " + c.Retry.Backoff.String() + "\n"))
 	b.Write([]byte("enabled      " + "\t" + strconv.FormatBool(c.One.Enabled) + "\n"))
+	b.Write([]byte("storage      " + "\t" + c.One.Storage + "\n"))
 	for _, v := range c.One.Regions {
 		b.Write([]byte(api.ONEZONE + "\t" + v.OneZone + "\n"))
 		b.Write([]byte(api.ENDPOINT + "\t" + v.OneEndPoint + "\n"))
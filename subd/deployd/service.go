@@ -1,6 +1,7 @@
 package deployd
 
 import (
+	"context"
 	"fmt"
 	"sync"
 
@@ -24,6 +25,8 @@ type Service struct {
 	wg       sync.WaitGroup
 	err      chan error
 	Handler  *Handler
+	queue    *carton.Dispatcher
+	answer   *carton.Responder
 	Consumer *nsq.Consumer
 	Meta     *meta.Config
 	Deployd  *Config
@@ -37,12 +40,20 @@ func NewService(c *meta.Config, d *Config) *Service {
 		Deployd: d,
 	}
 	s.Handler = NewHandler(s.Deployd)
+	s.queue = carton.NewDispatcher(d.Concurrency, &s.wg)
+	s.answer = &carton.Responder{Topic: TOPIC, Retry: d.Retry}
 	//c.MkGlobal() //a setter for global meta config
 	return s
 }
 
 // Open starts the service
 func (s *Service) Open() error {
+	if _, err := carton.OpenJournal(s.Meta.Dir); err != nil {
+		return err
+	}
+	if _, err := carton.OpenDeployHistory(s.Meta.Dir); err != nil {
+		return err
+	}
 	go func() error {
 		log.Info("starting deployd service")
 		if err := nsq.Register(TOPIC, "engine", maxInFlight, s.processNSQ); err != nil {
@@ -74,7 +85,16 @@ func (s *Service) processNSQ(msg *nsq.Message) {
 		log.Errorf("%s", err)
 		return
 	}
-	go s.Handler.serveNSQ(re)
+	// the message is answered only after the request is served.
+	msg.DisableAutoResponse()
+	done := make(chan struct{})
+	go s.answer.KeepAlive(msg, done)
+	s.queue.Dispatch(re, func(ctx context.Context, r *carton.Requests) error {
+		err := s.Handler.serveNSQ(ctx, r)
+		close(done)
+		s.answer.Respond(msg, int(msg.Attempts), r, err)
+		return err
+	})
 	return
 }
 
@@ -83,6 +103,8 @@ func (s *Service) Close() error {
 	if s.Consumer != nil {
 		s.Consumer.Stop()
 	}
+	// cancel the inflight operations, they are requeued to be served again.
+	s.queue.Cancel()
 
 	s.wg.Wait()
 	return nil
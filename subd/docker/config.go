@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/megamsys/libgo/cmd"
 	constants "github.com/megamsys/libgo/utils"
+	"github.com/megamsys/vertice/carton"
 	"github.com/megamsys/vertice/provision/docker"
 	"github.com/megamsys/vertice/provision/docker/cluster"
 	"github.com/megamsys/vertice/toml"
@@ -51,8 +52,10 @@ const (
 )
 
 type Config struct {
-	Provider string        `json:"provider" toml:"provider"`
-	Docker   docker.Docker `json:"docker" toml:"docker"`
+	Provider    string        `json:"provider" toml:"provider"`
+	Concurrency int           `json:"concurrency" toml:"concurrency"`
+	Retry       carton.Retry  `json:"retry" toml:"retry"`
+	Docker      docker.Docker `json:"docker" toml:"docker"`
 }
 
 func NewConfig() *Config {
@@ -70,8 +73,10 @@ func NewConfig() *Config {
 		Regions: append(rg, r),
 	}
 	return &Config{
-		Provider: DefaultProvider,
-		Docker:   o,
+		Provider:    DefaultProvider,
+		Concurrency: carton.DefaultConcurrency,
+		Retry:       carton.NewRetry(),
+		Docker:      o,
 	}
 }
 
@@ -82,7 +87,10 @@ func (c Config) String() string {
 	b.Write([]byte(cmd.Colorfy("Config:", "white", "", "bold") + "\t" +
 		cmd.Colorfy("docker", "cyan", "", "") + "\n"))
 	b.Write([]byte(constants.PROVIDER + "\t" + c.Provider + "\n"))
+	b.Write([]byte("concurrency  " + "\t" + strconv.Itoa(c.Concurrency) + "\n"))
+	b.Write([]byte("retry        " + "\t" + strconv.Itoa(c.Retry.Attempts) + " attempts, backoff " + c.Retry.Backoff.String() + "\n"))
 	b.Write([]byte("enabled      " + "\t" + strconv.FormatBool(c.Docker.Enabled) + "\n"))
+	b.Write([]byte("storage      " + "\t" + c.Docker.Storage + "\n"))
 	for _, v := range c.Docker.Regions {
 		b.Write([]byte(cluster.DOCKER_ZONE + "\t" + v.DockerZone + "\n"))
 		b.Write([]byte(cluster.DOCKER_SWARM + "\t" + v.SwarmEndPoint + "\n"))
@@ -1,6 +1,8 @@
 package docker
 
 import (
+	"context"
+
 	log "github.com/Sirupsen/logrus"
 	"github.com/megamsys/vertice/carton"
 )
@@ -15,18 +17,18 @@ func NewHandler(c *Config) *Handler {
 	return &Handler{D: c}
 }
 
-func (h *Handler) serveNSQ(r *carton.Requests) error {
+func (h *Handler) serveNSQ(ctx context.Context, r *carton.Requests) error {
 	p, err := carton.ParseRequest(r)
 	if err != nil {
 		return err
 	}
 
 	if rp := carton.NewReqOperator(r); rp != nil {
-		err = rp.Accept(&p)
+		err = rp.Accept(ctx, &p)
 		if err != nil {
 			log.Errorf("Error Request : %s  -  %s  : %s", r.Category, r.Action, err)
 		}
-		return err // error swalled here
+		return err
 	}
 
 	return nil
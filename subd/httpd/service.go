@@ -48,6 +48,7 @@ func (s *Service) Open() error {
 	idleTracker := newIdleTracker()
 	shutdown.Register(idleTracker)
 	shutdown.Register(&api.LogTracker)
+	shutdown.Register(&api.VncTracker)
 	readTimeout := 10 * 60
 	writeTimeout := 10 * 60
 	srv := &graceful.Server{
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/megamsys/libgo/cmd"
 	constants "github.com/megamsys/libgo/utils"
+	"github.com/megamsys/vertice/carton"
 	"github.com/megamsys/vertice/provision/rancher"
 	"github.com/megamsys/vertice/provision/rancher/cluster"
 	"github.com/megamsys/vertice/toml"
@@ -47,8 +48,10 @@ const (
 )
 
 type Config struct {
-	Provider string          `json:"provider" toml:"provider"`
-	Rancher  rancher.Rancher `json:"container" toml:"container"`
+	Provider    string          `json:"provider" toml:"provider"`
+	Concurrency int             `json:"concurrency" toml:"concurrency"`
+	Retry       carton.Retry    `json:"retry" toml:"retry"`
+	Rancher     rancher.Rancher `json:"container" toml:"container"`
 }
 
 func NewConfig() *Config {
@@ -71,8 +74,10 @@ func NewConfig() *Config {
 	}
 
 	return &Config{
-		Provider: DefaultProvider,
-		Rancher:  o,
+		Provider:    DefaultProvider,
+		Concurrency: carton.DefaultConcurrency,
+		Retry:       carton.NewRetry(),
+		Rancher:     o,
 	}
 }
 
@@ -83,7 +88,10 @@ func (c Config) String() string {
 	b.Write([]byte(cmd.Colorfy("Config:", "white", "", "bold") + "\t" +
 		cmd.Colorfy("rancher", "cyan", "", "") + "\n"))
 	b.Write([]byte(constants.PROVIDER + "\t" + c.Provider + "\n"))
+	b.Write([]byte("concurrency  " + "\t" + strconv.Itoa(c.Concurrency) + "\n"))
+	b.Write([]byte("retry        " + "\t" + strconv.Itoa(c.Retry.Attempts) + " attempts, backoff " + c.Retry.Backoff.String() + "\n"))
 	b.Write([]byte("enabled      " + "\t" + strconv.FormatBool(c.Rancher.Enabled) + "\n"))
+	b.Write([]byte("storage      " + "\t" + c.Rancher.Storage + "\n"))
 	for _, v := range c.Rancher.Regions {
 		b.Write([]byte(cluster.RANCHER_ZONE + "\t" + v.RancherZone + "\n"))
 		b.Write([]byte(cluster.RANCHER_SERVER + "\t" + v.RancherEndPoint + "\n"))
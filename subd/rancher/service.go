@@ -1,6 +1,7 @@
 package rancher
 
 import (
+	"context"
 	"fmt"
 	"sync"
 
@@ -23,6 +24,8 @@ type Service struct {
 	wg       sync.WaitGroup
 	err      chan error
 	Handler  *Handler
+	queue    *carton.Dispatcher
+	answer   *carton.Responder
 	Consumer *nsq.Consumer
 	Meta     *meta.Config
 	Rancherd *Config
@@ -36,11 +39,19 @@ func NewService(c *meta.Config, d *Config) *Service {
 		Rancherd: d,
 	}
 	s.Handler = NewHandler(s.Rancherd)
+	s.queue = carton.NewDispatcher(d.Concurrency, &s.wg)
+	s.answer = &carton.Responder{Topic: TOPIC, Retry: d.Retry}
 	return s
 }
 
 // Open starts the service
 func (s *Service) Open() error {
+	if _, err := carton.OpenJournal(s.Meta.Dir); err != nil {
+		return err
+	}
+	if _, err := carton.OpenDeployHistory(s.Meta.Dir); err != nil {
+		return err
+	}
 	go func() error {
 		log.Info("starting rancherd service")
 		if err := nsq.Register(TOPIC, "engine", maxInFlight, s.processNSQ); err != nil {
@@ -71,7 +82,16 @@ func (s *Service) processNSQ(msg *nsq.Message) {
 	if err != nil {
 		return
 	}
-	go s.Handler.serveNSQ(re)
+	// the message is answered only after the request is served.
+	msg.DisableAutoResponse()
+	done := make(chan struct{})
+	go s.answer.KeepAlive(msg, done)
+	s.queue.Dispatch(re, func(ctx context.Context, r *carton.Requests) error {
+		err := s.Handler.serveNSQ(ctx, r)
+		close(done)
+		s.answer.Respond(msg, int(msg.Attempts), r, err)
+		return err
+	})
 	return
 }
 
@@ -80,6 +100,8 @@ func (s *Service) Close() error {
 	if s.Consumer != nil {
 		s.Consumer.Stop()
 	}
+	// cancel the inflight operations, they are requeued to be served again.
+	s.queue.Cancel()
 
 	s.wg.Wait()
 	return nil